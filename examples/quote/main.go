@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pseudocodes/tqsdk-go/shinny"
+)
+
+func main() {
+	auth := shinny.NewTqAuth(os.Getenv("TQ_USER"), os.Getenv("TQ_PASSWORD"))
+	client := shinny.NewClient(auth, nil)
+	defer client.Close()
+
+	ctx := context.Background()
+	if err := client.InitMarket(ctx); err != nil {
+		panic(err)
+	}
+
+	unauthorized, err := client.SubscribeQuote(ctx, "SHFE.cu2601", "DCE.m2601")
+	if err != nil {
+		panic(err)
+	}
+	if len(unauthorized) > 0 {
+		fmt.Println("no market data grants for:", unauthorized)
+	}
+	client.OnQuote(func(q *shinny.Quote) {
+		fmt.Printf("%s %s last=%v bid=%v ask=%v\n", q.Datetime, q.Symbol(), q.LastPrice, q.BidPrice1, q.AskPrice1)
+	})
+	select {}
+}
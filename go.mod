@@ -0,0 +1,3 @@
+module github.com/pseudocodes/tqsdk-go
+
+go 1.21
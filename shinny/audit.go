@@ -0,0 +1,52 @@
+package shinny
+
+import "time"
+
+// 审计记录的操作类型
+const (
+	AuditActionInsert = "insert_order"
+	AuditActionCancel = "cancel_order"
+)
+
+// AuditEntry 一笔下单/撤单操作的完整审计记录, 只含业务字段,
+// 不含密码等敏感信息
+type AuditEntry struct {
+	Time       time.Time
+	Action     string
+	Broker     string
+	UserID     string
+	OrderID    string
+	Symbol     string
+	Direction  string
+	Offset     string
+	PriceType  string
+	LimitPrice float64
+	Volume     int64
+	// Result 操作结果: "ok" 或失败原因
+	Result string
+}
+
+// AuditLogger 下单审计日志接口: 合规场景要求每笔报撤单的请求与
+// 结果落入独立存储, 持久化与轮转由实现方负责
+type AuditLogger interface {
+	Audit(e *AuditEntry)
+}
+
+// auditLog 补全会话字段后写入审计日志, 未配置 AuditLogger 时为空操作
+func (s *TradeSession) auditLog(e *AuditEntry) {
+	if s.audit == nil {
+		return
+	}
+	e.Time = time.Now()
+	e.Broker = s.broker
+	e.UserID = s.userID
+	s.audit.Audit(e)
+}
+
+// auditResult 把操作错误转成审计记录的 Result 字段
+func auditResult(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return err.Error()
+}
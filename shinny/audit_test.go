@@ -0,0 +1,88 @@
+package shinny
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// memoryAuditLogger 测试用审计日志, 记录全部条目
+type memoryAuditLogger struct {
+	mu      sync.Mutex
+	entries []*AuditEntry
+}
+
+func (m *memoryAuditLogger) Audit(e *AuditEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries = append(m.entries, e)
+}
+
+func (m *memoryAuditLogger) all() []*AuditEntry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*AuditEntry(nil), m.entries...)
+}
+
+func TestAuditLoggerRecordsOrders(t *testing.T) {
+	audit := &memoryAuditLogger{}
+	dm := NewDataManager()
+	ws := NewTqTradeWebsocket(&WebSocketConfig{}, dm, nil)
+	session := newTradeSession(ws, dm, "simnow", "user1", defaultLogger(), WithAuditLogger(audit))
+
+	o, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 3, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder: %v", err)
+	}
+	if err := session.CancelOrder(context.Background(), o.OrderID); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+
+	entries := audit.all()
+	if len(entries) != 2 {
+		t.Fatalf("audit entries = %d, want 2", len(entries))
+	}
+	in := entries[0]
+	if in.Action != AuditActionInsert || in.Result != "ok" {
+		t.Fatalf("insert entry = %+v", in)
+	}
+	if in.Broker != "simnow" || in.UserID != "user1" || in.Time.IsZero() {
+		t.Fatalf("insert entry missing session fields: %+v", in)
+	}
+	if in.Symbol != "SHFE.cu2601" || in.Direction != DirectionBuy || in.Offset != OffsetOpen {
+		t.Fatalf("insert entry contract fields = %+v", in)
+	}
+	if in.Volume != 3 || in.PriceType != PriceTypeLimit || in.LimitPrice != 68000 || in.OrderID != o.OrderID {
+		t.Fatalf("insert entry order fields = %+v", in)
+	}
+	cancel := entries[1]
+	if cancel.Action != AuditActionCancel || cancel.OrderID != o.OrderID || cancel.Result != "ok" {
+		t.Fatalf("cancel entry = %+v", cancel)
+	}
+}
+
+func TestAuditLoggerRecordsFailures(t *testing.T) {
+	audit := &memoryAuditLogger{}
+	dm := NewDataManager()
+	ws := NewTqTradeWebsocket(&WebSocketConfig{}, dm, nil)
+	session := newTradeSession(ws, dm, "simnow", "user1", defaultLogger(),
+		WithAuditLogger(audit), WithReadOnly())
+
+	if _, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	}); err == nil {
+		t.Fatal("read-only insert should fail")
+	}
+
+	entries := audit.all()
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %d, want 1", len(entries))
+	}
+	if entries[0].Result != ErrReadOnly.Error() {
+		t.Fatalf("failure result = %q, want %q", entries[0].Result, ErrReadOnly.Error())
+	}
+}
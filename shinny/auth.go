@@ -0,0 +1,181 @@
+package shinny
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// AuthServerURL 信易账户认证服务地址
+var AuthServerURL = "https://auth.shinnytech.com/auth/realms/shinnytech/protocol/openid-connect/token"
+
+// limitedIndexes 免费账户也可订阅的指数合约前缀
+var limitedIndexes = map[string]bool{
+	"KQ.i@SHFE.au": true,
+	"KQ.i@SHFE.ag": true,
+	"KQ.i@SHFE.cu": true,
+	"KQ.i@CZCE.TA": true,
+	"KQ.i@DCE.m":   true,
+}
+
+// featureExchanges 各功能权限可访问的交易所: futr 覆盖全部期货/期权
+// 交易所, sec 覆盖股票交易所. lmt_idx 只放开部分指数合约, 不单独
+// 对应交易所
+var featureExchanges = map[string][]string{
+	"futr": {"CFFEX", "CZCE", "DCE", "GFEX", "INE", "SHFE"},
+	"sec":  {"SSE", "SZSE"},
+}
+
+// TqAuth 信易账户, 登录后持有访问令牌与权限列表
+type TqAuth struct {
+	UserName string
+	Password string
+
+	accessToken string
+	// grants 为账户拥有的功能权限, 如 futr / lmt_idx / tq_dm / adv
+	grants map[string]interface{}
+	// features 从 grants 解析出的功能列表
+	features []string
+	// otgIDs 从 grants 解析出的可登录期货公司列表
+	otgIDs []string
+
+	httpClient *http.Client
+}
+
+func NewTqAuth(userName, password string) *TqAuth {
+	return &TqAuth{
+		UserName:   userName,
+		Password:   password,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// Login 向认证服务器换取访问令牌与权限
+func (a *TqAuth) Login(ctx context.Context) error {
+	form := url.Values{}
+	form.Set("grant_type", "password")
+	form.Set("username", a.UserName)
+	form.Set("password", a.Password)
+	form.Set("client_id", "shinny_tq")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, AuthServerURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", UserAgent)
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("shinny: auth login failed: %s", resp.Status)
+	}
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+	a.accessToken = body.AccessToken
+	a.parseGrants()
+	return nil
+}
+
+// parseGrants 从 access token 的 payload 解析 grants
+func (a *TqAuth) parseGrants() {
+	parts := strings.Split(a.accessToken, ".")
+	if len(parts) != 3 {
+		return
+	}
+	payload, err := base64URLDecode(parts[1])
+	if err != nil {
+		return
+	}
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return
+	}
+	if grants, ok := claims["grants"].(map[string]interface{}); ok {
+		a.setGrants(grants)
+	}
+}
+
+func (a *TqAuth) setGrants(grants map[string]interface{}) {
+	a.grants = grants
+	a.features = a.features[:0]
+	if fs, ok := grants["features"].([]interface{}); ok {
+		for _, f := range fs {
+			a.features = append(a.features, toString(f))
+		}
+	}
+	a.otgIDs = a.otgIDs[:0]
+	if ids, ok := grants["otg_ids"].([]interface{}); ok {
+		for _, id := range ids {
+			a.otgIDs = append(a.otgIDs, toString(id))
+		}
+	}
+}
+
+// Brokers 返回 grants 里授权的期货公司列表
+func (a *TqAuth) Brokers() []string {
+	return append([]string{}, a.otgIDs...)
+}
+
+func (a *TqAuth) hasFeature(name string) bool {
+	for _, f := range a.features {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// HasMdGrants 校验账户是否有给定合约的行情权限,
+// 任一合约无权限时返回错误
+func (a *TqAuth) HasMdGrants(symbols ...string) error {
+	_, unauthorized := a.FilterMdGrants(symbols...)
+	if len(unauthorized) > 0 {
+		return fmt.Errorf("shinny: no market data grants for %s", strings.Join(unauthorized, ","))
+	}
+	return nil
+}
+
+// FilterMdGrants 按行情权限把合约分为可订阅与无权限两组
+func (a *TqAuth) FilterMdGrants(symbols ...string) (granted, unauthorized []string) {
+	for _, symbol := range symbols {
+		if a.hasMdGrant(symbol) {
+			granted = append(granted, symbol)
+		} else {
+			unauthorized = append(unauthorized, symbol)
+		}
+	}
+	return granted, unauthorized
+}
+
+func (a *TqAuth) hasMdGrant(symbol string) bool {
+	if strings.HasPrefix(symbol, "KQ.i@") {
+		return limitedIndexes[symbol] || a.hasFeature("lmt_idx")
+	}
+	return a.hasFeature("futr")
+}
+
+// HasTdGrants 校验账户是否有交易权限
+func (a *TqAuth) HasTdGrants() error {
+	if a.hasFeature("futr") {
+		return nil
+	}
+	return fmt.Errorf("shinny: account %s has no trade grants", a.UserName)
+}
+
+func base64URLDecode(s string) ([]byte, error) {
+	if m := len(s) % 4; m != 0 {
+		s += strings.Repeat("=", 4-m)
+	}
+	return base64.URLEncoding.DecodeString(s)
+}
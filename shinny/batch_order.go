@@ -0,0 +1,94 @@
+package shinny
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrBatchAborted 原子批量下单中因其他订单校验失败而未提交的订单错误
+var ErrBatchAborted = errors.New("shinny: batch order aborted by failed sibling order")
+
+// batchOptions 批量下单选项集合
+type batchOptions struct {
+	// atomic 原子模式: 提交前先校验全部订单, 任一校验失败则整批不下
+	atomic bool
+}
+
+// BatchOption 批量下单选项
+type BatchOption func(*batchOptions)
+
+// WithAtomicBatch 启用原子模式: 先对整批订单做本地校验, 任一失败则
+// 一笔都不提交, 未通过的订单返回其校验错误, 其余返回 ErrBatchAborted.
+// 注意原子性只覆盖本地校验, 提交后的拒单仍按逐笔结果返回
+func WithAtomicBatch() BatchOption {
+	return func(o *batchOptions) {
+		o.atomic = true
+	}
+}
+
+// validateOrderRequest 下单请求的本地静态校验
+func validateOrderRequest(req *InsertOrderRequest) error {
+	if req == nil {
+		return fmt.Errorf("shinny: nil order request")
+	}
+	if req.Symbol == "" {
+		return fmt.Errorf("shinny: order request missing symbol")
+	}
+	if req.Direction != DirectionBuy && req.Direction != DirectionSell {
+		return fmt.Errorf("shinny: invalid order direction %q", req.Direction)
+	}
+	if req.Offset != OffsetOpen && req.Offset != OffsetClose && req.Offset != OffsetCloseToday {
+		return fmt.Errorf("shinny: invalid order offset %q", req.Offset)
+	}
+	if req.Volume <= 0 {
+		return fmt.Errorf("shinny: invalid order volume %d", req.Volume)
+	}
+	if req.PriceType == PriceTypeLimit && req.LimitPrice <= 0 {
+		return fmt.Errorf("shinny: limit order requires positive limit price")
+	}
+	return nil
+}
+
+// insertOrdersVia 逐笔提交批量订单: 先本地校验, 原子模式下任一校验
+// 失败则整批不提交, 否则校验失败的跳过、其余照常提交
+func insertOrdersVia(ctx context.Context, insert func(context.Context, *InsertOrderRequest) (*Order, error), reqs []*InsertOrderRequest, opts ...BatchOption) ([]*Order, []error) {
+	var bo batchOptions
+	for _, opt := range opts {
+		opt(&bo)
+	}
+	orders := make([]*Order, len(reqs))
+	errs := make([]error, len(reqs))
+	for i, req := range reqs {
+		errs[i] = validateOrderRequest(req)
+	}
+	if bo.atomic {
+		for _, err := range errs {
+			if err != nil {
+				for i := range errs {
+					if errs[i] == nil {
+						errs[i] = ErrBatchAborted
+					}
+				}
+				return orders, errs
+			}
+		}
+	}
+	for i, req := range reqs {
+		if errs[i] != nil {
+			continue
+		}
+		orders[i], errs[i] = insert(ctx, req)
+	}
+	return orders, errs
+}
+
+// InsertOrders 批量下单 (篮子单), 返回与 reqs 一一对应的订单与错误
+func (s *TradeSession) InsertOrders(ctx context.Context, reqs []*InsertOrderRequest, opts ...BatchOption) ([]*Order, []error) {
+	return insertOrdersVia(ctx, s.InsertOrder, reqs, opts...)
+}
+
+// InsertOrders 批量下单 (篮子单), 返回与 reqs 一一对应的订单与错误
+func (v *VirtualTrader) InsertOrders(ctx context.Context, reqs []*InsertOrderRequest, opts ...BatchOption) ([]*Order, []error) {
+	return insertOrdersVia(ctx, v.InsertOrder, reqs, opts...)
+}
@@ -0,0 +1,804 @@
+package shinny
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MdServerURLs 默认行情服务器地址
+var MdServerURLs = []string{
+	"wss://openmd.shinnytech.com/t/md/front/mobile",
+}
+
+// ClientConfig 客户端配置
+type ClientConfig struct {
+	// MdURLs 行情服务器候选地址, 为空使用默认地址
+	MdURLs []string
+	// CacheDir 合约信息缓存目录, 为空使用系统临时目录
+	CacheDir string
+	// MdShards 行情连接数, > 1 时订阅按合约哈希拆分到多条连接,
+	// 用于突破单连接订阅数/带宽上限, 各连接独立重连, 默认 1
+	MdShards int
+	// Log 日志配置
+	Log *LogConfig
+}
+
+// Client 是 SDK 的入口, 持有行情连接/数据截面/订阅管理,
+// 交易账户通过 LoginTrade 建立独立会话
+type Client struct {
+	auth   *TqAuth
+	conf   *ClientConfig
+	logger *slog.Logger
+
+	dm *DataManager
+	// quoteShards 行情连接, 至少一条; quotesWs 指向首条连接,
+	// 图表(K线/Tick)订阅走该连接
+	quoteShards []*TqQuoteWebsocket
+	quotesWs    *TqQuoteWebsocket
+	quoteSub    *QuoteSubscription
+
+	// quotesInfo 全量合约信息, InitMarket 时加载; 同进程多个 Client
+	// 可能共享同一份 map (见 symbolsMemCache), 只读不得修改
+	quotesInfo map[string]*SymbolInfo
+
+	// aliases 自定义合约代码别名 -> 标准 symbol, 见 RegisterAlias
+	aliases map[string]string
+
+	// tdURLs / tdURLResolver 交易服务器地址覆盖, 私有部署或测试环境
+	// 跳过 files.shinnytech.com 解析
+	tdURLs        map[string]string
+	tdURLResolver func(ctx context.Context, broker string) (string, error)
+
+	mu       sync.Mutex
+	sessions []*TradeSession
+	// seriesSubs 活跃的图表订阅, 用于导出订阅配置
+	seriesSubs []*SeriesSubscription
+
+	expiringWatches []*expiringWatch
+	expiringOnce    sync.Once
+
+	// closeCh Close 时关闭, 通知客户端内部的后台 goroutine 退出
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// expiringWatch 一个合约到期预警注册项
+type expiringWatch struct {
+	daysBefore int
+	handler    func(symbol string, expireTime time.Time)
+	// notified 已提醒过的合约, 每个合约只提醒一次
+	notified map[string]bool
+}
+
+func NewClient(auth *TqAuth, conf *ClientConfig) *Client {
+	if conf == nil {
+		conf = &ClientConfig{}
+	}
+	logger := NewLogger(conf.Log)
+	dm := NewDataManager()
+	dm.logger = logger
+	urls := conf.MdURLs
+	if len(urls) == 0 {
+		urls = MdServerURLs
+	}
+	c := &Client{
+		auth:    auth,
+		conf:    conf,
+		logger:  logger,
+		dm:      dm,
+		closeCh: make(chan struct{}),
+	}
+	shards := conf.MdShards
+	if shards < 1 {
+		shards = 1
+	}
+	for i := 0; i < shards; i++ {
+		c.quoteShards = append(c.quoteShards, NewTqQuoteWebsocket(&WebSocketConfig{URLs: urls}, dm, logger))
+	}
+	c.quotesWs = c.quoteShards[0]
+	c.quoteSub = newQuoteSubscription(c.quoteShards, dm, logger)
+	// 连接断开时把该连接承载的行情标记为过期
+	for i, ws := range c.quoteShards {
+		i := i
+		ws.OnClose(func() { c.quoteSub.markStale(i) })
+	}
+	return c
+}
+
+// DataManager 返回底层数据截面
+func (c *Client) DataManager() *DataManager {
+	return c.dm
+}
+
+// QuotesInfo 返回全量合约信息
+func (c *Client) QuotesInfo() map[string]*SymbolInfo {
+	return c.quotesInfo
+}
+
+// SymbolsByInstrument 根据交易所原始代码 (如 rb2505) 反查完整合约
+// 代码, 同名代码可能存在于多个交易所, 返回全部匹配并按字典序排序.
+// 需先 InitMarket 加载合约表
+func (c *Client) SymbolsByInstrument(instrumentID string) []string {
+	var out []string
+	for sym := range c.quotesInfo {
+		if _, ins := splitSymbol(sym); ins == instrumentID {
+			out = append(out, sym)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// AvailableGrants 返回账户的权限范围: features 为 grants 里的功能
+// 权限列表, exchanges 为据此推导出的可访问行情的交易所列表, 均按
+// 字典序排列. 启动时预查可避免运行中才发现订阅无权限
+func (c *Client) AvailableGrants() (features []string, exchanges []string) {
+	features = append([]string{}, c.auth.features...)
+	sort.Strings(features)
+	seen := make(map[string]struct{})
+	for _, f := range features {
+		for _, ex := range featureExchanges[f] {
+			if _, ok := seen[ex]; ok {
+				continue
+			}
+			seen[ex] = struct{}{}
+			exchanges = append(exchanges, ex)
+		}
+	}
+	sort.Strings(exchanges)
+	return features, exchanges
+}
+
+// ValidateVolume 校验下单量是否满足合约的最小/最大限价单手数限制,
+// 需先 InitMarket 加载合约表. 组合合约 (class=COMBINE) 的手数规则由
+// 腿合约决定, 跳过校验; 未登记最小手数的合约按 1 手起
+func (c *Client) ValidateVolume(symbol string, volume int64) error {
+	symbol = c.ResolveSymbol(symbol)
+	info := c.quotesInfo[symbol]
+	if info == nil {
+		return fmt.Errorf("shinny: no symbol info for %s, call InitMarket first", symbol)
+	}
+	if info.Class == "COMBINE" {
+		return nil
+	}
+	if volume <= 0 {
+		return fmt.Errorf("shinny: invalid order volume %d for %s", volume, symbol)
+	}
+	min := info.MinLimitOrderVolume
+	if min <= 0 {
+		min = 1
+	}
+	if volume < min {
+		return fmt.Errorf("shinny: order volume %d below minimum %d for %s", volume, min, symbol)
+	}
+	if max := info.MaxLimitOrderVolume; max > 0 && volume > max {
+		return fmt.Errorf("shinny: order volume %d exceeds maximum %d for %s", volume, max, symbol)
+	}
+	return nil
+}
+
+// wsDialTimeout 可达性预检的 TCP 拨号超时
+const wsDialTimeout = 3 * time.Second
+
+// wsPrecheckDial 可达性预检使用的拨号函数, 测试中可替换
+var wsPrecheckDial = func(addr string) error {
+	conn, err := net.DialTimeout("tcp", addr, wsDialTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// validateWsURL 校验 websocket 地址: scheme 必须为 ws/wss 且 host
+// 非空, precheck 为 true 时再对 host 做一次 TCP 可达性预检.
+// 行情交易分服务器部署时地址容易配错, 在建连前返回明确错误,
+// 避免之后静默重连失败
+func validateWsURL(raw string, precheck bool) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("shinny: invalid websocket url %q: %w", raw, err)
+	}
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return fmt.Errorf("shinny: websocket url %q: unsupported scheme %q, want ws or wss", raw, u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("shinny: websocket url %q: missing host", raw)
+	}
+	if precheck {
+		addr := u.Host
+		if u.Port() == "" {
+			port := "80"
+			if u.Scheme == "wss" {
+				port = "443"
+			}
+			addr = net.JoinHostPort(u.Hostname(), port)
+		}
+		if err := wsPrecheckDial(addr); err != nil {
+			return fmt.Errorf("shinny: websocket server %s unreachable: %w", addr, err)
+		}
+	}
+	return nil
+}
+
+// InitMarket 完成账户认证, 加载合约信息并建立行情连接
+func (c *Client) InitMarket(ctx context.Context) error {
+	urls := c.conf.MdURLs
+	if len(urls) == 0 {
+		urls = MdServerURLs
+	}
+	for _, u := range urls {
+		if err := validateWsURL(u, true); err != nil {
+			return err
+		}
+	}
+	if err := c.auth.Login(ctx); err != nil {
+		return err
+	}
+	if err := c.loadSymbols(ctx); err != nil {
+		return err
+	}
+	for _, ws := range c.quoteShards {
+		if err := ws.Init(ctx); err != nil {
+			return err
+		}
+	}
+	// 确保全部行情连接就绪, 返回后即可直接订阅
+	for _, ws := range c.quoteShards {
+		if err := ws.WaitReady(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EnableLivePositionProfit 用最新行情实时重算 session 持仓浮盈:
+// 订阅当前持仓合约的行情, 每次 Quote 更新时重算该合约持仓的浮盈
+// (基于快照副本, 不回写截面) 并通过 handler 推送; 成交产生的新持仓
+// 合约自动补订阅
+func (c *Client) EnableLivePositionProfit(s *TradeSession, handler func(*Position)) {
+	if positions, err := s.readPositions(); err == nil {
+		var symbols []string
+		for symbol := range positions {
+			symbols = append(symbols, symbol)
+		}
+		if len(symbols) > 0 {
+			c.SubscribeQuote(context.Background(), symbols...)
+		}
+	}
+	s.OnTrade(func(t *Trade) {
+		c.SubscribeQuote(context.Background(), joinSymbol(t.ExchangeID, t.InstrumentID))
+	})
+	c.OnQuote(func(q *Quote) {
+		if q.VolumeMultiple <= 0 {
+			return
+		}
+		positions, err := s.readPositions()
+		if err != nil {
+			return
+		}
+		pos, ok := positions[q.Symbol()]
+		if !ok || pos.VolumeLong+pos.VolumeShort == 0 {
+			return
+		}
+		pos.RecalcFloatProfit(q.LastPrice, q.VolumeMultiple)
+		handler(pos)
+	})
+}
+
+// RegisterAlias 注册自定义合约代码别名 (如 myRB -> SHFE.rb2505):
+// 订阅与下单接口收到 alias 时内部转换为标准 symbol. 重复注册同一
+// 别名时覆盖旧映射
+func (c *Client) RegisterAlias(alias, symbol string) error {
+	if alias == "" || symbol == "" {
+		return errors.New("shinny: alias and symbol must not be empty")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.aliases == nil {
+		c.aliases = make(map[string]string)
+	}
+	c.aliases[alias] = symbol
+	return nil
+}
+
+// ResolveSymbol 返回别名对应的标准合约代码, 未注册别名的输入原样返回
+func (c *Client) ResolveSymbol(symbol string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if resolved, ok := c.aliases[symbol]; ok {
+		return resolved
+	}
+	return symbol
+}
+
+func (c *Client) resolveSymbols(symbols []string) []string {
+	out := make([]string, len(symbols))
+	for i, s := range symbols {
+		out[i] = c.ResolveSymbol(s)
+	}
+	return out
+}
+
+// SubscribeQuote 订阅一批合约行情, 无行情权限的合约被跳过并通过
+// unauthorized 返回, 其余合约正常订阅; 合约代码支持 RegisterAlias
+// 注册的别名
+func (c *Client) SubscribeQuote(ctx context.Context, symbols ...string) (unauthorized []string, err error) {
+	symbols = c.resolveSymbols(symbols)
+	granted, unauthorized := c.auth.FilterMdGrants(symbols...)
+	if len(unauthorized) > 0 {
+		c.logger.Warn("subscribe quote skipped unauthorized symbols", "symbols", unauthorized)
+	}
+	if len(granted) == 0 {
+		if len(unauthorized) > 0 {
+			return unauthorized, c.auth.HasMdGrants(symbols...)
+		}
+		return nil, nil
+	}
+	return unauthorized, c.quoteSub.AddSymbols(granted...)
+}
+
+// subscribeExchangeBatch 全交易所订阅时单次 AddSymbols 的合约数上限,
+// 避免一次拼出过大的订阅报文
+const subscribeExchangeBatch = 500
+
+// SubscribeExchange 订阅一个交易所的全部在市合约行情, 合约列表从
+// quotesInfo 筛选 (需先 InitMarket), 已下市合约跳过; 内部分批订阅,
+// 无行情权限的合约通过 unauthorized 返回
+func (c *Client) SubscribeExchange(ctx context.Context, exchange string) (unauthorized []string, err error) {
+	var symbols []string
+	for symbol, info := range c.quotesInfo {
+		if info.ExchangeID != exchange || info.Expired {
+			continue
+		}
+		symbols = append(symbols, symbol)
+	}
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("shinny: no listed instruments for exchange %s, call InitMarket first", exchange)
+	}
+	sort.Strings(symbols)
+	for start := 0; start < len(symbols); start += subscribeExchangeBatch {
+		end := start + subscribeExchangeBatch
+		if end > len(symbols) {
+			end = len(symbols)
+		}
+		skipped, err := c.SubscribeQuote(ctx, symbols[start:end]...)
+		if err != nil {
+			return unauthorized, err
+		}
+		unauthorized = append(unauthorized, skipped...)
+	}
+	return unauthorized, nil
+}
+
+// SubscribeQuoteHighPriority 订阅核心合约并标记为高优先级: 截面变更
+// 时先于普通合约分发, SymbolChannel 使用更大缓冲, 不受降频影响
+func (c *Client) SubscribeQuoteHighPriority(ctx context.Context, symbols ...string) (unauthorized []string, err error) {
+	c.quoteSub.SetHighPriority(symbols...)
+	return c.SubscribeQuote(ctx, symbols...)
+}
+
+// SetLowPriorityInterval 设置普通合约的最小分发间隔, 间隔内的多次
+// 更新只分发最新截面, 用于大量次要合约订阅时降低回调压力
+func (c *Client) SetLowPriorityInterval(interval time.Duration) {
+	c.quoteSub.SetLowPriorityInterval(interval)
+}
+
+// UnsubscribeQuote 退订一批合约行情, 与 SubscribeQuote 一样支持别名
+func (c *Client) UnsubscribeQuote(ctx context.Context, symbols ...string) error {
+	return c.quoteSub.RemoveSymbols(c.resolveSymbols(symbols)...)
+}
+
+// IsSubscribed 判断一个合约是否已确认订阅, 支持别名
+func (c *Client) IsSubscribed(symbol string) bool {
+	return c.quoteSub.IsSubscribed(c.ResolveSymbol(symbol))
+}
+
+// SubscribedSymbols 返回已确认订阅的合约列表
+func (c *Client) SubscribedSymbols() []string {
+	return c.quoteSub.SubscribedSymbols()
+}
+
+// OnQuote 注册行情回调
+func (c *Client) OnQuote(handler func(*Quote)) {
+	c.quoteSub.OnQuote(handler)
+}
+
+// OnFirstQuote 注册合约首个有效行情回调, 每个合约只触发一次
+func (c *Client) OnFirstQuote(handler func(*Quote)) {
+	c.quoteSub.OnFirstQuote(handler)
+}
+
+// OnQuoteWithType 注册区分快照/增量的行情回调: 每个合约首次收齐
+// 有效数据时以 isSnapshot=true 触发, 之后均为增量更新
+func (c *Client) OnQuoteWithType(handler func(q *Quote, isSnapshot bool)) {
+	c.quoteSub.OnQuoteWithType(handler)
+}
+
+// OnStale 注册行情过期回调: 行情连接断开时以受影响的合约列表触发,
+// 受影响合约在重连收到新数据前 Quote.IsStale 为 true
+func (c *Client) OnStale(handler func(symbols []string)) {
+	c.quoteSub.OnStale(handler)
+}
+
+// EnableOrderBook 为一批合约启用订单薄维护, 需配合行情订阅使用
+func (c *Client) EnableOrderBook(symbols ...string) {
+	c.quoteSub.EnableOrderBook(c.resolveSymbols(symbols)...)
+}
+
+// OrderBook 返回合约的订单薄, 未启用时返回 nil
+func (c *Client) OrderBook(symbol string) *OrderBook {
+	return c.quoteSub.OrderBook(c.ResolveSymbol(symbol))
+}
+
+// GetQuote 读取合约当前行情快照, 合约代码支持别名
+func (c *Client) GetQuote(symbol string) (*Quote, error) {
+	return c.quoteSub.GetQuote(c.ResolveSymbol(symbol))
+}
+
+// SymbolChannel 返回指定合约的行情推送通道, 支持别名
+func (c *Client) SymbolChannel(symbol string) <-chan *Quote {
+	return c.quoteSub.Channel(c.ResolveSymbol(symbol))
+}
+
+// GetQuotes 批量获取多个合约的当前行情快照: 未订阅的合约自动订阅,
+// 阻塞等待所有合约收到有效数据. ctx 结束时返回已拿到的部分快照和错误
+func (c *Client) GetQuotes(ctx context.Context, symbols []string) (map[string]*Quote, error) {
+	var missing []string
+	for _, s := range symbols {
+		if !c.quoteSub.IsSubscribed(s) {
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) > 0 {
+		if _, err := c.SubscribeQuote(ctx, missing...); err != nil {
+			return nil, err
+		}
+	}
+
+	quotes := make(map[string]*Quote, len(symbols))
+	for {
+		ready := true
+		for _, s := range symbols {
+			if quotes[s] != nil {
+				continue
+			}
+			q, err := c.GetQuote(s)
+			if err != nil || !q.IsValid() {
+				ready = false
+				continue
+			}
+			quotes[s] = q
+		}
+		if ready {
+			return quotes, nil
+		}
+		select {
+		case <-ctx.Done():
+			return quotes, fmt.Errorf("shinny: get quotes wait interrupted: %w", ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+// rankQuotes 按 metric 降序排列合约: 未订阅的合约自动订阅,
+// 等待所有合约出现有效行情后排序, ctx 结束时返回错误
+func (c *Client) rankQuotes(ctx context.Context, symbols []string, metric func(*Quote) float64) ([]string, error) {
+	var missing []string
+	for _, s := range symbols {
+		if !c.quoteSub.IsSubscribed(s) {
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) > 0 {
+		if _, err := c.SubscribeQuote(ctx, missing...); err != nil {
+			return nil, err
+		}
+	}
+
+	quotes := make(map[string]*Quote, len(symbols))
+	for {
+		ready := true
+		for _, s := range symbols {
+			if quotes[s] != nil {
+				continue
+			}
+			q, err := c.GetQuote(s)
+			if err != nil || !q.IsValid() {
+				ready = false
+				continue
+			}
+			quotes[s] = q
+		}
+		if ready {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("shinny: rank quotes wait interrupted: %w", ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	out := append([]string{}, symbols...)
+	sort.SliceStable(out, func(i, j int) bool {
+		return metric(quotes[out[i]]) > metric(quotes[out[j]])
+	})
+	return out, nil
+}
+
+// RankByVolume 按最新行情的当日成交量从高到低排列合约,
+// 未订阅的合约自动订阅并等待数据, 用于选主力或筛活跃合约
+func (c *Client) RankByVolume(ctx context.Context, symbols ...string) ([]string, error) {
+	return c.rankQuotes(ctx, symbols, func(q *Quote) float64 { return float64(q.Volume) })
+}
+
+// RankByOpenInterest 按最新行情的持仓量从高到低排列合约,
+// 未订阅的合约自动订阅并等待数据
+func (c *Client) RankByOpenInterest(ctx context.Context, symbols ...string) ([]string, error) {
+	return c.rankQuotes(ctx, symbols, func(q *Quote) float64 { return float64(q.OpenInterest) })
+}
+
+// subscriptionSnapshot 订阅配置的序列化形式, 见 ExportSubscriptions
+type subscriptionSnapshot struct {
+	Quotes       []string         `json:"quotes,omitempty"`
+	HighPriority []string         `json:"high_priority,omitempty"`
+	Series       []seriesSnapshot `json:"series,omitempty"`
+}
+
+// seriesSnapshot 一个图表订阅的配置, Duration 为纳秒, 0 表示 Tick
+type seriesSnapshot struct {
+	Symbols   []string `json:"symbols"`
+	Duration  int64    `json:"duration"`
+	ViewWidth int      `json:"view_width"`
+}
+
+// ExportSubscriptions 序列化当前全部 Quote/Series 订阅配置,
+// 可持久化后通过 ImportSubscriptions 在新会话重建
+func (c *Client) ExportSubscriptions() ([]byte, error) {
+	snap := subscriptionSnapshot{
+		Quotes:       c.quoteSub.Symbols(),
+		HighPriority: c.quoteSub.HighPrioritySymbols(),
+	}
+	c.mu.Lock()
+	subs := append([]*SeriesSubscription{}, c.seriesSubs...)
+	c.mu.Unlock()
+	for _, ss := range subs {
+		ss.mu.Lock()
+		closed := ss.closed
+		entry := seriesSnapshot{
+			Symbols:   append([]string{}, ss.symbols...),
+			Duration:  ss.duration.Nanoseconds(),
+			ViewWidth: ss.viewWidth,
+		}
+		ss.mu.Unlock()
+		if closed {
+			continue
+		}
+		snap.Series = append(snap.Series, entry)
+	}
+	return json.Marshal(snap)
+}
+
+// ImportSubscriptions 按 ExportSubscriptions 导出的配置重建订阅
+func (c *Client) ImportSubscriptions(ctx context.Context, data []byte) error {
+	var snap subscriptionSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+	if len(snap.HighPriority) > 0 {
+		c.quoteSub.SetHighPriority(snap.HighPriority...)
+	}
+	if len(snap.Quotes) > 0 {
+		if _, err := c.SubscribeQuote(ctx, snap.Quotes...); err != nil {
+			return err
+		}
+	}
+	api := c.Series()
+	for _, entry := range snap.Series {
+		var err error
+		switch {
+		case entry.Duration <= 0:
+			_, err = api.Tick(ctx, entry.Symbols[0], entry.ViewWidth)
+		case len(entry.Symbols) > 1:
+			_, err = api.MultiKline(ctx, entry.Symbols, time.Duration(entry.Duration), entry.ViewWidth)
+		default:
+			_, err = api.Kline(ctx, entry.Symbols[0], time.Duration(entry.Duration), entry.ViewWidth)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithTdURL 指定期货公司的交易服务器地址, LoginTrade 时优先使用,
+// 不再从 files.shinnytech.com 解析
+func (c *Client) WithTdURL(broker, url string) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tdURLs == nil {
+		c.tdURLs = make(map[string]string)
+	}
+	c.tdURLs[broker] = url
+	return c
+}
+
+// WithTdURLResolver 注入交易服务器地址解析函数, 优先级低于
+// WithTdURL 的显式配置, 高于默认的 files.shinnytech.com 解析
+func (c *Client) WithTdURLResolver(resolver func(ctx context.Context, broker string) (string, error)) *Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tdURLResolver = resolver
+	return c
+}
+
+// resolveTdURL 解析期货公司交易服务器地址: 显式配置 > 自定义解析
+// 函数 > files.shinnytech.com
+func (c *Client) resolveTdURL(ctx context.Context, broker string) (string, error) {
+	c.mu.Lock()
+	url, ok := c.tdURLs[broker]
+	resolver := c.tdURLResolver
+	c.mu.Unlock()
+	if ok {
+		return url, nil
+	}
+	if resolver != nil {
+		return resolver(ctx, broker)
+	}
+	return GetTdUrl(ctx, broker)
+}
+
+// LoginTrade 建立交易会话并登录期货公司柜台
+func (c *Client) LoginTrade(ctx context.Context, broker, userID, password string, opts ...TradeOption) (*TradeSession, error) {
+	if err := c.auth.HasTdGrants(); err != nil {
+		return nil, err
+	}
+	tdURL, err := c.resolveTdURL(ctx, broker)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateWsURL(tdURL, true); err != nil {
+		return nil, err
+	}
+	dm := NewDataManager()
+	dm.logger = c.logger
+	tradeWs := NewTqTradeWebsocket(&WebSocketConfig{URLs: []string{tdURL}}, dm, c.logger)
+	if err := tradeWs.Init(ctx); err != nil {
+		return nil, err
+	}
+	if err := tradeWs.ReqLogin(broker, userID, password); err != nil {
+		tradeWs.Close()
+		return nil, err
+	}
+	session := newTradeSession(tradeWs, dm, broker, userID, c.logger, opts...)
+	session.symbolInfo = func(symbol string) *SymbolInfo { return c.quotesInfo[symbol] }
+	session.quoteSnapshot = c.GetQuote
+	session.resolveSymbol = c.ResolveSymbol
+	session.validateVolume = c.ValidateVolume
+	c.mu.Lock()
+	c.sessions = append(c.sessions, session)
+	c.mu.Unlock()
+	return session, nil
+}
+
+// GetBrokers 返回账户可登录的期货公司列表: 优先使用交易服务器
+// 下发的 rtn_brokers, 否则从认证 grants 的 otg_ids 推导
+func (c *Client) GetBrokers(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	sessions := append([]*TradeSession{}, c.sessions...)
+	c.mu.Unlock()
+	for _, s := range sessions {
+		if brokers := s.ws.Brokers(); len(brokers) > 0 {
+			return brokers, nil
+		}
+	}
+	if brokers := c.auth.Brokers(); len(brokers) > 0 {
+		return brokers, nil
+	}
+	return nil, errors.New("shinny: no broker list available, login first")
+}
+
+// ServerTime 返回估算的服务器时间: 最近一次行情更新的时间戳,
+// 尚无行情时退回本地时间
+func (c *Client) ServerTime() time.Time {
+	if dt := c.quoteSub.LatestDatetime(); dt != "" {
+		if t, err := parseQuoteDatetime(dt); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+// OnContractExpiring 注册合约到期预警: 任一交易会话的持仓合约距交割
+// 不足 daysBefore 天时回调提醒, 每个合约只提醒一次
+func (c *Client) OnContractExpiring(daysBefore int, handler func(symbol string, expireTime time.Time)) {
+	c.mu.Lock()
+	c.expiringWatches = append(c.expiringWatches, &expiringWatch{
+		daysBefore: daysBefore,
+		handler:    handler,
+		notified:   make(map[string]bool),
+	})
+	c.mu.Unlock()
+	c.expiringOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-c.closeCh:
+					return
+				case <-ticker.C:
+					c.checkExpiring()
+				}
+			}
+		}()
+	})
+}
+
+// checkExpiring 扫描各会话持仓, 触发到期预警
+func (c *Client) checkExpiring() {
+	now := c.ServerTime()
+	c.mu.Lock()
+	sessions := append([]*TradeSession{}, c.sessions...)
+	watches := append([]*expiringWatch{}, c.expiringWatches...)
+	c.mu.Unlock()
+
+	held := make(map[string]bool)
+	for _, s := range sessions {
+		positions, err := s.readPositions()
+		if err != nil {
+			continue
+		}
+		for _, p := range positions {
+			if p.VolumeLong > 0 || p.VolumeShort > 0 {
+				held[p.ExchangeID+"."+p.InstrumentID] = true
+			}
+		}
+	}
+	for symbol := range held {
+		info, ok := c.quotesInfo[symbol]
+		if !ok || info.ExpireDatetime <= 0 {
+			continue
+		}
+		expire := time.Unix(int64(info.ExpireDatetime), 0)
+		for _, w := range watches {
+			if expire.Sub(now) >= time.Duration(w.daysBefore)*24*time.Hour {
+				continue
+			}
+			c.mu.Lock()
+			done := w.notified[symbol]
+			w.notified[symbol] = true
+			c.mu.Unlock()
+			if !done {
+				go w.handler(symbol, expire)
+			}
+		}
+	}
+}
+
+// Close 关闭所有连接
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() { close(c.closeCh) })
+	c.mu.Lock()
+	sessions := append([]*TradeSession(nil), c.sessions...)
+	c.mu.Unlock()
+	for _, s := range sessions {
+		s.Close()
+	}
+	var firstErr error
+	for _, ws := range c.quoteShards {
+		if err := ws.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	c.dm.Close()
+	return firstErr
+}
@@ -0,0 +1,553 @@
+package shinny
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestClient 构造一个不连接服务器的客户端, features 为账户权限
+func newTestClient(t *testing.T, features ...string) *Client {
+	t.Helper()
+	auth := NewTqAuth("tester", "secret")
+	grants := map[string]interface{}{"features": make([]interface{}, 0, len(features))}
+	for _, f := range features {
+		grants["features"] = append(grants["features"].([]interface{}), f)
+	}
+	auth.setGrants(grants)
+	return NewClient(auth, nil)
+}
+
+func TestSubscribeQuotePartialGrants(t *testing.T) {
+	client := newTestClient(t, "futr")
+
+	unauthorized, err := client.SubscribeQuote(context.Background(),
+		"SHFE.cu2601", "KQ.i@CFFEX.IF", "DCE.m2601")
+	if err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+	if len(unauthorized) != 1 || unauthorized[0] != "KQ.i@CFFEX.IF" {
+		t.Fatalf("unauthorized = %v, want [KQ.i@CFFEX.IF]", unauthorized)
+	}
+	symbols := client.quoteSub.Symbols()
+	if len(symbols) != 2 {
+		t.Fatalf("subscribed symbols = %v, want granted 2", symbols)
+	}
+	for _, s := range symbols {
+		if s == "KQ.i@CFFEX.IF" {
+			t.Fatal("unauthorized symbol was subscribed")
+		}
+	}
+}
+
+func TestOnContractExpiring(t *testing.T) {
+	client := newTestClient(t, "futr")
+
+	session, sdm := newTestTradeSession(t)
+	client.sessions = append(client.sessions, session)
+
+	now := time.Now()
+	client.quotesInfo = map[string]*SymbolInfo{
+		"SHFE.cu2609": {
+			ExchangeID:     "SHFE",
+			InstrumentID:   "cu2609",
+			ExpireDatetime: float64(now.Add(2 * 24 * time.Hour).Unix()),
+		},
+		"DCE.m2701": {
+			ExchangeID:     "DCE",
+			InstrumentID:   "m2701",
+			ExpireDatetime: float64(now.Add(90 * 24 * time.Hour).Unix()),
+		},
+	}
+	sdm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"positions": map[string]interface{}{
+					"SHFE.cu2609": map[string]interface{}{
+						"exchange_id": "SHFE", "instrument_id": "cu2609", "volume_long": 1.0,
+					},
+					"DCE.m2701": map[string]interface{}{
+						"exchange_id": "DCE", "instrument_id": "m2701", "volume_short": 1.0,
+					},
+				},
+			},
+		},
+	})
+
+	var mu sync.Mutex
+	var alerts []string
+	client.OnContractExpiring(5, func(symbol string, expireTime time.Time) {
+		mu.Lock()
+		alerts = append(alerts, symbol)
+		mu.Unlock()
+	})
+
+	client.checkExpiring()
+	client.checkExpiring() // 不应重复提醒
+	time.Sleep(50 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(alerts) != 1 || alerts[0] != "SHFE.cu2609" {
+		t.Fatalf("alerts = %v, want [SHFE.cu2609]", alerts)
+	}
+}
+
+func TestSubscribeQuoteAllUnauthorized(t *testing.T) {
+	client := newTestClient(t) // 无任何权限
+
+	unauthorized, err := client.SubscribeQuote(context.Background(), "SHFE.cu2601")
+	if err == nil {
+		t.Fatal("expected error when no symbol can be subscribed")
+	}
+	if len(unauthorized) != 1 {
+		t.Fatalf("unauthorized = %v", unauthorized)
+	}
+}
+
+func TestGetBrokersFromRtnBrokers(t *testing.T) {
+	client := newTestClient(t, "futr")
+	session, _ := newTestTradeSession(t)
+	client.sessions = append(client.sessions, session)
+
+	session.ws.handleMessage(map[string]interface{}{
+		"aid":     "rtn_brokers",
+		"brokers": []interface{}{"simnow", "H海通期货", "G国泰君安"},
+	})
+
+	brokers, err := client.GetBrokers(context.Background())
+	if err != nil {
+		t.Fatalf("GetBrokers error: %v", err)
+	}
+	if len(brokers) != 3 || brokers[1] != "H海通期货" {
+		t.Fatalf("brokers = %v", brokers)
+	}
+}
+
+func TestGetBrokersFromGrants(t *testing.T) {
+	auth := NewTqAuth("tester", "secret")
+	auth.setGrants(map[string]interface{}{
+		"features": []interface{}{"futr"},
+		"otg_ids":  []interface{}{"simnow"},
+	})
+	client := NewClient(auth, nil)
+
+	brokers, err := client.GetBrokers(context.Background())
+	if err != nil {
+		t.Fatalf("GetBrokers error: %v", err)
+	}
+	if len(brokers) != 1 || brokers[0] != "simnow" {
+		t.Fatalf("brokers = %v", brokers)
+	}
+
+	// 既无会话也无 otg_ids 时报错
+	if _, err := newTestClient(t).GetBrokers(context.Background()); err == nil {
+		t.Fatal("expected error without any broker source")
+	}
+}
+
+func TestExportImportSubscriptions(t *testing.T) {
+	client := newTestClient(t, "futr")
+	if _, err := client.SubscribeQuoteHighPriority(context.Background(), "SHFE.cu2601"); err != nil {
+		t.Fatalf("SubscribeQuoteHighPriority error: %v", err)
+	}
+	if _, err := client.SubscribeQuote(context.Background(), "DCE.m2601", "CZCE.MA601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+	if _, err := client.Series().Kline(context.Background(), "SHFE.cu2601", time.Minute, 200); err != nil {
+		t.Fatalf("Kline error: %v", err)
+	}
+	closed, err := client.Series().Tick(context.Background(), "DCE.m2601", 100)
+	if err != nil {
+		t.Fatalf("Tick error: %v", err)
+	}
+	// 已关闭的订阅不应被导出
+	closed.Close()
+
+	data, err := client.ExportSubscriptions()
+	if err != nil {
+		t.Fatalf("ExportSubscriptions error: %v", err)
+	}
+
+	restored := newTestClient(t, "futr")
+	if err := restored.ImportSubscriptions(context.Background(), data); err != nil {
+		t.Fatalf("ImportSubscriptions error: %v", err)
+	}
+	want := client.quoteSub.Symbols()
+	got := restored.quoteSub.Symbols()
+	if len(got) != len(want) {
+		t.Fatalf("restored symbols = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("restored symbols = %v, want %v", got, want)
+		}
+	}
+	if hp := restored.quoteSub.HighPrioritySymbols(); len(hp) != 1 || hp[0] != "SHFE.cu2601" {
+		t.Fatalf("restored high priority = %v", hp)
+	}
+	restored.mu.Lock()
+	series := len(restored.seriesSubs)
+	restored.mu.Unlock()
+	if series != 1 {
+		t.Fatalf("restored series subscriptions = %d, want 1", series)
+	}
+}
+
+func TestResolveTdURLOverrides(t *testing.T) {
+	client := newTestClient(t, "futr")
+
+	// 显式配置优先
+	client.WithTdURL("simnow", "ws://127.0.0.1:8001/trade")
+	client.WithTdURLResolver(func(ctx context.Context, broker string) (string, error) {
+		return "ws://resolver.local/" + broker, nil
+	})
+	url, err := client.resolveTdURL(context.Background(), "simnow")
+	if err != nil {
+		t.Fatalf("resolveTdURL error: %v", err)
+	}
+	if url != "ws://127.0.0.1:8001/trade" {
+		t.Fatalf("url = %q, want explicit override", url)
+	}
+
+	// 未显式配置的 broker 走自定义解析函数
+	url, err = client.resolveTdURL(context.Background(), "otherbroker")
+	if err != nil {
+		t.Fatalf("resolveTdURL error: %v", err)
+	}
+	if url != "ws://resolver.local/otherbroker" {
+		t.Fatalf("url = %q, want resolver result", url)
+	}
+}
+
+func TestSymbolsByInstrument(t *testing.T) {
+	client := newTestClient(t, "futr")
+	client.quotesInfo = map[string]*SymbolInfo{
+		"SHFE.rb2505": {},
+		"DCE.m2505":   {},
+		// 同名原始代码出现在多个交易所
+		"CFFEX.T2506": {},
+		"CZCE.T2506":  {},
+	}
+
+	if got := client.SymbolsByInstrument("rb2505"); len(got) != 1 || got[0] != "SHFE.rb2505" {
+		t.Fatalf("SymbolsByInstrument(rb2505) = %v", got)
+	}
+	got := client.SymbolsByInstrument("T2506")
+	if len(got) != 2 || got[0] != "CFFEX.T2506" || got[1] != "CZCE.T2506" {
+		t.Fatalf("SymbolsByInstrument(T2506) = %v", got)
+	}
+	if got := client.SymbolsByInstrument("nosuch"); len(got) != 0 {
+		t.Fatalf("SymbolsByInstrument(nosuch) = %v", got)
+	}
+}
+
+func TestSubscribeExchange(t *testing.T) {
+	client := newTestClient(t, "futr")
+	client.quotesInfo = map[string]*SymbolInfo{}
+	// 超过单批上限以覆盖分批订阅
+	for i := 0; i < subscribeExchangeBatch+200; i++ {
+		id := fmt.Sprintf("cu26%04d", i)
+		client.quotesInfo["SHFE."+id] = &SymbolInfo{
+			ExchangeID: "SHFE", InstrumentID: id, Class: "FUTURE",
+		}
+	}
+	client.quotesInfo["SHFE.cu2401"] = &SymbolInfo{
+		ExchangeID: "SHFE", InstrumentID: "cu2401", Class: "FUTURE", Expired: true,
+	}
+	client.quotesInfo["DCE.m2601"] = &SymbolInfo{
+		ExchangeID: "DCE", InstrumentID: "m2601", Class: "FUTURE",
+	}
+
+	unauthorized, err := client.SubscribeExchange(context.Background(), "SHFE")
+	if err != nil {
+		t.Fatalf("SubscribeExchange error: %v", err)
+	}
+	if len(unauthorized) != 0 {
+		t.Fatalf("unauthorized = %v, want none", unauthorized)
+	}
+	symbols := client.quoteSub.Symbols()
+	if len(symbols) != subscribeExchangeBatch+200 {
+		t.Fatalf("subscribed %d symbols, want %d", len(symbols), subscribeExchangeBatch+200)
+	}
+	for _, s := range symbols {
+		if s == "SHFE.cu2401" || s == "DCE.m2601" {
+			t.Fatalf("unexpected symbol subscribed: %s", s)
+		}
+	}
+
+	// 未加载合约信息的交易所报错提示先 InitMarket
+	if _, err := client.SubscribeExchange(context.Background(), "CZCE"); err == nil {
+		t.Fatal("expected error for exchange without listed instruments")
+	}
+}
+
+func TestRankByVolumeAndOpenInterest(t *testing.T) {
+	client := newTestClient(t, "futr")
+	feed := func(symbol string, volume, oi float64) {
+		client.dm.MergeData(map[string]interface{}{
+			"quotes": map[string]interface{}{
+				symbol: map[string]interface{}{
+					"last_price":    68000.0,
+					"datetime":      "2026-08-27 10:00:00.000000",
+					"volume":        volume,
+					"open_interest": oi,
+				},
+			},
+		})
+	}
+
+	symbols := []string{"SHFE.cu2601", "SHFE.cu2602", "SHFE.cu2603"}
+	// 后台先喂数据再触发排序, 验证自动订阅 + 等待
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		feed("SHFE.cu2601", 100, 3000)
+		feed("SHFE.cu2602", 300, 1000)
+		feed("SHFE.cu2603", 200, 2000)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	byVolume, err := client.RankByVolume(ctx, symbols...)
+	if err != nil {
+		t.Fatalf("RankByVolume error: %v", err)
+	}
+	if byVolume[0] != "SHFE.cu2602" || byVolume[1] != "SHFE.cu2603" || byVolume[2] != "SHFE.cu2601" {
+		t.Fatalf("rank by volume = %v", byVolume)
+	}
+	for _, s := range symbols {
+		if !client.IsSubscribed(s) {
+			t.Fatalf("%s not auto subscribed", s)
+		}
+	}
+
+	byOI, err := client.RankByOpenInterest(ctx, symbols...)
+	if err != nil {
+		t.Fatalf("RankByOpenInterest error: %v", err)
+	}
+	if byOI[0] != "SHFE.cu2601" || byOI[2] != "SHFE.cu2602" {
+		t.Fatalf("rank by open interest = %v", byOI)
+	}
+
+	// 等不到数据时随 ctx 退出
+	shortCtx, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+	if _, err := client.RankByVolume(shortCtx, "SHFE.ni2601"); err == nil {
+		t.Fatal("expected error when quote data never arrives")
+	}
+}
+
+func TestGetQuotesBatchSnapshot(t *testing.T) {
+	client := newTestClient(t, "futr")
+	feed := func(symbol string, price float64) {
+		client.dm.MergeData(map[string]interface{}{
+			"quotes": map[string]interface{}{
+				symbol: map[string]interface{}{
+					"last_price": price,
+					"datetime":   "2026-08-27 10:00:00.000000",
+				},
+			},
+		})
+	}
+
+	symbols := []string{"SHFE.cu2601", "SHFE.cu2602"}
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		feed("SHFE.cu2601", 68000)
+		feed("SHFE.cu2602", 68500)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	quotes, err := client.GetQuotes(ctx, symbols)
+	if err != nil {
+		t.Fatalf("GetQuotes error: %v", err)
+	}
+	if len(quotes) != 2 {
+		t.Fatalf("got %d quotes, want 2", len(quotes))
+	}
+	if quotes["SHFE.cu2601"].LastPrice != 68000 || quotes["SHFE.cu2602"].LastPrice != 68500 {
+		t.Fatalf("quote prices = %v/%v", quotes["SHFE.cu2601"].LastPrice, quotes["SHFE.cu2602"].LastPrice)
+	}
+	for _, s := range symbols {
+		if !client.IsSubscribed(s) {
+			t.Fatalf("%s not auto subscribed", s)
+		}
+	}
+}
+
+func TestGetQuotesTimeoutReturnsPartial(t *testing.T) {
+	client := newTestClient(t, "futr")
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{
+				"last_price": 68000.0,
+				"datetime":   "2026-08-27 10:00:00.000000",
+			},
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// cu2602 永远不会有数据, 超时后返回已拿到的部分
+	quotes, err := client.GetQuotes(ctx, []string{"SHFE.cu2601", "SHFE.cu2602"})
+	if err == nil {
+		t.Fatal("GetQuotes should fail on timeout")
+	}
+	if len(quotes) != 1 || quotes["SHFE.cu2601"] == nil {
+		t.Fatalf("partial quotes = %v, want only SHFE.cu2601", quotes)
+	}
+}
+
+func TestValidateWsURL(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string // 错误信息需要包含的片段, 空表示合法
+	}{
+		{"wss://free-api.shinnytech.com/t/nfmd/front/mobile", ""},
+		{"ws://127.0.0.1:7777/trade", ""},
+		{"ftp://example.com/md", "unsupported scheme"},
+		{"wss://", "missing host"},
+		{"://bad", "invalid websocket url"},
+	}
+	for _, c := range cases {
+		err := validateWsURL(c.raw, false)
+		if c.want == "" {
+			if err != nil {
+				t.Fatalf("validateWsURL(%q) = %v, want nil", c.raw, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Fatalf("validateWsURL(%q) = %v, want error containing %q", c.raw, err, c.want)
+		}
+	}
+}
+
+func TestInitMarketRejectsBadMdURL(t *testing.T) {
+	auth := NewTqAuth("tester", "secret")
+	client := NewClient(auth, &ClientConfig{MdURLs: []string{"http://md.example.com"}})
+	err := client.InitMarket(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "unsupported scheme") {
+		t.Fatalf("InitMarket error = %v, want unsupported scheme", err)
+	}
+}
+
+func TestLoginTradeRejectsBadTdURL(t *testing.T) {
+	client := newTestClient(t, "futr")
+	client.WithTdURL("simnow", "wss://")
+	_, err := client.LoginTrade(context.Background(), "simnow", "user1", "pass")
+	if err == nil || !strings.Contains(err.Error(), "missing host") {
+		t.Fatalf("LoginTrade error = %v, want missing host", err)
+	}
+
+	// 地址格式合法但可达性预检失败
+	orig := wsPrecheckDial
+	wsPrecheckDial = func(addr string) error { return errors.New("connection refused") }
+	defer func() { wsPrecheckDial = orig }()
+	client.WithTdURL("simnow", "wss://td.example.com/trade")
+	_, err = client.LoginTrade(context.Background(), "simnow", "user1", "pass")
+	if err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Fatalf("LoginTrade error = %v, want unreachable", err)
+	}
+}
+
+func TestValidateVolume(t *testing.T) {
+	client := newTestClient(t, "futr")
+	client.quotesInfo = map[string]*SymbolInfo{
+		"SHFE.cu2601": {ExchangeID: "SHFE", InstrumentID: "cu2601",
+			Class: "FUTURE", MinLimitOrderVolume: 1, MaxLimitOrderVolume: 500},
+		"CZCE.SPD MA601&MA605": {ExchangeID: "CZCE", InstrumentID: "SPD MA601&MA605",
+			Class: "COMBINE"},
+		"CFFEX.IF2601": {ExchangeID: "CFFEX", InstrumentID: "IF2601",
+			Class: "FUTURE", MinLimitOrderVolume: 5, MaxLimitOrderVolume: 20},
+	}
+
+	cases := []struct {
+		symbol string
+		volume int64
+		ok     bool
+	}{
+		{"SHFE.cu2601", 1, true},    // 下界
+		{"SHFE.cu2601", 500, true},  // 上界
+		{"SHFE.cu2601", 0, false},   // 非正手数
+		{"SHFE.cu2601", -3, false},  // 负手数
+		{"SHFE.cu2601", 501, false}, // 超上界
+		{"CFFEX.IF2601", 4, false},  // 低于最小手数
+		{"CFFEX.IF2601", 5, true},
+		{"CZCE.SPD MA601&MA605", 9999, true}, // 组合合约跳过校验
+	}
+	for _, c := range cases {
+		err := client.ValidateVolume(c.symbol, c.volume)
+		if c.ok && err != nil {
+			t.Fatalf("ValidateVolume(%s, %d) = %v, want nil", c.symbol, c.volume, err)
+		}
+		if !c.ok && err == nil {
+			t.Fatalf("ValidateVolume(%s, %d) = nil, want error", c.symbol, c.volume)
+		}
+	}
+
+	// 未加载合约信息的合约报错提示先 InitMarket
+	if err := client.ValidateVolume("DCE.m2601", 1); err == nil ||
+		!strings.Contains(err.Error(), "InitMarket") {
+		t.Fatalf("unknown symbol error = %v, want InitMarket hint", err)
+	}
+}
+
+func TestInsertOrderVolumeValidation(t *testing.T) {
+	client := newTestClient(t, "futr")
+	client.quotesInfo = map[string]*SymbolInfo{
+		"SHFE.cu2601": {ExchangeID: "SHFE", InstrumentID: "cu2601",
+			Class: "FUTURE", MinLimitOrderVolume: 1, MaxLimitOrderVolume: 500},
+	}
+	dm := NewDataManager()
+	ws := NewTqTradeWebsocket(&WebSocketConfig{}, dm, nil)
+	session := newTradeSession(ws, dm, "simnow", "user1", defaultLogger(), WithVolumeValidation())
+	session.validateVolume = client.ValidateVolume
+
+	_, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 501, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	})
+	if err == nil || !strings.Contains(err.Error(), "exceeds maximum") {
+		t.Fatalf("InsertOrder error = %v, want volume error", err)
+	}
+	// 校验失败不应发出任何报文
+	if n := len(ws.queue); n != 0 {
+		t.Fatalf("queued packets = %d, want 0", n)
+	}
+
+	if _, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 2, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	}); err != nil {
+		t.Fatalf("valid volume rejected: %v", err)
+	}
+}
+
+func TestAvailableGrants(t *testing.T) {
+	client := newTestClient(t, "lmt_idx", "futr")
+	features, exchanges := client.AvailableGrants()
+	if len(features) != 2 || features[0] != "futr" || features[1] != "lmt_idx" {
+		t.Fatalf("features = %v, want [futr lmt_idx]", features)
+	}
+	want := []string{"CFFEX", "CZCE", "DCE", "GFEX", "INE", "SHFE"}
+	if len(exchanges) != len(want) {
+		t.Fatalf("exchanges = %v, want %v", exchanges, want)
+	}
+	for i := range want {
+		if exchanges[i] != want[i] {
+			t.Fatalf("exchanges = %v, want %v", exchanges, want)
+		}
+	}
+
+	// 无任何权限时两个列表均为空
+	features, exchanges = newTestClient(t).AvailableGrants()
+	if len(features) != 0 || len(exchanges) != 0 {
+		t.Fatalf("empty grants: features=%v exchanges=%v", features, exchanges)
+	}
+}
@@ -0,0 +1,728 @@
+package shinny
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CallbackMode 控制 OnData 回调的触发方式
+type CallbackMode int
+
+const (
+	// CallbackAsync 每次 MergeData 后为每个回调启动独立 goroutine (默认)
+	CallbackAsync CallbackMode = iota
+	// CallbackSerial 所有回调在单一 goroutine 上串行执行,
+	// 执行期间到达的多次变更合并为一次触发
+	CallbackSerial
+)
+
+// DataManager 维护 DIFF 协议的业务信息截面 (snapshot), 所有行情/交易数据
+// 都通过 MergeData 合并到内部的 map 树上, 消费方通过 GetByPath/Watch 读取
+type DataManager struct {
+	mu sync.RWMutex
+
+	data  map[string]interface{}
+	epoch int64
+	// pathEpochs 记录每个被更新过的节点路径最近一次变更时的 epoch
+	pathEpochs map[string]int64
+
+	watchers        map[string][]*watcher
+	onDataCallbacks []func()
+	// epochChans EpochChannel 的订阅通道, 容量 1 覆盖式推送
+	epochChans []chan int64
+
+	// maxMemoryBytes 截面内存上限 (估算值), <=0 不限制
+	maxMemoryBytes int64
+
+	cbMode CallbackMode
+	// cbInterval 串行模式下两次回调的最小间隔, 间隔内的变更合并触发
+	cbInterval time.Duration
+	// cbSignal 容量为 1, 挤掉积压信号实现 coalescing
+	cbSignal chan struct{}
+	// cbStop 关闭后 dispatchLoop 退出, 由 Close 触发
+	cbStop chan struct{}
+	// cbMu 串行模式下合并与回调执行互斥: 回调运行期间截面冻结,
+	// 回调里的读取不会与下一次合并竞争. 串行回调内不得再调用
+	// MergeData/BatchMerge, 否则自身死锁
+	cbMu sync.Mutex
+
+	logger *slog.Logger
+}
+
+type watcher struct {
+	ctx  context.Context
+	path string
+	ch   chan int64
+
+	// blocking 为 true 时变更先入 pending 队列, 由独立 goroutine
+	// 阻塞送入 ch, 保证不丢通知且不阻塞 MergeData
+	blocking bool
+	pendMu   sync.Mutex
+	pending  []int64
+	signal   chan struct{}
+}
+
+// enqueue 记录一次变更并唤醒分发 goroutine, 只在 blocking 模式下使用
+func (w *watcher) enqueue(epoch int64) {
+	w.pendMu.Lock()
+	w.pending = append(w.pending, epoch)
+	w.pendMu.Unlock()
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// forward 按序将 pending 中的 epoch 阻塞送入 ch, ctx 取消后退出
+func (w *watcher) forward() {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-w.signal:
+		}
+		for {
+			w.pendMu.Lock()
+			if len(w.pending) == 0 {
+				w.pendMu.Unlock()
+				break
+			}
+			epoch := w.pending[0]
+			w.pending = w.pending[1:]
+			w.pendMu.Unlock()
+			select {
+			case w.ch <- epoch:
+			case <-w.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func NewDataManager() *DataManager {
+	return &DataManager{
+		data:       make(map[string]interface{}),
+		pathEpochs: make(map[string]int64),
+		watchers:   make(map[string][]*watcher),
+		logger:     defaultLogger(),
+	}
+}
+
+// Epoch 返回当前数据版本号, 每次 MergeData 成功后 +1
+func (m *DataManager) Epoch() int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.epoch
+}
+
+// OnData 注册数据变更回调, 每次 MergeData 完成后触发
+func (m *DataManager) OnData(cb func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onDataCallbacks = append(m.onDataCallbacks, cb)
+}
+
+// SetCallbackMode 设置 OnData 回调触发方式, 须在注册回调前调用
+func (m *DataManager) SetCallbackMode(mode CallbackMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cbMode = mode
+	m.ensureDispatchLoopLocked()
+}
+
+// SetCallbackCoalesce 启用按最小间隔合并触发: 回调串行执行,
+// interval 内的多次变更只触发一次回调 (保留最新截面)
+func (m *DataManager) SetCallbackCoalesce(interval time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cbMode = CallbackSerial
+	m.cbInterval = interval
+	m.ensureDispatchLoopLocked()
+}
+
+func (m *DataManager) ensureDispatchLoopLocked() {
+	if m.cbMode != CallbackSerial || m.cbSignal != nil {
+		return
+	}
+	m.cbSignal = make(chan struct{}, 1)
+	m.cbStop = make(chan struct{})
+	go m.dispatchLoop(m.cbSignal, m.cbStop)
+}
+
+// dispatchLoop 串行执行回调, 两次执行之间至少间隔 cbInterval.
+// 回调在 cbMu 保护下运行, 与合并互斥; stop 关闭后退出
+func (m *DataManager) dispatchLoop(signal, stop chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case <-signal:
+		}
+		m.mu.RLock()
+		callbacks := append([]func(){}, m.onDataCallbacks...)
+		interval := m.cbInterval
+		m.mu.RUnlock()
+		m.cbMu.Lock()
+		for _, cb := range callbacks {
+			cb()
+		}
+		m.cbMu.Unlock()
+		if interval > 0 {
+			select {
+			case <-stop:
+				return
+			case <-time.After(interval):
+			}
+		}
+	}
+}
+
+// Close 停止串行分发 goroutine, 由持有 DataManager 的 Client /
+// TradeSession 在关闭时调用; 未启用串行模式时为空操作, 可重复调用
+func (m *DataManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cbStop != nil {
+		close(m.cbStop)
+		m.cbStop = nil
+	}
+}
+
+// MergeData 将一个 DIFF 数据包合并进截面
+func (m *DataManager) MergeData(diff map[string]interface{}) {
+	// 串行回调执行期间冻结合并, 见 cbMu
+	m.cbMu.Lock()
+	defer m.cbMu.Unlock()
+	m.mu.Lock()
+	m.epoch++
+	epoch := m.epoch
+	changed := make([]string, 0, 8)
+	m.mergeObject(m.data, diff, nil, &changed)
+	m.enforceMemoryLimitLocked(&changed)
+	callbacks := make([]func(), len(m.onDataCallbacks))
+	copy(callbacks, m.onDataCallbacks)
+	epochChans := append([]chan int64(nil), m.epochChans...)
+	mode := m.cbMode
+	signal := m.cbSignal
+	m.mu.Unlock()
+
+	m.notifyWatchers(changed)
+	pushEpoch(epochChans, epoch)
+	if mode == CallbackSerial && signal != nil {
+		select {
+		case signal <- struct{}{}:
+		default:
+		}
+		return
+	}
+	for _, cb := range callbacks {
+		go cb()
+	}
+}
+
+// EpochChannel 返回一个 epoch 订阅通道: 每次 MergeData/BatchMerge
+// 完成后推送最新 epoch 号. 通道容量为 1 且覆盖式推送, 消费慢时只保
+// 留最新 epoch 不积压, 消费方据此自行批量读取感兴趣的截面
+func (m *DataManager) EpochChannel() <-chan int64 {
+	ch := make(chan int64, 1)
+	m.mu.Lock()
+	m.epochChans = append(m.epochChans, ch)
+	m.mu.Unlock()
+	return ch
+}
+
+// pushEpoch 覆盖式推送 epoch: 通道已满时挤掉旧值保留最新
+func pushEpoch(chans []chan int64, epoch int64) {
+	for _, ch := range chans {
+		for {
+			select {
+			case ch <- epoch:
+			default:
+				select {
+				case <-ch:
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
+
+// MergeContext 收集一个事务内的多块 DIFF 数据, 见 BatchMerge
+type MergeContext struct {
+	m       *DataManager
+	changed []string
+}
+
+// Merge 合并一块 DIFF 数据, 只能在 BatchMerge 的回调内调用
+func (mc *MergeContext) Merge(diff map[string]interface{}) {
+	mc.m.mergeObject(mc.m.data, diff, nil, &mc.changed)
+}
+
+// BatchMerge 在一个事务内合并多块数据: 整个过程只推进一次 epoch,
+// 结束后只通知一次 watcher 与 OnData 回调, 消费方不会观察到中间态
+func (m *DataManager) BatchMerge(fn func(mc *MergeContext)) {
+	m.cbMu.Lock()
+	defer m.cbMu.Unlock()
+	m.mu.Lock()
+	m.epoch++
+	epoch := m.epoch
+	mc := &MergeContext{m: m, changed: make([]string, 0, 8)}
+	fn(mc)
+	m.enforceMemoryLimitLocked(&mc.changed)
+	callbacks := make([]func(), len(m.onDataCallbacks))
+	copy(callbacks, m.onDataCallbacks)
+	epochChans := append([]chan int64(nil), m.epochChans...)
+	mode := m.cbMode
+	signal := m.cbSignal
+	m.mu.Unlock()
+
+	m.notifyWatchers(mc.changed)
+	pushEpoch(epochChans, epoch)
+	if mode == CallbackSerial && signal != nil {
+		select {
+		case signal <- struct{}{}:
+		default:
+		}
+		return
+	}
+	for _, cb := range callbacks {
+		go cb()
+	}
+}
+
+// mergeObject 递归合并 diff 到 target, value 为 nil 且 deleteNullObj 时删除该 key
+func (m *DataManager) mergeObject(target, diff map[string]interface{}, path []string, changed *[]string) {
+	for k, v := range diff {
+		sub := append(append([]string(nil), path...), k)
+		key := strings.Join(sub, "/")
+		if v == nil {
+			// 服务端发 null 表示删除整个子树 (如撤单后的 order /
+			// 平光后的 position), 同时清理后代路径的变更记录并
+			// 通知后代路径上的 watcher
+			delete(target, k)
+			m.pathEpochs[key] = m.epoch
+			*changed = append(*changed, key)
+			prefix := key + "/"
+			for p := range m.pathEpochs {
+				if strings.HasPrefix(p, prefix) {
+					delete(m.pathEpochs, p)
+					*changed = append(*changed, p)
+				}
+			}
+			continue
+		}
+		if vm, ok := v.(map[string]interface{}); ok {
+			tm, ok := target[k].(map[string]interface{})
+			if !ok {
+				tm = make(map[string]interface{})
+				target[k] = tm
+			}
+			m.pathEpochs[key] = m.epoch
+			m.mergeObject(tm, vm, sub, changed)
+		} else {
+			if n, ok := v.(json.Number); ok {
+				v = normalizeNumber(n)
+			}
+			target[k] = v
+			m.pathEpochs[key] = m.epoch
+			*changed = append(*changed, key)
+		}
+	}
+}
+
+// seriesEntryBytes 每条 kline/tick 数据的估算字节数, 内存限制按
+// 条目数 * 该系数估算
+const seriesEntryBytes int64 = 400
+
+// SetMaxMemory 设置截面内存上限 (字节, 估算值), <=0 取消限制.
+// 每次合并后按条目数估算 kline/tick 序列占用, 超限时从最久未更新的
+// 序列子树开始逐个清理并记录告警, 防止订阅失控导致 OOM
+func (m *DataManager) SetMaxMemory(bytes int64) {
+	m.mu.Lock()
+	m.maxMemoryBytes = bytes
+	m.mu.Unlock()
+}
+
+// enforceMemoryLimitLocked 估算序列占用并按 LRU 清理超限部分,
+// 被清理的路径追加进 changed 以通知对应 watcher
+func (m *DataManager) enforceMemoryLimitLocked(changed *[]string) {
+	if m.maxMemoryBytes <= 0 {
+		return
+	}
+	for {
+		entries := m.seriesEntriesLocked()
+		var total int64
+		for _, n := range entries {
+			total += int64(n) * seriesEntryBytes
+		}
+		if total <= m.maxMemoryBytes {
+			return
+		}
+		// 最久未更新 (pathEpochs 最小) 的序列优先清理
+		var victim string
+		var victimEpoch int64
+		for key := range entries {
+			e := m.pathEpochs[key]
+			if victim == "" || e < victimEpoch {
+				victim, victimEpoch = key, e
+			}
+		}
+		if victim == "" {
+			return
+		}
+		m.removeSubtreeLocked(victim)
+		*changed = append(*changed, victim)
+		m.logger.Warn("data manager memory limit exceeded, series evicted",
+			"path", victim, "estimated_bytes", total, "limit_bytes", m.maxMemoryBytes)
+	}
+}
+
+// seriesEntriesLocked 统计各 kline/tick 序列的数据条目数,
+// key 为截面内的节点路径
+func (m *DataManager) seriesEntriesLocked() map[string]int {
+	out := make(map[string]int)
+	if klines, ok := m.data["klines"].(map[string]interface{}); ok {
+		for symbol, bySym := range klines {
+			durs, _ := bySym.(map[string]interface{})
+			for dur, node := range durs {
+				if series, ok := node.(map[string]interface{}); ok {
+					if bars, ok := series["data"].(map[string]interface{}); ok {
+						out["klines/"+symbol+"/"+dur] = len(bars)
+					}
+				}
+			}
+		}
+	}
+	if ticks, ok := m.data["ticks"].(map[string]interface{}); ok {
+		for symbol, node := range ticks {
+			if series, ok := node.(map[string]interface{}); ok {
+				if data, ok := series["data"].(map[string]interface{}); ok {
+					out["ticks/"+symbol] = len(data)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// removeSubtreeLocked 删除指定路径的子树, 与服务端 null 删除一样
+// 记录本路径变更并清理后代路径的变更记录
+func (m *DataManager) removeSubtreeLocked(key string) {
+	parts := strings.Split(key, "/")
+	node := m.data
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := node[p].(map[string]interface{})
+		if !ok {
+			return
+		}
+		node = next
+	}
+	delete(node, parts[len(parts)-1])
+	m.pathEpochs[key] = m.epoch
+	prefix := key + "/"
+	for p := range m.pathEpochs {
+		if strings.HasPrefix(p, prefix) {
+			delete(m.pathEpochs, p)
+		}
+	}
+}
+
+// normalizeNumber 把 UseNumber 解码出的 json.Number 还原为数值:
+// float64 可精确表示的仍存 float64 (与历史行为一致), 超过 float64
+// 精度范围的大整数 (如纳秒时间戳) 存 int64 避免精度丢失
+func normalizeNumber(n json.Number) interface{} {
+	if i, err := n.Int64(); err == nil {
+		if int64(float64(i)) == i {
+			return float64(i)
+		}
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n.String()
+}
+
+// Get 读取截面顶层 key
+func (m *DataManager) Get(key string) (interface{}, bool) {
+	return m.GetByPath(key)
+}
+
+// GetByPath 按路径读取截面节点, 例如 GetByPath("quotes", "SHFE.cu2601").
+// 返回的是截面内的活动节点, 后续合并会原地修改它: 只适合在读取后
+// 立即取标量字段的场景, 需要在锁外遍历或序列化节点时用 GetCopyByPath
+func (m *DataManager) GetByPath(path ...string) (interface{}, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var cur interface{} = m.data
+	for _, p := range path {
+		cm, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = cm[p]
+		if !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// GetCopyByPath 按路径读取截面节点的深拷贝: 拷贝在读锁内完成,
+// 返回值与截面不共享容器, 可在锁外安全遍历/序列化. 回调或独立
+// goroutine 里把节点交给 json.Marshal (如 ConvertToStruct) 前
+// 必须用本方法取数, 否则与并发合并互相竞争
+func (m *DataManager) GetCopyByPath(path ...string) (interface{}, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var cur interface{} = m.data
+	for _, p := range path {
+		cm, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		cur, ok = cm[p]
+		if !ok {
+			return nil, false
+		}
+	}
+	return deepCopyValue(cur), true
+}
+
+// IsChanging 判断指定路径的数据在当前 epoch 是否发生过变更.
+// 只看目标路径本身的 epoch: 兄弟节点的变更虽然会推进共同祖先的
+// epoch, 但不会使该路径被误判为有变化
+func (m *DataManager) IsChanging(path ...string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	e, ok := m.pathEpochs[strings.Join(path, "/")]
+	return ok && e == m.epoch
+}
+
+// PathEpoch 返回指定路径最近一次变更时的 epoch, 从未变更过返回 0.
+// 回调里与自己记录的上次处理位置比较, 可以避免异步回调期间 epoch
+// 已被后续合并推进导致的漏判
+func (m *DataManager) PathEpoch(path ...string) int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pathEpochs[strings.Join(path, "/")]
+}
+
+// Watch 返回一个 channel, 指定路径数据变更时推送当前 epoch,
+// channel 缓冲写满时丢弃本次通知
+func (m *DataManager) Watch(ctx context.Context, path ...string) <-chan int64 {
+	return m.WatchWithOptions(ctx, strings.Join(path, "/"), WatchOptions{})
+}
+
+// WatchOptions 控制 WatchWithOptions 返回 channel 的行为
+type WatchOptions struct {
+	// Buffer channel 缓冲大小, <= 0 时取默认值 10
+	Buffer int
+	// Blocking 为 true 时缓冲写满不丢弃通知, 而是由独立分发
+	// goroutine 阻塞等待消费, 保证每次变更都送达 (适合审计场景);
+	// 消费方退出前应取消 ctx 以释放分发 goroutine
+	Blocking bool
+}
+
+// WatchWithOptions 与 Watch 相同, 但可定制缓冲大小与背压行为,
+// path 为 "/" 分隔的节点路径, 例如 "quotes/SHFE.cu2601"
+func (m *DataManager) WatchWithOptions(ctx context.Context, path string, opts WatchOptions) <-chan int64 {
+	buffer := opts.Buffer
+	if buffer <= 0 {
+		buffer = 10
+	}
+	w := &watcher{
+		ctx:      ctx,
+		path:     path,
+		ch:       make(chan int64, buffer),
+		blocking: opts.Blocking,
+	}
+	if w.blocking {
+		if w.ctx == nil {
+			w.ctx = context.Background()
+		}
+		w.signal = make(chan struct{}, 1)
+		go w.forward()
+	}
+	m.mu.Lock()
+	m.watchers[w.path] = append(m.watchers[w.path], w)
+	m.mu.Unlock()
+	return w.ch
+}
+
+func (m *DataManager) notifyWatchers(changed []string) {
+	m.mu.RLock()
+	epoch := m.epoch
+	var targets []*watcher
+	for _, key := range changed {
+		for prefix, ws := range m.watchers {
+			if prefix == "" || key == prefix || strings.HasPrefix(key, prefix+"/") {
+				targets = append(targets, ws...)
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	seen := make(map[*watcher]bool, len(targets))
+	for _, w := range targets {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		if w.ctx != nil && w.ctx.Err() != nil {
+			continue
+		}
+		if w.blocking {
+			w.enqueue(epoch)
+			continue
+		}
+		select {
+		case w.ch <- epoch:
+		default:
+			// 消费不及时, 丢弃本次通知
+		}
+	}
+}
+
+// Clone 深拷贝当前截面, 递归复制 map/slice, 不共享底层容器
+func (m *DataManager) Clone() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return deepCopyMap(m.data)
+}
+
+func deepCopyMap(src map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(src))
+	for k, v := range src {
+		out[k] = deepCopyValue(v)
+	}
+	return out
+}
+
+func deepCopyValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		return deepCopyMap(x)
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, e := range x {
+			out[i] = deepCopyValue(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// DataStats 截面数据量统计, 用于观察内存占用与决定是否收缩 ViewWidth
+type DataStats struct {
+	// Quotes 已有行情数据的合约数
+	Quotes int
+	// KlineBars 每个 "symbol/duration" 序列的 bar 条数
+	KlineBars map[string]int
+	// Ticks 每个合约的 tick 条数
+	Ticks map[string]int
+	// Charts 活跃图表数
+	Charts int
+	// EstimatedBytes 估算的截面内存占用 (按容器与节点粗略估计)
+	EstimatedBytes int64
+}
+
+// Stats 统计当前截面的数据条目数与估算内存
+func (m *DataManager) Stats() *DataStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	st := &DataStats{
+		KlineBars: make(map[string]int),
+		Ticks:     make(map[string]int),
+	}
+	if quotes, ok := m.data["quotes"].(map[string]interface{}); ok {
+		st.Quotes = len(quotes)
+	}
+	if klines, ok := m.data["klines"].(map[string]interface{}); ok {
+		for symbol, bySym := range klines {
+			durs, ok := bySym.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for dur, node := range durs {
+				if series, ok := node.(map[string]interface{}); ok {
+					if bars, ok := series["data"].(map[string]interface{}); ok {
+						st.KlineBars[symbol+"/"+dur] = len(bars)
+					}
+				}
+			}
+		}
+	}
+	if ticks, ok := m.data["ticks"].(map[string]interface{}); ok {
+		for symbol, node := range ticks {
+			if series, ok := node.(map[string]interface{}); ok {
+				if data, ok := series["data"].(map[string]interface{}); ok {
+					st.Ticks[symbol] = len(data)
+				}
+			}
+		}
+	}
+	if charts, ok := m.data["charts"].(map[string]interface{}); ok {
+		st.Charts = len(charts)
+	}
+	st.EstimatedBytes = estimateSize(m.data)
+	return st
+}
+
+// estimateSize 粗估一个节点的内存占用: map/slice 按容器开销累加,
+// 标量按机器字宽, 字符串按实际长度
+func estimateSize(v interface{}) int64 {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		size := int64(48)
+		for k, e := range x {
+			size += int64(len(k)) + 32 + estimateSize(e)
+		}
+		return size
+	case []interface{}:
+		size := int64(24)
+		for _, e := range x {
+			size += 16 + estimateSize(e)
+		}
+		return size
+	case string:
+		return int64(len(x)) + 16
+	default:
+		return 16
+	}
+}
+
+// Dump 导出全量截面数据
+func (m *DataManager) Dump() ([]byte, error) {
+	data := m.Clone()
+	return json.Marshal(data)
+}
+
+// DumpPath 只导出指定子树, 避免大截面下的全量拷贝
+func (m *DataManager) DumpPath(path ...string) ([]byte, error) {
+	m.mu.RLock()
+	var cur interface{} = m.data
+	for _, p := range path {
+		cm, ok := cur.(map[string]interface{})
+		if !ok {
+			m.mu.RUnlock()
+			return nil, fmt.Errorf("shinny: no data at path %v", path)
+		}
+		cur, ok = cm[p]
+		if !ok {
+			m.mu.RUnlock()
+			return nil, fmt.Errorf("shinny: no data at path %v", path)
+		}
+	}
+	copied := deepCopyValue(cur)
+	m.mu.RUnlock()
+	return json.Marshal(copied)
+}
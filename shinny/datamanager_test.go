@@ -0,0 +1,644 @@
+package shinny
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMergeDataAndGetByPath(t *testing.T) {
+	dm := NewDataManager()
+	dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"last_price": 68000.0},
+		},
+	})
+	dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"bid_price1": 67990.0},
+		},
+	})
+	v, ok := dm.GetByPath("quotes", "SHFE.cu2601", "last_price")
+	if !ok || v.(float64) != 68000.0 {
+		t.Fatalf("last_price = %v, want 68000", v)
+	}
+	v, ok = dm.GetByPath("quotes", "SHFE.cu2601", "bid_price1")
+	if !ok || v.(float64) != 67990.0 {
+		t.Fatalf("bid_price1 = %v, want 67990", v)
+	}
+}
+
+func TestIsChanging(t *testing.T) {
+	dm := NewDataManager()
+	dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"last_price": 68000.0},
+		},
+	})
+	if !dm.IsChanging("quotes", "SHFE.cu2601") {
+		t.Fatal("expected quotes/SHFE.cu2601 to be changing")
+	}
+	dm.MergeData(map[string]interface{}{
+		"ticks": map[string]interface{}{},
+	})
+	if dm.IsChanging("quotes", "SHFE.cu2601", "last_price") {
+		t.Fatal("expected quotes/SHFE.cu2601/last_price unchanged in current epoch")
+	}
+}
+
+func TestCallbackSerialMode(t *testing.T) {
+	dm := NewDataManager()
+	dm.SetCallbackMode(CallbackSerial)
+	var mu sync.Mutex
+	var order []int
+	dm.OnData(func() {
+		mu.Lock()
+		order = append(order, 1)
+		mu.Unlock()
+	})
+	dm.OnData(func() {
+		mu.Lock()
+		order = append(order, 2)
+		mu.Unlock()
+	})
+	dm.MergeData(map[string]interface{}{"a": 1.0})
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(order)
+		mu.Unlock()
+		if n >= 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) < 2 || order[0] != 1 || order[1] != 2 {
+		t.Fatalf("callbacks not serialized in order, got %v", order)
+	}
+}
+
+func TestCallbackCoalesce(t *testing.T) {
+	dm := NewDataManager()
+	dm.SetCallbackCoalesce(50 * time.Millisecond)
+	var calls atomic.Int64
+	dm.OnData(func() {
+		calls.Add(1)
+	})
+	const merges = 20
+	for i := 0; i < merges; i++ {
+		dm.MergeData(map[string]interface{}{"seq": float64(i)})
+	}
+	time.Sleep(150 * time.Millisecond)
+	got := calls.Load()
+	if got == 0 {
+		t.Fatal("expected at least one callback")
+	}
+	if got >= merges {
+		t.Fatalf("coalescing did not reduce callbacks: %d calls for %d merges", got, merges)
+	}
+	// 最终截面应为最新值
+	v, _ := dm.GetByPath("seq")
+	if v.(float64) != float64(merges-1) {
+		t.Fatalf("seq = %v, want %d", v, merges-1)
+	}
+}
+
+func TestCloneIndependent(t *testing.T) {
+	dm := NewDataManager()
+	dm.MergeData(map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": 1.0,
+			"s": []interface{}{map[string]interface{}{"x": 1.0}},
+		},
+	})
+	clone := dm.Clone()
+	clone["a"].(map[string]interface{})["b"] = 2.0
+	clone["a"].(map[string]interface{})["s"].([]interface{})[0].(map[string]interface{})["x"] = 9.0
+	if v, _ := dm.GetByPath("a", "b"); v.(float64) != 1.0 {
+		t.Fatalf("clone mutated source map, got %v", v)
+	}
+	raw, _ := dm.GetByPath("a", "s")
+	if x := raw.([]interface{})[0].(map[string]interface{})["x"]; x.(float64) != 1.0 {
+		t.Fatalf("clone mutated source slice, got %v", x)
+	}
+}
+
+func TestDumpPath(t *testing.T) {
+	dm := NewDataManager()
+	dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"last_price": 68000.0},
+			"DCE.m2601":   map[string]interface{}{"last_price": 3000.0},
+		},
+	})
+	data, err := dm.DumpPath("quotes", "SHFE.cu2601")
+	if err != nil {
+		t.Fatalf("DumpPath error: %v", err)
+	}
+	if string(data) != `{"last_price":68000}` {
+		t.Fatalf("DumpPath = %s", data)
+	}
+	if _, err := dm.DumpPath("quotes", "nonexistent"); err == nil {
+		t.Fatal("expected error for missing path")
+	}
+}
+
+// buildLargeSnapshot 构造一个较大的截面用于基准对比
+func buildLargeSnapshot(dm *DataManager) {
+	quotes := make(map[string]interface{}, 500)
+	for i := 0; i < 500; i++ {
+		quotes["SYM"+string(rune('A'+i%26))+string(rune('0'+i%10))+string(rune('0'+i/10%10))+string(rune('0'+i/100))] = map[string]interface{}{
+			"last_price": float64(i), "bid_price1": float64(i) - 1, "ask_price1": float64(i) + 1,
+			"volume": float64(i * 10), "datetime": "2026-08-27 10:00:00.000000",
+		}
+	}
+	dm.MergeData(map[string]interface{}{"quotes": quotes})
+}
+
+func BenchmarkClone(b *testing.B) {
+	dm := NewDataManager()
+	buildLargeSnapshot(dm)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dm.Clone()
+	}
+}
+
+func BenchmarkCloneJSONRoundTrip(b *testing.B) {
+	dm := NewDataManager()
+	buildLargeSnapshot(dm)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf, _ := json.Marshal(dm.data)
+		out := make(map[string]interface{})
+		json.Unmarshal(buf, &out)
+	}
+}
+
+func TestBatchMergeSingleNotification(t *testing.T) {
+	dm := NewDataManager()
+	var calls atomic.Int64
+	dm.OnData(func() { calls.Add(1) })
+
+	start := dm.Epoch()
+	dm.BatchMerge(func(mc *MergeContext) {
+		mc.Merge(map[string]interface{}{
+			"trade": map[string]interface{}{"user1": map[string]interface{}{
+				"accounts": map[string]interface{}{"CNY": map[string]interface{}{"balance": 100000.0}},
+			}},
+		})
+		mc.Merge(map[string]interface{}{
+			"trade": map[string]interface{}{"user1": map[string]interface{}{
+				"positions": map[string]interface{}{"SHFE.cu2601": map[string]interface{}{"volume_long": 2.0}},
+			}},
+		})
+		mc.Merge(map[string]interface{}{
+			"trade": map[string]interface{}{"user1": map[string]interface{}{
+				"orders": map[string]interface{}{"TQGO_b1": map[string]interface{}{"status": StatusAlive}},
+			}},
+		})
+	})
+
+	if got := dm.Epoch(); got != start+1 {
+		t.Fatalf("epoch advanced by %d, want 1", got-start)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("OnData calls = %d, want 1", got)
+	}
+	if v, ok := dm.GetByPath("trade", "user1", "positions", "SHFE.cu2601", "volume_long"); !ok || v != 2.0 {
+		t.Fatalf("merged data missing, got %v", v)
+	}
+	if !dm.IsChanging("trade", "user1", "orders", "TQGO_b1") {
+		t.Fatal("batch-merged path should be changing in current epoch")
+	}
+}
+
+func TestStatsCounts(t *testing.T) {
+	dm := NewDataManager()
+	dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"last_price": 68000.0},
+			"DCE.m2601":   map[string]interface{}{"last_price": 3000.0},
+		},
+		"klines": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{
+				"60000000000": map[string]interface{}{
+					"last_id": 10.0,
+					"data": map[string]interface{}{
+						"9":  map[string]interface{}{"close": 68000.0},
+						"10": map[string]interface{}{"close": 68010.0},
+					},
+				},
+			},
+		},
+		"ticks": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{
+				"last_id": 3.0,
+				"data": map[string]interface{}{
+					"1": map[string]interface{}{"last_price": 68000.0},
+					"2": map[string]interface{}{"last_price": 68010.0},
+					"3": map[string]interface{}{"last_price": 68020.0},
+				},
+			},
+		},
+		"charts": map[string]interface{}{
+			"TQGO_chart_x": map[string]interface{}{"more_data": false},
+		},
+	})
+
+	st := dm.Stats()
+	if st.Quotes != 2 {
+		t.Fatalf("Quotes = %d, want 2", st.Quotes)
+	}
+	if got := st.KlineBars["SHFE.cu2601/60000000000"]; got != 2 {
+		t.Fatalf("KlineBars = %v", st.KlineBars)
+	}
+	if got := st.Ticks["SHFE.cu2601"]; got != 3 {
+		t.Fatalf("Ticks = %v", st.Ticks)
+	}
+	if st.Charts != 1 {
+		t.Fatalf("Charts = %d, want 1", st.Charts)
+	}
+	if st.EstimatedBytes <= 0 {
+		t.Fatalf("EstimatedBytes = %d, want > 0", st.EstimatedBytes)
+	}
+}
+
+func TestWatchWithOptionsBlockingNoLoss(t *testing.T) {
+	dm := NewDataManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// 缓冲 1 + 慢消费, 非阻塞模式下必然丢通知
+	ch := dm.WatchWithOptions(ctx, "quotes/SHFE.cu2601", WatchOptions{Buffer: 1, Blocking: true})
+
+	const merges = 50
+	for i := 0; i < merges; i++ {
+		dm.MergeData(map[string]interface{}{
+			"quotes": map[string]interface{}{
+				"SHFE.cu2601": map[string]interface{}{"last_price": float64(i)},
+			},
+		})
+	}
+
+	var got []int64
+	for len(got) < merges {
+		select {
+		case epoch := <-ch:
+			got = append(got, epoch)
+			time.Sleep(time.Millisecond)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("received %d notifications, want %d", len(got), merges)
+		}
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Fatalf("epochs not strictly increasing: %v", got)
+		}
+	}
+}
+
+func TestWatchWithOptionsDefaultDrops(t *testing.T) {
+	dm := NewDataManager()
+	ch := dm.WatchWithOptions(context.Background(), "seq", WatchOptions{Buffer: 1})
+	for i := 0; i < 20; i++ {
+		dm.MergeData(map[string]interface{}{"seq": float64(i)})
+	}
+	// 非阻塞模式缓冲写满即丢弃, 收到的通知数不超过缓冲容量
+	var got int
+	for {
+		select {
+		case <-ch:
+			got++
+		default:
+			if got == 0 || got > 1 {
+				t.Fatalf("received %d notifications, want 1", got)
+			}
+			return
+		}
+	}
+}
+
+func TestIsChangingSiblingNotAffected(t *testing.T) {
+	dm := NewDataManager()
+	dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"last_price": 68000.0},
+			"DCE.m2601":   map[string]interface{}{"last_price": 3000.0},
+		},
+	})
+	// 只更新 A 合约
+	dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"last_price": 68001.0},
+		},
+	})
+	if !dm.IsChanging("quotes", "SHFE.cu2601") {
+		t.Fatal("changed symbol should report changing")
+	}
+	if !dm.IsChanging("quotes") {
+		t.Fatal("parent node should report changing")
+	}
+	// 兄弟合约未变, 父层 epoch 推进不应造成误报
+	if dm.IsChanging("quotes", "DCE.m2601") {
+		t.Fatal("unchanged sibling symbol must not report changing")
+	}
+	if dm.IsChanging("quotes", "DCE.m2601", "last_price") {
+		t.Fatal("unchanged sibling leaf must not report changing")
+	}
+}
+
+func TestMergeNullDeletesSubtree(t *testing.T) {
+	dm := NewDataManager()
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"orders": map[string]interface{}{
+					"ord1": map[string]interface{}{"status": "ALIVE", "volume_left": 2.0},
+					"ord2": map[string]interface{}{"status": "ALIVE", "volume_left": 1.0},
+				},
+				"positions": map[string]interface{}{
+					"SHFE.cu2601": map[string]interface{}{"volume_long": 3.0},
+				},
+			},
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch := dm.Watch(ctx, "trade", "user1", "orders", "ord1", "status")
+
+	// 撤单后服务端对整个 order 发 null
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"orders": map[string]interface{}{"ord1": nil},
+			},
+		},
+	})
+	if _, ok := dm.GetByPath("trade", "user1", "orders", "ord1"); ok {
+		t.Fatal("deleted order still present")
+	}
+	if _, ok := dm.GetByPath("trade", "user1", "orders", "ord2"); !ok {
+		t.Fatal("sibling order dropped")
+	}
+	// 子树内路径的 watcher 收到删除通知
+	select {
+	case <-watch:
+	case <-time.After(time.Second):
+		t.Fatal("descendant watcher not notified on subtree delete")
+	}
+	// 后代路径的变更记录被清理
+	if dm.PathEpoch("trade", "user1", "orders", "ord1", "status") != 0 {
+		t.Fatal("stale descendant epoch after delete")
+	}
+
+	// 平光后的持仓同样整体删除
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"positions": map[string]interface{}{"SHFE.cu2601": nil},
+			},
+		},
+	})
+	if _, ok := dm.GetByPath("trade", "user1", "positions", "SHFE.cu2601"); ok {
+		t.Fatal("deleted position still present")
+	}
+}
+
+func TestDataReaderView(t *testing.T) {
+	client := newTestClient(t)
+	reader := client.DataReader()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := reader.Watch(ctx, "quotes", "SHFE.cu2601")
+
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"last_price": 68000.0},
+		},
+	})
+
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("watch notification not received via reader")
+	}
+	v, ok := reader.GetByPath("quotes", "SHFE.cu2601", "last_price")
+	if !ok || v != 68000.0 {
+		t.Fatalf("GetByPath = %v ok=%v, want 68000", v, ok)
+	}
+	if !reader.IsChanging("quotes", "SHFE.cu2601", "last_price") {
+		t.Fatal("IsChanging should report the merged path")
+	}
+	if reader.Epoch() != client.dm.Epoch() {
+		t.Fatal("reader epoch diverged from underlying snapshot")
+	}
+}
+
+func TestEpochChannelPushesLatest(t *testing.T) {
+	dm := NewDataManager()
+	ch := dm.EpochChannel()
+
+	dm.MergeData(map[string]interface{}{"a": 1.0})
+	select {
+	case e := <-ch:
+		if e != 1 {
+			t.Fatalf("epoch = %d, want 1", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("epoch not pushed after merge")
+	}
+
+	// 消费慢时覆盖旧值只保留最新 epoch
+	dm.MergeData(map[string]interface{}{"a": 2.0})
+	dm.MergeData(map[string]interface{}{"a": 3.0})
+	select {
+	case e := <-ch:
+		if e != 3 {
+			t.Fatalf("coalesced epoch = %d, want 3", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("epoch not pushed after coalescing")
+	}
+	select {
+	case e := <-ch:
+		t.Fatalf("unexpected extra epoch %d", e)
+	default:
+	}
+
+	// BatchMerge 事务完成后也推送
+	dm.BatchMerge(func(mc *MergeContext) {
+		mc.Merge(map[string]interface{}{"b": 1.0})
+	})
+	select {
+	case e := <-ch:
+		if e != 4 {
+			t.Fatalf("batch epoch = %d, want 4", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("epoch not pushed after batch merge")
+	}
+}
+
+func TestLargeTimestampKeepsPrecision(t *testing.T) {
+	dm := NewDataManager()
+	qw := NewTqQuoteWebsocket(&WebSocketConfig{}, dm, defaultLogger())
+
+	// 1501074872000000001 超出 float64 精确整数范围, 经 float64 会被截断
+	const ts = int64(1501074872000000001)
+	pkt := fmt.Sprintf(`{"aid":"rtn_data","data":[{"klines":{"SHFE.cu2601":{"60000000000":{"data":{"0":{"datetime":%d,"close":68000.5}}}}}}]}`, ts)
+	qw.handleIncoming([]byte(pkt))
+
+	v, ok := dm.GetByPath("klines", "SHFE.cu2601", "60000000000", "data", "0", "datetime")
+	if !ok {
+		t.Fatal("datetime not merged")
+	}
+	if got := toInt64(v); got != ts {
+		t.Fatalf("datetime = %d, want %d (precision lost)", got, ts)
+	}
+
+	// 常规范围数值仍按 float64 存储, 与历史行为一致
+	price, ok := dm.GetByPath("klines", "SHFE.cu2601", "60000000000", "data", "0", "close")
+	if !ok || price != 68000.5 {
+		t.Fatalf("close = %v (%T), want float64 68000.5", price, price)
+	}
+}
+
+func TestToInt64JSONNumber(t *testing.T) {
+	if got := toInt64(json.Number("1501074872000000001")); got != 1501074872000000001 {
+		t.Fatalf("toInt64(json.Number) = %d", got)
+	}
+	if got := toFloat64(json.Number("3.14")); got != 3.14 {
+		t.Fatalf("toFloat64(json.Number) = %v", got)
+	}
+}
+
+func TestMaxMemoryEvictsOldestSeries(t *testing.T) {
+	dm := NewDataManager()
+	// 上限 100 条序列数据
+	dm.SetMaxMemory(100 * seriesEntryBytes)
+
+	makeBars := func(n int) map[string]interface{} {
+		bars := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			bars[fmt.Sprintf("%d", i)] = map[string]interface{}{
+				"id": float64(i), "close": 68000.0,
+			}
+		}
+		return bars
+	}
+
+	// 两个序列各 80 条: 第一次合并不超限, 第二次合并后共 160 条超限,
+	// 最久未更新的 cu2601 序列被清理
+	mergeKlines(dm, "SHFE.cu2601", time.Minute, 79, makeBars(80))
+	if _, ok := dm.GetByPath("klines", "SHFE.cu2601", formatDurationKey(time.Minute)); !ok {
+		t.Fatal("first series missing before limit exceeded")
+	}
+	mergeKlines(dm, "DCE.m2601", time.Minute, 79, makeBars(80))
+
+	if _, ok := dm.GetByPath("klines", "SHFE.cu2601", formatDurationKey(time.Minute)); ok {
+		t.Fatal("oldest series not evicted after limit exceeded")
+	}
+	if _, ok := dm.GetByPath("klines", "DCE.m2601", formatDurationKey(time.Minute)); !ok {
+		t.Fatal("most recent series should be kept")
+	}
+
+	// 清理后低于上限, 新数据正常合并
+	mergeKlines(dm, "DCE.m2601", time.Minute, 80, map[string]interface{}{
+		"80": map[string]interface{}{"id": 80.0, "close": 68010.0},
+	})
+	if _, ok := dm.GetByPath("klines", "DCE.m2601", formatDurationKey(time.Minute), "data", "80"); !ok {
+		t.Fatal("merge after eviction failed")
+	}
+}
+
+func TestMaxMemoryDisabledByDefault(t *testing.T) {
+	dm := NewDataManager()
+	bars := make(map[string]interface{}, 300)
+	for i := 0; i < 300; i++ {
+		bars[fmt.Sprintf("%d", i)] = map[string]interface{}{"id": float64(i)}
+	}
+	mergeKlines(dm, "SHFE.cu2601", time.Minute, 299, bars)
+	if _, ok := dm.GetByPath("klines", "SHFE.cu2601", formatDurationKey(time.Minute)); !ok {
+		t.Fatal("series evicted without memory limit configured")
+	}
+}
+
+func TestCallbackSerialFrozenDuringCallback(t *testing.T) {
+	dm := NewDataManager()
+	dm.SetCallbackMode(CallbackSerial)
+	defer dm.Close()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	dm.OnData(func() {
+		once.Do(func() {
+			close(entered)
+			<-release
+		})
+	})
+	dm.MergeData(map[string]interface{}{"a": 1.0})
+	<-entered
+
+	// 回调执行期间合并被冻结, 截面保持回调开始时的状态
+	merged := make(chan struct{})
+	go func() {
+		dm.MergeData(map[string]interface{}{"a": 2.0})
+		close(merged)
+	}()
+	select {
+	case <-merged:
+		t.Fatal("merge completed while serial callback still running")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if v, _ := dm.GetByPath("a"); v != 1.0 {
+		t.Fatalf("a = %v, want 1.0 while callback holds barrier", v)
+	}
+	close(release)
+	select {
+	case <-merged:
+	case <-time.After(time.Second):
+		t.Fatal("merge not released after callback returned")
+	}
+	if v, _ := dm.GetByPath("a"); v != 2.0 {
+		t.Fatalf("a = %v, want 2.0 after barrier released", v)
+	}
+}
+
+func TestDataManagerCloseStopsDispatchLoop(t *testing.T) {
+	dm := NewDataManager()
+	dm.SetCallbackMode(CallbackSerial)
+
+	var calls atomic.Int64
+	dm.OnData(func() { calls.Add(1) })
+	dm.MergeData(map[string]interface{}{"a": 1.0})
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if calls.Load() == 0 {
+		t.Fatal("callback not fired before close")
+	}
+
+	dm.Close()
+	dm.Close() // 幂等
+	before := calls.Load()
+	dm.MergeData(map[string]interface{}{"a": 2.0})
+	time.Sleep(50 * time.Millisecond)
+	if calls.Load() != before {
+		t.Fatal("callback fired after Close")
+	}
+	// 关闭只停回调分发, 截面本身仍可合并与读取
+	if v, _ := dm.GetByPath("a"); v != 2.0 {
+		t.Fatalf("a = %v, want 2.0", v)
+	}
+}
@@ -0,0 +1,37 @@
+package shinny
+
+import "context"
+
+// DataReader 是 DataManager 的只读视图, 只暴露查询与监听能力,
+// 不包含 MergeData 等写方法. 策略等下游模块应依赖本接口而非
+// *DataManager, 以保证数据只能经由 SDK 内部的行情/交易链路写入
+type DataReader interface {
+	// Epoch 返回当前数据版本号
+	Epoch() int64
+	// Get 读取截面顶层节点
+	Get(key string) (interface{}, bool)
+	// GetByPath 按路径逐级读取截面节点
+	GetByPath(path ...string) (interface{}, bool)
+	// IsChanging 判断节点是否在最近一次合并中发生变更
+	IsChanging(path ...string) bool
+	// PathEpoch 返回节点最近一次变更时的 epoch, 未变更过返回 0
+	PathEpoch(path ...string) int64
+	// Watch 监听节点变更, 变更时收到对应 epoch
+	Watch(ctx context.Context, path ...string) <-chan int64
+	// WatchWithOptions 与 Watch 相同, 但可定制缓冲与背压行为
+	WatchWithOptions(ctx context.Context, path string, opts WatchOptions) <-chan int64
+	// Clone 返回整个截面的深拷贝
+	Clone() map[string]interface{}
+	// Dump 序列化整个截面
+	Dump() ([]byte, error)
+	// DumpPath 序列化指定路径的子树
+	DumpPath(path ...string) ([]byte, error)
+}
+
+// 编译期保证 DataManager 实现了只读接口
+var _ DataReader = (*DataManager)(nil)
+
+// DataReader 返回底层数据截面的只读视图
+func (c *Client) DataReader() DataReader {
+	return c.dm
+}
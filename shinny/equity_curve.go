@@ -0,0 +1,117 @@
+package shinny
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EquityPoint 权益曲线上的一个采样点
+type EquityPoint struct {
+	Time    time.Time
+	Balance float64
+}
+
+// equityRecorder 按固定间隔采样账户权益的内存记录器,
+// 由 TradeSession 与 VirtualTrader 共用
+type equityRecorder struct {
+	mu     sync.Mutex
+	points []EquityPoint
+	cancel context.CancelFunc
+}
+
+// start 启动采样 goroutine, sample 返回当前权益, 读取失败时跳过
+// 本次采样; 重复启动会先停掉旧的采样
+func (r *equityRecorder) start(interval time.Duration, sample func() (float64, bool)) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.mu.Lock()
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.cancel = cancel
+	r.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				balance, ok := sample()
+				if !ok {
+					continue
+				}
+				r.mu.Lock()
+				r.points = append(r.points, EquityPoint{Time: now, Balance: balance})
+				r.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// curve 返回已采样点的拷贝
+func (r *equityRecorder) curve() []EquityPoint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]EquityPoint{}, r.points...)
+}
+
+// stop 停止采样, 已采样的点保留
+func (r *equityRecorder) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cancel != nil {
+		r.cancel()
+		r.cancel = nil
+	}
+}
+
+// MaxDrawdown 返回权益曲线的最大回撤比例 (0~1): 从历史峰值到其后
+// 最低点的最大跌幅, 曲线为空或权益未回落时返回 0
+func MaxDrawdown(points []EquityPoint) float64 {
+	var peak, maxDD float64
+	for _, p := range points {
+		if p.Balance > peak {
+			peak = p.Balance
+		}
+		if peak > 0 {
+			if dd := (peak - p.Balance) / peak; dd > maxDD {
+				maxDD = dd
+			}
+		}
+	}
+	return maxDD
+}
+
+// EnableEquityCurve 按 interval 采样账户权益到内存, 会话关闭前持续
+// 记录, 用于绘制资金曲线与回撤统计
+func (s *TradeSession) EnableEquityCurve(interval time.Duration) {
+	s.equity.start(interval, func() (float64, bool) {
+		acc, err := s.readAccount()
+		if err != nil {
+			return 0, false
+		}
+		return acc.Balance, true
+	})
+}
+
+// GetEquityCurve 返回已采样的权益曲线
+func (s *TradeSession) GetEquityCurve() []EquityPoint {
+	return s.equity.curve()
+}
+
+// EnableEquityCurve 按 interval 采样模拟账户权益到内存
+func (v *VirtualTrader) EnableEquityCurve(interval time.Duration) {
+	v.equity.start(interval, func() (float64, bool) {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		return v.account.Balance, true
+	})
+}
+
+// GetEquityCurve 返回已采样的权益曲线
+func (v *VirtualTrader) GetEquityCurve() []EquityPoint {
+	return v.equity.curve()
+}
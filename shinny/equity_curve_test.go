@@ -0,0 +1,60 @@
+package shinny
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEquityCurveSampling(t *testing.T) {
+	vt := NewVirtualTrader(1000000)
+	vt.EnableEquityCurve(5 * time.Millisecond)
+	defer vt.Close()
+
+	deadline := time.Now().Add(time.Second)
+	for len(vt.GetEquityCurve()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	points := vt.GetEquityCurve()
+	if len(points) < 3 {
+		t.Fatalf("sampled %d points, want >= 3", len(points))
+	}
+	for _, p := range points {
+		if p.Balance != 1000000 {
+			t.Fatalf("sampled balance = %v, want 1000000", p.Balance)
+		}
+		if p.Time.IsZero() {
+			t.Fatal("sample missing timestamp")
+		}
+	}
+
+	// Close 后停止采样
+	vt.Close()
+	n := len(vt.GetEquityCurve())
+	time.Sleep(20 * time.Millisecond)
+	if got := len(vt.GetEquityCurve()); got != n {
+		t.Fatalf("curve grew from %d to %d after Close", n, got)
+	}
+}
+
+func TestMaxDrawdown(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	mk := func(balances ...float64) []EquityPoint {
+		out := make([]EquityPoint, len(balances))
+		for i, b := range balances {
+			out[i] = EquityPoint{Time: base.Add(time.Duration(i) * time.Minute), Balance: b}
+		}
+		return out
+	}
+
+	// 峰值 120 回落到 90: 回撤 25%
+	if dd := MaxDrawdown(mk(100, 120, 90, 110)); dd != 0.25 {
+		t.Fatalf("drawdown = %v, want 0.25", dd)
+	}
+	// 单调上涨无回撤
+	if dd := MaxDrawdown(mk(100, 110, 120)); dd != 0 {
+		t.Fatalf("drawdown = %v, want 0", dd)
+	}
+	if dd := MaxDrawdown(nil); dd != 0 {
+		t.Fatalf("empty curve drawdown = %v, want 0", dd)
+	}
+}
@@ -0,0 +1,89 @@
+package shinny
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HistoryLoader 回测历史行情加载器: 通过 SeriesAPI 的历史订阅
+// (focus_datetime 分段拉取) 批量下载指定区间的 K线, 并缓存到本地
+// 文件; 同一区间再次加载时直接读缓存, 避免重复下载. 回测按
+// Replay 逐条回放
+type HistoryLoader struct {
+	api      *SeriesAPI
+	cacheDir string
+}
+
+// NewHistoryLoader 创建历史数据加载器, cacheDir 为空时使用系统临时目录
+func NewHistoryLoader(api *SeriesAPI, cacheDir string) *HistoryLoader {
+	if cacheDir == "" {
+		cacheDir = os.TempDir()
+	}
+	return &HistoryLoader{api: api, cacheDir: cacheDir}
+}
+
+// cachePath 区间缓存文件路径, 按 合约/周期/区间 唯一
+func (l *HistoryLoader) cachePath(symbol string, duration time.Duration, start, end time.Time) string {
+	sanitized := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, symbol)
+	name := fmt.Sprintf("tqsdk-go-history-%s-%d-%d-%d.json",
+		sanitized, duration.Nanoseconds(), start.UnixNano(), end.UnixNano())
+	return filepath.Join(l.cacheDir, name)
+}
+
+// Load 加载 [start, end] 区间的 K线, 按时间升序返回.
+// 本地缓存命中时不访问服务端; 下载成功后写入缓存, 写缓存失败
+// 不影响返回数据
+func (l *HistoryLoader) Load(ctx context.Context, symbol string, duration time.Duration, start, end time.Time) ([]*Kline, error) {
+	path := l.cachePath(symbol, duration, start, end)
+	if data, err := os.ReadFile(path); err == nil {
+		var klines []*Kline
+		if err := json.Unmarshal(data, &klines); err == nil {
+			return klines, nil
+		}
+		// 缓存损坏, 删除后回退网络
+		os.Remove(path)
+	}
+	klines, err := l.api.KlineByTimeRange(ctx, symbol, duration, start, end)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(klines, func(i, j int) bool { return klines[i].Datetime < klines[j].Datetime })
+	if data, err := json.Marshal(klines); err == nil {
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o644); err == nil {
+			os.Rename(tmp, path)
+		}
+	}
+	return klines, nil
+}
+
+// Replay 按时间升序逐条回放区间内的 K线, 数据来自 Load (缓存优先).
+// fn 返回非 nil 错误时中止回放并原样返回该错误
+func (l *HistoryLoader) Replay(ctx context.Context, symbol string, duration time.Duration, start, end time.Time, fn func(*Kline) error) error {
+	klines, err := l.Load(ctx, symbol, duration, start, end)
+	if err != nil {
+		return err
+	}
+	for _, k := range klines {
+		if err := ctxErr(ctx); err != nil {
+			return err
+		}
+		if err := fn(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
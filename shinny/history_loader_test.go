@@ -0,0 +1,135 @@
+package shinny
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// mockChartServer 模拟服务端: 监听 set_chart 报文并下发覆盖区间的日线
+func mockChartServer(client *Client, stop chan struct{}, dayNano func(d int) float64) {
+	day := 24 * time.Hour
+	handled := make(map[string]bool)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(2 * time.Millisecond):
+		}
+		client.quotesWs.TqWebsocket.mu.Lock()
+		queue := append([][]byte(nil), client.quotesWs.queue...)
+		client.quotesWs.TqWebsocket.mu.Unlock()
+		for _, raw := range queue {
+			var pkt map[string]interface{}
+			if json.Unmarshal(raw, &pkt) != nil || pkt["aid"] != "set_chart" {
+				continue
+			}
+			chartID, _ := pkt["chart_id"].(string)
+			if chartID == "" || handled[chartID] {
+				continue
+			}
+			if _, ok := pkt["focus_datetime"]; !ok {
+				continue
+			}
+			handled[chartID] = true
+			bars := map[string]interface{}{}
+			for i := 0; i < 8; i++ {
+				bars[strconv.Itoa(i)] = map[string]interface{}{
+					"datetime": dayNano(i) - float64(2*day.Nanoseconds()),
+					"open":     100.0 + float64(i), "close": 101.0 + float64(i),
+					"high": 102.0, "low": 99.0, "volume": 10.0,
+				}
+			}
+			client.dm.MergeData(map[string]interface{}{
+				"charts": map[string]interface{}{
+					chartID: map[string]interface{}{"more_data": false},
+				},
+				"klines": map[string]interface{}{
+					"SHFE.cu2601": map[string]interface{}{
+						formatDurationKey(day): map[string]interface{}{
+							"last_id": 7.0,
+							"data":    bars,
+						},
+					},
+				},
+			})
+		}
+	}
+}
+
+func TestHistoryLoaderLoadAndReplay(t *testing.T) {
+	client := newTestClient(t, "futr")
+	day := 24 * time.Hour
+	dayNano := func(d int) float64 {
+		return float64(time.Date(2025, 9, d, 0, 0, 0, 0, time.UTC).UnixNano())
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go mockChartServer(client, stop, dayNano)
+
+	cacheDir := t.TempDir()
+	loader := NewHistoryLoader(client.Series(), cacheDir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 9, 5, 0, 0, 0, 0, time.UTC)
+
+	klines, err := loader.Load(ctx, "SHFE.cu2601", day, start, end)
+	if err != nil {
+		t.Fatalf("Load error: %v", err)
+	}
+	if len(klines) != 5 {
+		t.Fatalf("klines = %d, want 5", len(klines))
+	}
+	if _, err := os.Stat(loader.cachePath("SHFE.cu2601", day, start, end)); err != nil {
+		t.Fatalf("cache file not written: %v", err)
+	}
+
+	// 无服务端的新加载器复用本地缓存, 不触发下载
+	offline := NewHistoryLoader(newTestClient(t, "futr").Series(), cacheDir)
+	offCtx, offCancel := context.WithTimeout(context.Background(), time.Second)
+	defer offCancel()
+	cached, err := offline.Load(offCtx, "SHFE.cu2601", day, start, end)
+	if err != nil {
+		t.Fatalf("cached Load error: %v", err)
+	}
+	if len(cached) != len(klines) {
+		t.Fatalf("cached klines = %d, want %d", len(cached), len(klines))
+	}
+
+	// 回放按时间升序逐条触发
+	var replayed []int64
+	if err := offline.Replay(offCtx, "SHFE.cu2601", day, start, end, func(k *Kline) error {
+		replayed = append(replayed, k.Datetime)
+		return nil
+	}); err != nil {
+		t.Fatalf("Replay error: %v", err)
+	}
+	if len(replayed) != 5 {
+		t.Fatalf("replayed = %d, want 5", len(replayed))
+	}
+	for i := 1; i < len(replayed); i++ {
+		if replayed[i] <= replayed[i-1] {
+			t.Fatalf("replay out of order: %v", replayed)
+		}
+	}
+
+	// 回调出错时中止回放并透传错误
+	stopErr := errors.New("stop replay")
+	count := 0
+	err = offline.Replay(offCtx, "SHFE.cu2601", day, start, end, func(k *Kline) error {
+		count++
+		if count == 2 {
+			return stopErr
+		}
+		return nil
+	})
+	if !errors.Is(err, stopErr) || count != 2 {
+		t.Fatalf("replay abort: err=%v count=%d", err, count)
+	}
+}
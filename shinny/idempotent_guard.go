@@ -0,0 +1,108 @@
+package shinny
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// guardEntry 一次已放行下单的记录. 放行时先以在途占位写入,
+// done 关闭后 order/err 才可读, 同 key 的并发调用在 done 上等待
+type guardEntry struct {
+	order *Order
+	err   error
+	at    time.Time
+	done  chan struct{}
+}
+
+// IdempotentOrderGuard 下单去抖中间件: 包装任意 Trader, 相同信号
+// key 在时间窗口内的重复 InsertOrder 被抑制, 直接返回上一次的
+// Order. 适合策略在相邻 tick 重复产生同一信号的场景
+type IdempotentOrderGuard struct {
+	Trader
+
+	window time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*guardEntry
+
+	// now 便于测试注入时钟
+	now func() time.Time
+}
+
+// NewIdempotentOrderGuard 包装 trader, 相同 key 在 window 内只放行
+// 一次下单
+func NewIdempotentOrderGuard(trader Trader, window time.Duration) *IdempotentOrderGuard {
+	return &IdempotentOrderGuard{
+		Trader:  trader,
+		window:  window,
+		entries: make(map[string]*guardEntry),
+		now:     time.Now,
+	}
+}
+
+// signalKey 从请求字段派生默认信号 key
+func signalKey(req *InsertOrderRequest) string {
+	return strings.Join([]string{
+		req.Symbol, req.Direction, req.Offset,
+		strconv.FormatInt(req.Volume, 10), req.PriceType,
+		strconv.FormatFloat(req.LimitPrice, 'g', -1, 64),
+	}, "|")
+}
+
+// InsertOrder 以请求字段派生的 key 去抖下单
+func (g *IdempotentOrderGuard) InsertOrder(ctx context.Context, req *InsertOrderRequest) (*Order, error) {
+	if err := validateOrderRequest(req); err != nil {
+		return nil, err
+	}
+	return g.InsertOrderWithKey(ctx, signalKey(req), req)
+}
+
+// InsertOrderWithKey 以显式信号 key 去抖下单: 窗口内同 key 的重复
+// 请求不再发单, 返回第一次放行产生的 Order. 同 key 的并发调用
+// 等待第一笔发单完成并共享其结果; 发单失败的 key 立即出窗口,
+// 后续请求可重试
+func (g *IdempotentOrderGuard) InsertOrderWithKey(ctx context.Context, key string, req *InsertOrderRequest) (*Order, error) {
+	if key == "" {
+		return nil, fmt.Errorf("shinny: idempotent order guard requires a signal key")
+	}
+	g.mu.Lock()
+	now := g.now()
+	g.pruneLocked(now)
+	if e, ok := g.entries[key]; ok {
+		g.mu.Unlock()
+		<-e.done
+		return e.order, e.err
+	}
+	// 发单前先占位, 并发同 key 的请求在 done 上等待而不是重复发单
+	e := &guardEntry{at: now, done: make(chan struct{})}
+	g.entries[key] = e
+	g.mu.Unlock()
+
+	order, err := g.Trader.InsertOrder(ctx, req)
+	g.mu.Lock()
+	if err != nil {
+		delete(g.entries, key)
+	}
+	e.order, e.err = order, err
+	close(e.done)
+	g.mu.Unlock()
+	return order, err
+}
+
+// pruneLocked 清理已出窗口的记录, 在途占位不参与清理
+func (g *IdempotentOrderGuard) pruneLocked(now time.Time) {
+	for key, e := range g.entries {
+		select {
+		case <-e.done:
+		default:
+			continue
+		}
+		if now.Sub(e.at) >= g.window {
+			delete(g.entries, key)
+		}
+	}
+}
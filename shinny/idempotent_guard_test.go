@@ -0,0 +1,125 @@
+package shinny
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowTrader 拦截 InsertOrder 并阻塞到 release 关闭, 用于拉开
+// 并发同 key 下单的竞争窗口
+type slowTrader struct {
+	Trader
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (s *slowTrader) InsertOrder(ctx context.Context, req *InsertOrderRequest) (*Order, error) {
+	s.calls.Add(1)
+	<-s.release
+	return s.Trader.InsertOrder(ctx, req)
+}
+
+func TestIdempotentGuardSuppressesDuplicates(t *testing.T) {
+	vt := NewVirtualTrader(10000000)
+	vt.UpdateMarketPrice("SHFE.cu2601", 68000)
+	guard := NewIdempotentOrderGuard(vt, time.Second)
+	clock := time.Unix(1700000000, 0)
+	guard.now = func() time.Time { return clock }
+
+	req := &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeAny,
+	}
+	first, err := guard.InsertOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("InsertOrder error: %v", err)
+	}
+	// 窗口内重复信号不再发单, 返回上一次的委托
+	dup, err := guard.InsertOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("duplicated InsertOrder error: %v", err)
+	}
+	if dup.OrderID != first.OrderID {
+		t.Fatalf("duplicate returned new order %s, want %s", dup.OrderID, first.OrderID)
+	}
+	orders, _ := vt.GetOrders(context.Background())
+	if len(orders) != 1 {
+		t.Fatalf("orders = %d, want 1", len(orders))
+	}
+
+	// 不同信号 key 不受抑制
+	if _, err := guard.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionSell, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeAny,
+	}); err != nil {
+		t.Fatalf("different signal InsertOrder error: %v", err)
+	}
+
+	// 出窗口后同一信号重新放行
+	clock = clock.Add(time.Second)
+	again, err := guard.InsertOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("InsertOrder after window error: %v", err)
+	}
+	if again.OrderID == first.OrderID {
+		t.Fatal("order after window should be a new order")
+	}
+	orders, _ = vt.GetOrders(context.Background())
+	if len(orders) != 3 {
+		t.Fatalf("orders = %d, want 3", len(orders))
+	}
+}
+
+func TestIdempotentGuardConcurrentSameKey(t *testing.T) {
+	vt := NewVirtualTrader(10000000)
+	vt.UpdateMarketPrice("SHFE.cu2601", 68000)
+	slow := &slowTrader{Trader: vt, release: make(chan struct{})}
+	guard := NewIdempotentOrderGuard(slow, time.Second)
+
+	req := &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeAny,
+	}
+	const n = 8
+	results := make(chan *Order, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order, err := guard.InsertOrderWithKey(context.Background(), "sig-1", req)
+			if err != nil {
+				t.Errorf("InsertOrderWithKey: %v", err)
+				return
+			}
+			results <- order
+		}()
+	}
+	// 等第一笔进入发单, 其余调用都应落在占位上等待
+	for slow.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(slow.release)
+	wg.Wait()
+	close(results)
+
+	if got := slow.calls.Load(); got != 1 {
+		t.Fatalf("InsertOrder calls = %d, want 1", got)
+	}
+	var firstID string
+	for order := range results {
+		if firstID == "" {
+			firstID = order.OrderID
+		} else if order.OrderID != firstID {
+			t.Fatalf("concurrent call got order %s, want %s", order.OrderID, firstID)
+		}
+	}
+	orders, _ := vt.GetOrders(context.Background())
+	if len(orders) != 1 {
+		t.Fatalf("orders = %d, want 1", len(orders))
+	}
+}
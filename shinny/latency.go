@@ -0,0 +1,68 @@
+package shinny
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyStats 报文处理延迟的分位统计
+type LatencyStats struct {
+	// Count 累计采样条数
+	Count int64
+	// P50/P99 处理耗时分位数, 基于最近一窗采样计算
+	P50 time.Duration
+	P99 time.Duration
+	// Max 最近一窗采样中的最大耗时
+	Max time.Duration
+}
+
+// latencyRecorderCap 延迟采样环形缓冲大小
+const latencyRecorderCap = 4096
+
+// latencyRecorder 处理耗时采样器: 环形缓冲保留最近若干条样本,
+// 统计时排序求分位, 旧样本自然被覆盖
+type latencyRecorder struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	count   int64
+}
+
+func (l *latencyRecorder) record(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.samples == nil {
+		l.samples = make([]time.Duration, 0, latencyRecorderCap)
+	}
+	if len(l.samples) < latencyRecorderCap {
+		l.samples = append(l.samples, d)
+	} else {
+		l.samples[l.next] = d
+		l.next = (l.next + 1) % latencyRecorderCap
+	}
+	l.count++
+}
+
+func (l *latencyRecorder) stats() LatencyStats {
+	l.mu.Lock()
+	samples := append([]time.Duration{}, l.samples...)
+	count := l.count
+	l.mu.Unlock()
+
+	out := LatencyStats{Count: count}
+	if len(samples) == 0 {
+		return out
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	out.P50 = samples[len(samples)*50/100]
+	out.P99 = samples[len(samples)*99/100]
+	out.Max = samples[len(samples)-1]
+	return out
+}
+
+// ProcessingLatency 返回单条报文从收到至解码/合并/回调派发完成的
+// 耗时分位统计
+func (ws *TqWebsocket) ProcessingLatency() LatencyStats {
+	return ws.latency.stats()
+}
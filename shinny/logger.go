@@ -0,0 +1,149 @@
+package shinny
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogConfig 日志配置
+type LogConfig struct {
+	// Level 日志级别: debug / info / warn / error
+	Level string
+	// OutputPath 输出位置, 空或 "stdout" 输出到标准输出, 否则为文件路径
+	OutputPath string
+	// MaxSizeMB 单个日志文件的最大体积 (MB), 超过后轮转, 0 表示不轮转
+	MaxSizeMB int
+	// MaxBackups 轮转后保留的历史文件个数, 0 表示不限制
+	MaxBackups int
+	// MaxAgeDays 轮转文件的保留天数, 0 表示不限制
+	MaxAgeDays int
+}
+
+// NewLogger 根据配置创建 logger, 输出到文件且配置 MaxSizeMB 时
+// 按大小轮转并按 MaxBackups/MaxAgeDays 清理历史文件
+func NewLogger(cfg *LogConfig) *slog.Logger {
+	if cfg == nil {
+		cfg = &LogConfig{Level: "info"}
+	}
+	var w io.Writer = os.Stdout
+	if cfg.OutputPath != "" && cfg.OutputPath != "stdout" {
+		if cfg.MaxSizeMB > 0 {
+			w = newRotateWriter(cfg.OutputPath, int64(cfg.MaxSizeMB)*1024*1024, cfg.MaxBackups, cfg.MaxAgeDays)
+		} else if f, err := os.OpenFile(cfg.OutputPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err == nil {
+			w = f
+		}
+	}
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: parseLevel(cfg.Level)}))
+}
+
+// rotateWriter 按大小轮转的日志输出: 当前文件超过 maxSize 时改名为
+// <path>.<时间戳> 并新建文件, 超出 maxBackups 或 maxAge 的历史文件被删除
+type rotateWriter struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	maxAge     time.Duration
+
+	file *os.File
+	size int64
+}
+
+func newRotateWriter(path string, maxSize int64, maxBackups, maxAgeDays int) *rotateWriter {
+	return &rotateWriter{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+		maxAge:     time.Duration(maxAgeDays) * 24 * time.Hour,
+	}
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		if err := w.openLocked(); err != nil {
+			return 0, err
+		}
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotateWriter) openLocked() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w.file = f
+	if info, err := f.Stat(); err == nil {
+		w.size = info.Size()
+	}
+	return nil
+}
+
+// rotateLocked 把当前文件改名为带时间戳的历史文件并新建当前文件
+func (w *rotateWriter) rotateLocked() error {
+	w.file.Close()
+	w.file = nil
+	backup := w.path + "." + time.Now().Format("20060102T150405.000000000")
+	if err := os.Rename(w.path, backup); err != nil {
+		return err
+	}
+	w.pruneLocked()
+	w.size = 0
+	return w.openLocked()
+}
+
+// pruneLocked 删除超出保留个数或保留天数的历史文件
+func (w *rotateWriter) pruneLocked() {
+	backups, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return
+	}
+	// 时间戳后缀字典序即时间序, 新的在后
+	sort.Strings(backups)
+	if w.maxBackups > 0 && len(backups) > w.maxBackups {
+		for _, path := range backups[:len(backups)-w.maxBackups] {
+			os.Remove(path)
+		}
+		backups = backups[len(backups)-w.maxBackups:]
+	}
+	if w.maxAge <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-w.maxAge)
+	for _, path := range backups {
+		if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(path)
+		}
+	}
+}
+
+func parseLevel(s string) slog.Level {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+func defaultLogger() *slog.Logger {
+	return slog.Default()
+}
@@ -0,0 +1,60 @@
+package shinny
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotateWriterSplitsBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tqgo.log")
+	w := newRotateWriter(path, 100, 2, 0)
+
+	line := bytes.Repeat([]byte("x"), 39)
+	line = append(line, '\n')
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write(line); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+
+	// 当前文件不超过上限
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("current log missing: %v", err)
+	}
+	if info.Size() > 100 {
+		t.Fatalf("current log size = %d, want <= 100", info.Size())
+	}
+	// 历史文件按 MaxBackups 清理
+	backups, _ := filepath.Glob(path + ".*")
+	if len(backups) != 2 {
+		t.Fatalf("backups = %d, want 2", len(backups))
+	}
+	for _, b := range backups {
+		info, err := os.Stat(b)
+		if err != nil {
+			t.Fatalf("backup missing: %v", err)
+		}
+		if info.Size() == 0 || info.Size() > 100 {
+			t.Fatalf("backup size = %d", info.Size())
+		}
+	}
+}
+
+func TestNewLoggerRotatingOutput(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tqgo.log")
+	logger := NewLogger(&LogConfig{Level: "info", OutputPath: path, MaxSizeMB: 1})
+	logger.Info("hello", "n", 1)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("log file missing: %v", err)
+	}
+	if !bytes.Contains(data, []byte("hello")) {
+		t.Fatalf("log content = %q", data)
+	}
+}
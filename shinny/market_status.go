@@ -0,0 +1,59 @@
+package shinny
+
+import "time"
+
+// MarketStatus 合约所处的交易时段状态
+type MarketStatus string
+
+const (
+	MarketStatusTrading MarketStatus = "TRADING" // 连续交易 (开盘)
+	MarketStatusAuction MarketStatus = "AUCTION" // 集合竞价
+	MarketStatusBreak   MarketStatus = "BREAK"   // 盘中休市
+	MarketStatusClosed  MarketStatus = "CLOSED"  // 收盘或未开盘
+)
+
+// sessionSpan 以分钟表示的一天内时段 [start, end), 如 9:00-10:15
+type sessionSpan struct {
+	start, end int
+	status     MarketStatus
+}
+
+// cnFuturesSessions 国内期货交易所的标准交易时段表, 按时段起点排序.
+// 夜盘收盘时间因品种而异 (23:00/01:00/02:30), 这里取最长的 02:30
+var cnFuturesSessions = []sessionSpan{
+	{0 * 60, 2*60 + 30, MarketStatusTrading},      // 夜盘延续段
+	{8*60 + 55, 9 * 60, MarketStatusAuction},      // 日盘集合竞价
+	{9 * 60, 10*60 + 15, MarketStatusTrading},     // 日盘第一节
+	{10*60 + 15, 10*60 + 30, MarketStatusBreak},   // 小节休息
+	{10*60 + 30, 11*60 + 30, MarketStatusTrading}, // 日盘第二节
+	{11*60 + 30, 13*60 + 30, MarketStatusBreak},   // 午间休市
+	{13*60 + 30, 15 * 60, MarketStatusTrading},    // 日盘第三节
+	{20*60 + 55, 21 * 60, MarketStatusAuction},    // 夜盘集合竞价
+	{21 * 60, 24 * 60, MarketStatusTrading},       // 夜盘
+}
+
+// MarketStatusAt 按时刻在一天中的位置判断交易时段状态,
+// 表中未覆盖的时刻视为收盘
+func MarketStatusAt(t time.Time) MarketStatus {
+	minute := t.Hour()*60 + t.Minute()
+	for _, s := range cnFuturesSessions {
+		if minute >= s.start && minute < s.end {
+			return s.status
+		}
+	}
+	return MarketStatusClosed
+}
+
+// MarketStatus 结合行情时间戳推导合约当前交易时段状态:
+// 行情无效或时间戳无法解析时视为收盘. 时段表按国内期货交易所
+// 标准时段取值, 对不设夜盘的品种夜间行情不更新, 不会误判为开盘
+func (q *Quote) MarketStatus() MarketStatus {
+	if !q.IsValid() {
+		return MarketStatusClosed
+	}
+	t, err := parseQuoteDatetime(q.Datetime)
+	if err != nil {
+		return MarketStatusClosed
+	}
+	return MarketStatusAt(t)
+}
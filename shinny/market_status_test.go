@@ -0,0 +1,58 @@
+package shinny
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarketStatusAt(t *testing.T) {
+	day := func(h, m int) time.Time {
+		return time.Date(2026, 8, 27, h, m, 0, 0, time.Local)
+	}
+	cases := []struct {
+		at   time.Time
+		want MarketStatus
+	}{
+		{day(8, 57), MarketStatusAuction},  // 日盘集合竞价
+		{day(9, 30), MarketStatusTrading},  // 日盘第一节
+		{day(10, 20), MarketStatusBreak},   // 小节休息
+		{day(11, 0), MarketStatusTrading},  // 日盘第二节
+		{day(12, 0), MarketStatusBreak},    // 午间休市
+		{day(14, 59), MarketStatusTrading}, // 日盘第三节
+		{day(15, 0), MarketStatusClosed},   // 收盘
+		{day(18, 0), MarketStatusClosed},
+		{day(20, 57), MarketStatusAuction}, // 夜盘集合竞价
+		{day(22, 30), MarketStatusTrading}, // 夜盘
+		{day(1, 30), MarketStatusTrading},  // 夜盘延续段
+		{day(3, 0), MarketStatusClosed},
+	}
+	for _, c := range cases {
+		if got := MarketStatusAt(c.at); got != c.want {
+			t.Errorf("MarketStatusAt(%s) = %s, want %s", c.at.Format("15:04"), got, c.want)
+		}
+	}
+}
+
+func TestQuoteMarketStatus(t *testing.T) {
+	q := &Quote{InstrumentID: "cu2601", ExchangeID: "SHFE"}
+	// 未收到有效行情视为收盘
+	if got := q.MarketStatus(); got != MarketStatusClosed {
+		t.Fatalf("invalid quote status = %s, want CLOSED", got)
+	}
+
+	q.LastPrice = 68000
+	q.Datetime = "2026-08-27 09:30:00.500000"
+	if got := q.MarketStatus(); got != MarketStatusTrading {
+		t.Fatalf("status = %s, want TRADING", got)
+	}
+
+	q.Datetime = "2026-08-27 15:00:00.000000"
+	if got := q.MarketStatus(); got != MarketStatusClosed {
+		t.Fatalf("status = %s, want CLOSED", got)
+	}
+
+	q.Datetime = "not-a-time"
+	if got := q.MarketStatus(); got != MarketStatusClosed {
+		t.Fatalf("unparsable datetime status = %s, want CLOSED", got)
+	}
+}
@@ -0,0 +1,194 @@
+package shinny
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 期权类型, 与合约信息 option_class 字段对应
+const (
+	OptionClassCall = "CALL"
+	OptionClassPut  = "PUT"
+)
+
+// OptionFilter 期权合约筛选条件, 零值字段不参与过滤
+type OptionFilter struct {
+	// Underlying 标的合约完整代码, 如 "SHFE.cu2601"
+	Underlying string
+	// Class 期权类型, OptionClassCall 或 OptionClassPut
+	Class string
+	// MinStrike/MaxStrike 行权价区间, 0 表示不限
+	MinStrike float64
+	MaxStrike float64
+	// ExpireBefore/ExpireAfter 到期时间区间, 零值表示不限
+	ExpireBefore time.Time
+	ExpireAfter  time.Time
+}
+
+// FilterOptions 从全量合约信息中筛选符合条件的期权,
+// 结果按行权价升序排列, 需先 InitMarket 加载合约信息
+func (c *Client) FilterOptions(filter OptionFilter) []*SymbolInfo {
+	out := make([]*SymbolInfo, 0)
+	for _, info := range c.quotesInfo {
+		if !strings.Contains(info.Class, "OPTION") {
+			continue
+		}
+		if filter.Underlying != "" && info.UnderlyingSymbol != filter.Underlying {
+			continue
+		}
+		if filter.Class != "" && info.OptionClass != filter.Class {
+			continue
+		}
+		if filter.MinStrike > 0 && info.StrikePrice < filter.MinStrike {
+			continue
+		}
+		if filter.MaxStrike > 0 && info.StrikePrice > filter.MaxStrike {
+			continue
+		}
+		expire := time.Unix(int64(info.ExpireDatetime), 0)
+		if !filter.ExpireBefore.IsZero() && expire.After(filter.ExpireBefore) {
+			continue
+		}
+		if !filter.ExpireAfter.IsZero() && expire.Before(filter.ExpireAfter) {
+			continue
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].StrikePrice != out[j].StrikePrice {
+			return out[i].StrikePrice < out[j].StrikePrice
+		}
+		return out[i].Symbol() < out[j].Symbol()
+	})
+	return out
+}
+
+// OptionGreeks BS 模型下的期权希腊字母
+type OptionGreeks struct {
+	// Delta 期权价格对标的价的一阶敏感度
+	Delta float64
+	// Gamma Delta 对标的价的敏感度
+	Gamma float64
+	// Vega 期权价格对波动率的敏感度 (每 1 波动率)
+	Vega float64
+	// Theta 期权价格对时间的敏感度 (每年, 通常为负)
+	Theta float64
+}
+
+// OptionCalculator 期权定价计算器, 基于 Black-Scholes 模型
+// 反解隐含波动率并计算希腊字母. 行权价与到期时间从合约信息中读取
+type OptionCalculator struct {
+	client *Client
+	// riskFreeRate 连续复利年化无风险利率
+	riskFreeRate float64
+}
+
+// OptionCalculator 返回基于给定无风险利率的期权计算器
+func (c *Client) OptionCalculator(riskFreeRate float64) *OptionCalculator {
+	return &OptionCalculator{client: c, riskFreeRate: riskFreeRate}
+}
+
+// optionParams 从合约信息中取行权价/到期时间/期权类型
+func (oc *OptionCalculator) optionParams(q *Quote, now time.Time) (strike, years float64, isCall bool, err error) {
+	info, ok := oc.client.quotesInfo[q.Symbol()]
+	if !ok || !strings.Contains(info.Class, "OPTION") {
+		return 0, 0, false, fmt.Errorf("shinny: %s is not a known option", q.Symbol())
+	}
+	strike = info.StrikePrice
+	if strike <= 0 {
+		strike = q.StrikePrice
+	}
+	expireSec := info.ExpireDatetime
+	if expireSec <= 0 {
+		expireSec = q.ExpireDatetime
+	}
+	years = time.Unix(int64(expireSec), 0).Sub(now).Hours() / (24 * 365)
+	if strike <= 0 || years <= 0 {
+		return 0, 0, false, fmt.Errorf("shinny: option %s missing strike or already expired", q.Symbol())
+	}
+	return strike, years, info.OptionClass != OptionClassPut, nil
+}
+
+// ImpliedVolatility 用期权最新价反解 BS 隐含波动率,
+// underlyingPrice 为标的最新价, now 用于计算剩余到期时间
+func (oc *OptionCalculator) ImpliedVolatility(q *Quote, underlyingPrice float64, now time.Time) (float64, error) {
+	strike, years, isCall, err := oc.optionParams(q, now)
+	if err != nil {
+		return 0, err
+	}
+	return bsImpliedVolatility(q.LastPrice, underlyingPrice, strike, oc.riskFreeRate, years, isCall)
+}
+
+// Greeks 按给定波动率计算期权希腊字母,
+// sigma 通常取 ImpliedVolatility 的反解结果
+func (oc *OptionCalculator) Greeks(q *Quote, underlyingPrice, sigma float64, now time.Time) (*OptionGreeks, error) {
+	strike, years, isCall, err := oc.optionParams(q, now)
+	if err != nil {
+		return nil, err
+	}
+	return bsGreeks(underlyingPrice, strike, oc.riskFreeRate, sigma, years, isCall), nil
+}
+
+// normCDF 标准正态分布累积分布函数
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// normPDF 标准正态分布概率密度函数
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
+
+func bsD1D2(s, k, r, sigma, t float64) (float64, float64) {
+	d1 := (math.Log(s/k) + (r+sigma*sigma/2)*t) / (sigma * math.Sqrt(t))
+	return d1, d1 - sigma*math.Sqrt(t)
+}
+
+// BSPrice Black-Scholes 欧式期权理论价
+func BSPrice(s, k, r, sigma, t float64, isCall bool) float64 {
+	d1, d2 := bsD1D2(s, k, r, sigma, t)
+	if isCall {
+		return s*normCDF(d1) - k*math.Exp(-r*t)*normCDF(d2)
+	}
+	return k*math.Exp(-r*t)*normCDF(-d2) - s*normCDF(-d1)
+}
+
+// bsImpliedVolatility 二分法反解隐含波动率, 区间 [1e-4, 5.0]
+func bsImpliedVolatility(price, s, k, r, t float64, isCall bool) (float64, error) {
+	if price <= 0 || s <= 0 {
+		return 0, fmt.Errorf("shinny: invalid option price %v or underlying price %v", price, s)
+	}
+	lo, hi := 1e-4, 5.0
+	if price <= BSPrice(s, k, r, lo, t, isCall) || price >= BSPrice(s, k, r, hi, t, isCall) {
+		return 0, fmt.Errorf("shinny: option price %v out of BS model range", price)
+	}
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if BSPrice(s, k, r, mid, t, isCall) < price {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2, nil
+}
+
+// bsGreeks 计算 BS 模型下的希腊字母
+func bsGreeks(s, k, r, sigma, t float64, isCall bool) *OptionGreeks {
+	d1, d2 := bsD1D2(s, k, r, sigma, t)
+	g := &OptionGreeks{
+		Gamma: normPDF(d1) / (s * sigma * math.Sqrt(t)),
+		Vega:  s * normPDF(d1) * math.Sqrt(t),
+	}
+	if isCall {
+		g.Delta = normCDF(d1)
+		g.Theta = -s*normPDF(d1)*sigma/(2*math.Sqrt(t)) - r*k*math.Exp(-r*t)*normCDF(d2)
+	} else {
+		g.Delta = normCDF(d1) - 1
+		g.Theta = -s*normPDF(d1)*sigma/(2*math.Sqrt(t)) + r*k*math.Exp(-r*t)*normCDF(-d2)
+	}
+	return g
+}
@@ -0,0 +1,106 @@
+package shinny
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBSPriceAndImpliedVolatility(t *testing.T) {
+	// 标准算例: S=100, K=100, r=5%, sigma=20%, T=0.5 年
+	price := BSPrice(100, 100, 0.05, 0.2, 0.5, true)
+	if math.Abs(price-6.8887) > 0.001 {
+		t.Fatalf("BS call price = %v, want ~6.8887", price)
+	}
+	iv, err := bsImpliedVolatility(price, 100, 100, 0.05, 0.5, true)
+	if err != nil {
+		t.Fatalf("implied volatility error: %v", err)
+	}
+	if math.Abs(iv-0.2) > 1e-6 {
+		t.Fatalf("implied volatility = %v, want 0.2", iv)
+	}
+
+	// 价格超出模型上界时应报错而非返回无意义结果
+	if _, err := bsImpliedVolatility(100, 100, 100, 0.05, 0.5, true); err == nil {
+		t.Fatal("expected error for price above model range")
+	}
+}
+
+func TestOptionCalculatorIVAndGreeks(t *testing.T) {
+	client := newTestClient(t)
+	now := time.Unix(1700000000, 0)
+	// 半年 = 0.5 * 365 * 24 * 3600 秒
+	expire := now.Unix() + 15768000
+	client.quotesInfo = map[string]*SymbolInfo{
+		"SHFE.cu2601C68000": {
+			ExchangeID: "SHFE", InstrumentID: "cu2601C68000",
+			Class: "FUTURE_OPTION", OptionClass: OptionClassCall,
+			StrikePrice: 100, ExpireDatetime: float64(expire),
+			UnderlyingSymbol: "SHFE.cu2601",
+		},
+	}
+	oc := client.OptionCalculator(0.05)
+
+	q := &Quote{
+		ExchangeID: "SHFE", InstrumentID: "cu2601C68000",
+		LastPrice: BSPrice(100, 100, 0.05, 0.2, 0.5, true),
+	}
+	iv, err := oc.ImpliedVolatility(q, 100, now)
+	if err != nil {
+		t.Fatalf("ImpliedVolatility error: %v", err)
+	}
+	if math.Abs(iv-0.2) > 1e-4 {
+		t.Fatalf("iv = %v, want 0.2 within 1e-4", iv)
+	}
+
+	g, err := oc.Greeks(q, 100, iv, now)
+	if err != nil {
+		t.Fatalf("Greeks error: %v", err)
+	}
+	// d1 = 0.2475, N(d1) ≈ 0.5977
+	if math.Abs(g.Delta-0.5977) > 0.001 {
+		t.Fatalf("delta = %v, want ~0.5977", g.Delta)
+	}
+	if g.Gamma <= 0 || g.Vega <= 0 || g.Theta >= 0 {
+		t.Fatalf("greeks signs wrong: %+v", g)
+	}
+
+	// 非期权合约应报错
+	if _, err := oc.ImpliedVolatility(&Quote{InstrumentID: "cu2601"}, 100, now); err == nil {
+		t.Fatal("expected error for non-option symbol")
+	}
+}
+
+func TestFilterOptions(t *testing.T) {
+	client := newTestClient(t)
+	now := time.Unix(1700000000, 0)
+	mk := func(id, class string, strike float64, expire time.Time) *SymbolInfo {
+		return &SymbolInfo{
+			ExchangeID: "SHFE", InstrumentID: id, Class: "FUTURE_OPTION",
+			OptionClass: class, StrikePrice: strike,
+			ExpireDatetime:   float64(expire.Unix()),
+			UnderlyingSymbol: "SHFE.cu2601",
+		}
+	}
+	client.quotesInfo = map[string]*SymbolInfo{
+		"SHFE.cu2601C68000": mk("cu2601C68000", OptionClassCall, 68000, now.AddDate(0, 2, 0)),
+		"SHFE.cu2601C70000": mk("cu2601C70000", OptionClassCall, 70000, now.AddDate(0, 2, 0)),
+		"SHFE.cu2601P68000": mk("cu2601P68000", OptionClassPut, 68000, now.AddDate(0, 2, 0)),
+		"SHFE.cu2601C72000": mk("cu2601C72000", OptionClassCall, 72000, now.AddDate(0, 8, 0)),
+		"SHFE.cu2601":       {ExchangeID: "SHFE", InstrumentID: "cu2601", Class: "FUTURE"},
+	}
+
+	got := client.FilterOptions(OptionFilter{
+		Underlying:   "SHFE.cu2601",
+		Class:        OptionClassCall,
+		MaxStrike:    71000,
+		ExpireBefore: now.AddDate(0, 3, 0),
+	})
+	if len(got) != 2 {
+		t.Fatalf("filtered = %d options, want 2", len(got))
+	}
+	// 结果按行权价升序
+	if got[0].StrikePrice != 68000 || got[1].StrikePrice != 70000 {
+		t.Fatalf("strikes = %v/%v, want 68000/70000", got[0].StrikePrice, got[1].StrikePrice)
+	}
+}
@@ -0,0 +1,158 @@
+package shinny
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+)
+
+// routerAccount 路由器中注册的一个账户
+type routerAccount struct {
+	name   string
+	trader Trader
+	weight float64
+}
+
+// RoutedOrder 拆单后单个账户的下单结果
+type RoutedOrder struct {
+	// Account 注册时指定的账户名
+	Account string
+	// Volume 分配到该账户的手数
+	Volume int64
+	// Order 下单成功时的委托单
+	Order *Order
+	// Err 下单失败时的错误
+	Err error
+}
+
+// RouterOption 路由器选项
+type RouterOption func(*OrderRouter)
+
+// WithRouterRollback 任一账户下单失败时撤销本次已报出的其他账户
+// 委托, 保持各账户仓位比例一致; 默认只记录失败继续保留成功委托
+func WithRouterRollback() RouterOption {
+	return func(r *OrderRouter) {
+		r.rollback = true
+	}
+}
+
+// OrderRouter 多账户下单路由器: 注册多个 Trader 与分配权重,
+// RouteOrder 按权重拆分手数后分发到各账户并聚合结果
+type OrderRouter struct {
+	mu       sync.Mutex
+	accounts []*routerAccount
+	rollback bool
+	logger   *slog.Logger
+}
+
+func NewOrderRouter(opts ...RouterOption) *OrderRouter {
+	r := &OrderRouter{logger: defaultLogger()}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// AddTrader 注册一个账户及其分配权重, 权重只决定比例无需归一化
+func (r *OrderRouter) AddTrader(name string, trader Trader, weight float64) error {
+	if trader == nil || weight <= 0 {
+		return fmt.Errorf("shinny: router account %q requires a trader and positive weight", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.accounts {
+		if a.name == name {
+			return fmt.Errorf("shinny: router account %q already registered", name)
+		}
+	}
+	r.accounts = append(r.accounts, &routerAccount{name: name, trader: trader, weight: weight})
+	return nil
+}
+
+// splitVolume 按权重拆分手数, 用最大余额法保证拆分之和等于总手数
+func splitVolume(total int64, weights []float64) []int64 {
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	shares := make([]int64, len(weights))
+	fracs := make([]float64, len(weights))
+	var assigned int64
+	for i, w := range weights {
+		exact := float64(total) * w / sum
+		shares[i] = int64(math.Floor(exact))
+		fracs[i] = exact - float64(shares[i])
+		assigned += shares[i]
+	}
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return fracs[order[a]] > fracs[order[b]] })
+	for i := int64(0); i < total-assigned; i++ {
+		shares[order[i%int64(len(order))]]++
+	}
+	return shares
+}
+
+// RouteOrder 把一笔下单请求按权重拆分到所有注册账户, 返回各账户
+// 的下单结果. 某账户失败时默认记录并继续; 启用 WithRouterRollback
+// 则撤销已报出的委托并返回错误
+func (r *OrderRouter) RouteOrder(ctx context.Context, req *InsertOrderRequest) ([]*RoutedOrder, error) {
+	if err := validateOrderRequest(req); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	accounts := append([]*routerAccount{}, r.accounts...)
+	r.mu.Unlock()
+	if len(accounts) == 0 {
+		return nil, fmt.Errorf("shinny: order router has no registered trader")
+	}
+
+	weights := make([]float64, len(accounts))
+	for i, a := range accounts {
+		weights[i] = a.weight
+	}
+	shares := splitVolume(req.Volume, weights)
+
+	results := make([]*RoutedOrder, 0, len(accounts))
+	var failed bool
+	for i, a := range accounts {
+		res := &RoutedOrder{Account: a.name, Volume: shares[i]}
+		results = append(results, res)
+		if shares[i] == 0 {
+			continue
+		}
+		sub := *req
+		sub.Volume = shares[i]
+		order, err := a.trader.InsertOrder(ctx, &sub)
+		if err != nil {
+			res.Err = err
+			failed = true
+			r.logger.Warn("router leg order failed",
+				"account", a.name, "symbol", req.Symbol, "volume", shares[i], "error", err)
+			if r.rollback {
+				break
+			}
+			continue
+		}
+		res.Order = order
+	}
+
+	if failed && r.rollback {
+		for i, res := range results {
+			if res.Order == nil {
+				continue
+			}
+			if err := accounts[i].trader.CancelOrder(ctx, res.Order.OrderID); err != nil {
+				r.logger.Warn("router rollback cancel failed",
+					"account", res.Account, "order_id", res.Order.OrderID, "error", err)
+			}
+		}
+		return results, fmt.Errorf("shinny: order routing aborted, submitted legs cancelled")
+	}
+	return results, nil
+}
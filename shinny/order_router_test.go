@@ -0,0 +1,88 @@
+package shinny
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRouteOrderSplitsByWeight(t *testing.T) {
+	vt1 := NewVirtualTrader(10000000)
+	vt2 := NewVirtualTrader(10000000)
+	vt1.UpdateMarketPrice("SHFE.cu2601", 68000)
+	vt2.UpdateMarketPrice("SHFE.cu2601", 68000)
+
+	router := NewOrderRouter()
+	if err := router.AddTrader("main", vt1, 2); err != nil {
+		t.Fatalf("AddTrader error: %v", err)
+	}
+	if err := router.AddTrader("hedge", vt2, 1); err != nil {
+		t.Fatalf("AddTrader error: %v", err)
+	}
+
+	results, err := router.RouteOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 9, PriceType: PriceTypeAny,
+	})
+	if err != nil {
+		t.Fatalf("RouteOrder error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %d, want 2", len(results))
+	}
+	// 权重 2:1, 9 手拆为 6/3
+	if results[0].Volume != 6 || results[1].Volume != 3 {
+		t.Fatalf("split = %d/%d, want 6/3", results[0].Volume, results[1].Volume)
+	}
+	for _, res := range results {
+		if res.Err != nil || res.Order == nil {
+			t.Fatalf("leg %s failed: %v", res.Account, res.Err)
+		}
+	}
+
+	pos1, _ := vt1.GetPositions(context.Background())
+	pos2, _ := vt2.GetPositions(context.Background())
+	if pos1["SHFE.cu2601"].VolumeLong != 6 || pos2["SHFE.cu2601"].VolumeLong != 3 {
+		t.Fatalf("positions = %d/%d, want 6/3",
+			pos1["SHFE.cu2601"].VolumeLong, pos2["SHFE.cu2601"].VolumeLong)
+	}
+}
+
+// failingTrader 模拟下单必败的账户
+type failingTrader struct {
+	*VirtualTrader
+}
+
+func (f *failingTrader) InsertOrder(ctx context.Context, req *InsertOrderRequest) (*Order, error) {
+	return nil, errors.New("shinny: rejected by test")
+}
+
+func TestRouteOrderRollbackOnFailure(t *testing.T) {
+	vt1 := NewVirtualTrader(10000000)
+	vt1.UpdateMarketPrice("SHFE.cu2601", 68000)
+	bad := &failingTrader{VirtualTrader: NewVirtualTrader(10000000)}
+
+	router := NewOrderRouter(WithRouterRollback())
+	router.AddTrader("good", vt1, 1)
+	router.AddTrader("bad", bad, 1)
+
+	// 限价单挂在盘口下方不会成交, 回滚时可以撤掉
+	results, err := router.RouteOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 4, PriceType: PriceTypeLimit, LimitPrice: 60000,
+	})
+	if err == nil {
+		t.Fatal("expected rollback error")
+	}
+	if results[1].Err == nil {
+		t.Fatal("failed leg should record its error")
+	}
+	orders, _ := vt1.GetOrders(context.Background())
+	o := orders[results[0].Order.OrderID]
+	if o == nil || o.Status != StatusFinished {
+		t.Fatalf("rolled back order status = %+v, want FINISHED", o)
+	}
+	if o.VolumeLeft != 2 {
+		t.Fatalf("rolled back order filled %d lots unexpectedly", 2-o.VolumeLeft)
+	}
+}
@@ -0,0 +1,134 @@
+package shinny
+
+import (
+	"math"
+	"sync"
+)
+
+// PriceLevel 订单薄一档报价
+type PriceLevel struct {
+	Price  float64
+	Volume int64
+}
+
+// OrderBook 由五档盘口重建的订单薄: 每次行情更新整体替换五档,
+// 读写并发安全
+type OrderBook struct {
+	symbol string
+
+	mu   sync.RWMutex
+	bids []PriceLevel // 买盘, 价格降序
+	asks []PriceLevel // 卖盘, 价格升序
+}
+
+// NewOrderBook 创建一个空订单薄
+func NewOrderBook(symbol string) *OrderBook {
+	return &OrderBook{symbol: symbol}
+}
+
+// Symbol 返回订单薄对应的合约代码
+func (b *OrderBook) Symbol() string { return b.symbol }
+
+// UpdateFromQuote 用 Quote 的五档盘口替换订单薄, 价格为 0/NaN 的
+// 档位 (不足五档) 被跳过
+func (b *OrderBook) UpdateFromQuote(q *Quote) {
+	bids := collectLevels(
+		PriceLevel{q.BidPrice1, q.BidVolume1}, PriceLevel{q.BidPrice2, q.BidVolume2},
+		PriceLevel{q.BidPrice3, q.BidVolume3}, PriceLevel{q.BidPrice4, q.BidVolume4},
+		PriceLevel{q.BidPrice5, q.BidVolume5},
+	)
+	asks := collectLevels(
+		PriceLevel{q.AskPrice1, q.AskVolume1}, PriceLevel{q.AskPrice2, q.AskVolume2},
+		PriceLevel{q.AskPrice3, q.AskVolume3}, PriceLevel{q.AskPrice4, q.AskVolume4},
+		PriceLevel{q.AskPrice5, q.AskVolume5},
+	)
+	b.mu.Lock()
+	b.bids, b.asks = bids, asks
+	b.mu.Unlock()
+}
+
+// UpdateFromTick 用 Tick 的五档盘口替换订单薄
+func (b *OrderBook) UpdateFromTick(t *Tick) {
+	bids := collectLevels(
+		PriceLevel{t.BidPrice1, t.BidVolume1}, PriceLevel{t.BidPrice2, t.BidVolume2},
+		PriceLevel{t.BidPrice3, t.BidVolume3}, PriceLevel{t.BidPrice4, t.BidVolume4},
+		PriceLevel{t.BidPrice5, t.BidVolume5},
+	)
+	asks := collectLevels(
+		PriceLevel{t.AskPrice1, t.AskVolume1}, PriceLevel{t.AskPrice2, t.AskVolume2},
+		PriceLevel{t.AskPrice3, t.AskVolume3}, PriceLevel{t.AskPrice4, t.AskVolume4},
+		PriceLevel{t.AskPrice5, t.AskVolume5},
+	)
+	b.mu.Lock()
+	b.bids, b.asks = bids, asks
+	b.mu.Unlock()
+}
+
+func collectLevels(levels ...PriceLevel) []PriceLevel {
+	out := make([]PriceLevel, 0, len(levels))
+	for _, l := range levels {
+		if l.Price <= 0 || math.IsNaN(l.Price) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// BestBid 返回买一档, 空盘时返回零值
+func (b *OrderBook) BestBid() PriceLevel {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.bids) == 0 {
+		return PriceLevel{}
+	}
+	return b.bids[0]
+}
+
+// BestAsk 返回卖一档, 空盘时返回零值
+func (b *OrderBook) BestAsk() PriceLevel {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.asks) == 0 {
+		return PriceLevel{}
+	}
+	return b.asks[0]
+}
+
+// Depth 返回买卖双边的总挂单量
+func (b *OrderBook) Depth() (bidVolume, askVolume int64) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, l := range b.bids {
+		bidVolume += l.Volume
+	}
+	for _, l := range b.asks {
+		askVolume += l.Volume
+	}
+	return bidVolume, askVolume
+}
+
+// Imbalance 返回买卖盘不平衡度 (买量-卖量)/(买量+卖量), 取值
+// [-1, 1], 正值买盘强; 双边均空时返回 0
+func (b *OrderBook) Imbalance() float64 {
+	bid, ask := b.Depth()
+	total := bid + ask
+	if total == 0 {
+		return 0
+	}
+	return float64(bid-ask) / float64(total)
+}
+
+// Bids 返回买盘五档副本, 价格降序
+func (b *OrderBook) Bids() []PriceLevel {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]PriceLevel(nil), b.bids...)
+}
+
+// Asks 返回卖盘五档副本, 价格升序
+func (b *OrderBook) Asks() []PriceLevel {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]PriceLevel(nil), b.asks...)
+}
@@ -0,0 +1,88 @@
+package shinny
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestOrderBookFromQuote(t *testing.T) {
+	b := NewOrderBook("SHFE.cu2601")
+	b.UpdateFromQuote(&Quote{
+		BidPrice1: 68000, BidVolume1: 10,
+		BidPrice2: 67990, BidVolume2: 20,
+		BidPrice3: 67980, BidVolume3: 30,
+		AskPrice1: 68010, AskVolume1: 5,
+		AskPrice2: 68020, AskVolume2: 15,
+	})
+
+	if best := b.BestBid(); best.Price != 68000 || best.Volume != 10 {
+		t.Fatalf("best bid = %+v", best)
+	}
+	if best := b.BestAsk(); best.Price != 68010 || best.Volume != 5 {
+		t.Fatalf("best ask = %+v", best)
+	}
+	bid, ask := b.Depth()
+	if bid != 60 || ask != 20 {
+		t.Fatalf("depth = %d/%d, want 60/20", bid, ask)
+	}
+	// (60-20)/(60+20) = 0.5
+	if got := b.Imbalance(); got != 0.5 {
+		t.Fatalf("imbalance = %v, want 0.5", got)
+	}
+	// 价格为 0 的档位被跳过
+	if got := len(b.Bids()); got != 3 {
+		t.Fatalf("bid levels = %d, want 3", got)
+	}
+	if got := len(b.Asks()); got != 2 {
+		t.Fatalf("ask levels = %d, want 2", got)
+	}
+}
+
+func TestOrderBookEmpty(t *testing.T) {
+	b := NewOrderBook("SHFE.cu2601")
+	if best := b.BestBid(); best != (PriceLevel{}) {
+		t.Fatalf("empty best bid = %+v", best)
+	}
+	if got := b.Imbalance(); got != 0 {
+		t.Fatalf("empty imbalance = %v, want 0", got)
+	}
+}
+
+func TestQuoteSubscriptionMaintainsOrderBook(t *testing.T) {
+	client := newTestClient(t, "futr")
+	if _, err := client.SubscribeQuote(context.Background(), "SHFE.cu2601"); err != nil {
+		t.Fatalf("SubscribeQuote: %v", err)
+	}
+	client.EnableOrderBook("SHFE.cu2601")
+
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{
+				"last_price": 68000.0,
+				"datetime":   "2026-08-27 10:00:00.000000",
+				"bid_price1": 67990.0, "bid_volume1": 8.0,
+				"ask_price1": 68010.0, "ask_volume1": 4.0,
+			},
+		},
+	})
+
+	book := client.OrderBook("SHFE.cu2601")
+	if book == nil {
+		t.Fatal("order book not enabled")
+	}
+	deadline := time.Now().Add(time.Second)
+	for book.BestBid().Price == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if best := book.BestBid(); best.Price != 67990 || best.Volume != 8 {
+		t.Fatalf("best bid = %+v", best)
+	}
+	if best := book.BestAsk(); best.Price != 68010 || best.Volume != 4 {
+		t.Fatalf("best ask = %+v", best)
+	}
+	// 未启用的合约返回 nil
+	if client.OrderBook("SHFE.au2606") != nil {
+		t.Fatal("unexpected order book for unenabled symbol")
+	}
+}
@@ -0,0 +1,358 @@
+package shinny
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// 本文件实现一个最小化的 Parquet 写出器: PLAIN 编码、不压缩、
+// 全部列 required 无嵌套. 仓库不引入外部依赖, 这里只覆盖行情序列
+// 落盘所需的子集, 产出的文件可被任何标准 Parquet 读取器解析
+
+// thrift compact protocol 类型码
+const (
+	tcBoolTrue  = 1
+	tcBoolFalse = 2
+	tcI32       = 5
+	tcI64       = 6
+	tcBinary    = 8
+	tcList      = 9
+	tcStruct    = 12
+)
+
+// compactWriter thrift compact protocol 编码器, 只实现 Parquet
+// 元数据需要的子集
+type compactWriter struct {
+	buf bytes.Buffer
+	// last 各层 struct 的上一个 field id, 用于 delta 编码
+	last []int16
+}
+
+func (w *compactWriter) writeVarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	w.buf.Write(tmp[:n])
+}
+
+func (w *compactWriter) writeZigzag(v int64) {
+	w.writeVarint(uint64((v << 1) ^ (v >> 63)))
+}
+
+func (w *compactWriter) structBegin() {
+	w.last = append(w.last, 0)
+}
+
+func (w *compactWriter) structEnd() {
+	w.buf.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *compactWriter) fieldHeader(id int16, typ byte) {
+	delta := id - w.last[len(w.last)-1]
+	if delta >= 1 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.writeZigzag(int64(id))
+	}
+	w.last[len(w.last)-1] = id
+}
+
+func (w *compactWriter) fieldI32(id int16, v int32) {
+	w.fieldHeader(id, tcI32)
+	w.writeZigzag(int64(v))
+}
+
+func (w *compactWriter) fieldI64(id int16, v int64) {
+	w.fieldHeader(id, tcI64)
+	w.writeZigzag(v)
+}
+
+func (w *compactWriter) fieldBinary(id int16, v string) {
+	w.fieldHeader(id, tcBinary)
+	w.writeVarint(uint64(len(v)))
+	w.buf.WriteString(v)
+}
+
+func (w *compactWriter) listHeader(size int, elemType byte) {
+	if size < 15 {
+		w.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	w.buf.WriteByte(0xF0 | elemType)
+	w.writeVarint(uint64(size))
+}
+
+// Parquet 枚举值 (parquet.thrift)
+const (
+	parquetInt64  = 2 // Type.INT64
+	parquetDouble = 5 // Type.DOUBLE
+
+	parquetRequired     = 0 // FieldRepetitionType.REQUIRED
+	parquetPlain        = 0 // Encoding.PLAIN
+	parquetRLE          = 3 // Encoding.RLE
+	parquetUncompressed = 0 // CompressionCodec.UNCOMPRESSED
+	parquetDataPage     = 0 // PageType.DATA_PAGE
+)
+
+// parquetColumn 一列待写出的数据, typ 决定取 ints 还是 floats
+type parquetColumn struct {
+	name   string
+	typ    int32
+	ints   []int64
+	floats []float64
+}
+
+func (c *parquetColumn) rows() int {
+	if c.typ == parquetDouble {
+		return len(c.floats)
+	}
+	return len(c.ints)
+}
+
+// encodePlain 按 PLAIN 编码写出 [start, end) 行的列值
+func (c *parquetColumn) encodePlain(start, end int) []byte {
+	out := make([]byte, 0, (end-start)*8)
+	var tmp [8]byte
+	for i := start; i < end; i++ {
+		if c.typ == parquetDouble {
+			binary.LittleEndian.PutUint64(tmp[:], math.Float64bits(c.floats[i]))
+		} else {
+			binary.LittleEndian.PutUint64(tmp[:], uint64(c.ints[i]))
+		}
+		out = append(out, tmp[:]...)
+	}
+	return out
+}
+
+// parquetChunkInfo 单个 column chunk 写出后的元信息
+type parquetChunkInfo struct {
+	column     *parquetColumn
+	pageOffset int64
+	totalSize  int64
+	numValues  int64
+}
+
+// parquetRowGroupInfo 一个 row group 写出后的元信息
+type parquetRowGroupInfo struct {
+	chunks  []parquetChunkInfo
+	numRows int64
+}
+
+// parquetRowGroupRows 默认每个 row group 的行数, 写出时按该批量
+// 分组以限制单批缓冲的内存峰值
+const parquetRowGroupRows = 5000
+
+// writeParquetFile 把各列数据按 rowsPerGroup 分批写成 Parquet 文件
+func writeParquetFile(w io.Writer, cols []*parquetColumn, rowsPerGroup int) error {
+	if len(cols) == 0 {
+		return fmt.Errorf("shinny: parquet export requires at least one column")
+	}
+	numRows := cols[0].rows()
+	for _, c := range cols {
+		if c.rows() != numRows {
+			return fmt.Errorf("shinny: parquet column %q has %d rows, want %d", c.name, c.rows(), numRows)
+		}
+	}
+	if rowsPerGroup <= 0 {
+		rowsPerGroup = parquetRowGroupRows
+	}
+
+	offset := int64(0)
+	write := func(data []byte) error {
+		n, err := w.Write(data)
+		offset += int64(n)
+		return err
+	}
+	if err := write([]byte("PAR1")); err != nil {
+		return err
+	}
+
+	var groups []parquetRowGroupInfo
+	for start := 0; start < numRows || (numRows == 0 && start == 0); start += rowsPerGroup {
+		end := start + rowsPerGroup
+		if end > numRows {
+			end = numRows
+		}
+		g := parquetRowGroupInfo{numRows: int64(end - start)}
+		for _, c := range cols {
+			data := c.encodePlain(start, end)
+			header := encodePageHeader(end-start, len(data))
+			info := parquetChunkInfo{
+				column:     c,
+				pageOffset: offset,
+				totalSize:  int64(len(header) + len(data)),
+				numValues:  int64(end - start),
+			}
+			if err := write(header); err != nil {
+				return err
+			}
+			if err := write(data); err != nil {
+				return err
+			}
+			g.chunks = append(g.chunks, info)
+		}
+		groups = append(groups, g)
+		if numRows == 0 {
+			break
+		}
+	}
+
+	meta := encodeFileMetaData(cols, groups, int64(numRows))
+	if err := write(meta); err != nil {
+		return err
+	}
+	var tail [8]byte
+	binary.LittleEndian.PutUint32(tail[:4], uint32(len(meta)))
+	copy(tail[4:], "PAR1")
+	return write(tail[:])
+}
+
+// encodePageHeader 编码 v1 数据页头: PLAIN 编码, required 列无
+// repetition/definition level
+func encodePageHeader(numValues, dataSize int) []byte {
+	w := &compactWriter{}
+	w.structBegin()
+	w.fieldI32(1, parquetDataPage)
+	w.fieldI32(2, int32(dataSize))
+	w.fieldI32(3, int32(dataSize))
+	w.fieldHeader(5, tcStruct) // data_page_header
+	w.structBegin()
+	w.fieldI32(1, int32(numValues))
+	w.fieldI32(2, parquetPlain)
+	w.fieldI32(3, parquetRLE)
+	w.fieldI32(4, parquetRLE)
+	w.structEnd()
+	w.structEnd()
+	return w.buf.Bytes()
+}
+
+// encodeFileMetaData 编码文件尾部的 FileMetaData
+func encodeFileMetaData(cols []*parquetColumn, groups []parquetRowGroupInfo, numRows int64) []byte {
+	w := &compactWriter{}
+	w.structBegin()
+	w.fieldI32(1, 1) // version
+
+	// schema: 根节点 + 各 required 叶子列
+	w.fieldHeader(2, tcList)
+	w.listHeader(len(cols)+1, tcStruct)
+	w.structBegin()
+	w.fieldBinary(4, "schema")
+	w.fieldI32(5, int32(len(cols)))
+	w.structEnd()
+	for _, c := range cols {
+		w.structBegin()
+		w.fieldI32(1, c.typ)
+		w.fieldI32(3, parquetRequired)
+		w.fieldBinary(4, c.name)
+		w.structEnd()
+	}
+
+	w.fieldI64(3, numRows)
+
+	w.fieldHeader(4, tcList)
+	w.listHeader(len(groups), tcStruct)
+	for _, g := range groups {
+		w.structBegin()
+		w.fieldHeader(1, tcList)
+		w.listHeader(len(g.chunks), tcStruct)
+		var totalSize int64
+		for _, ch := range g.chunks {
+			totalSize += ch.totalSize
+			w.structBegin()
+			w.fieldI64(2, ch.pageOffset) // file_offset
+			w.fieldHeader(3, tcStruct)   // meta_data
+			w.structBegin()
+			w.fieldI32(1, ch.column.typ)
+			w.fieldHeader(2, tcList) // encodings
+			w.listHeader(1, tcI32)
+			w.writeZigzag(parquetPlain)
+			w.fieldHeader(3, tcList) // path_in_schema
+			w.listHeader(1, tcBinary)
+			w.writeVarint(uint64(len(ch.column.name)))
+			w.buf.WriteString(ch.column.name)
+			w.fieldI32(4, parquetUncompressed)
+			w.fieldI64(5, ch.numValues)
+			w.fieldI64(6, ch.totalSize)
+			w.fieldI64(7, ch.totalSize)
+			w.fieldI64(9, ch.pageOffset)
+			w.structEnd()
+			w.structEnd()
+		}
+		w.fieldI64(2, totalSize)
+		w.fieldI64(3, g.numRows)
+		w.structEnd()
+	}
+
+	w.fieldBinary(6, "tqsdk-go")
+	w.structEnd()
+	return w.buf.Bytes()
+}
+
+// WriteParquet 把 K线序列写成 Parquet 文件, 列为 id/datetime/
+// open/high/low/close/volume/open_oi/close_oi, 按批分 row group
+// 写出以限制内存峰值
+func (d *KlineSeriesData) WriteParquet(w io.Writer) error {
+	n := len(d.Klines)
+	cols := []*parquetColumn{
+		{name: "id", typ: parquetInt64, ints: make([]int64, n)},
+		{name: "datetime", typ: parquetInt64, ints: make([]int64, n)},
+		{name: "open", typ: parquetDouble, floats: make([]float64, n)},
+		{name: "high", typ: parquetDouble, floats: make([]float64, n)},
+		{name: "low", typ: parquetDouble, floats: make([]float64, n)},
+		{name: "close", typ: parquetDouble, floats: make([]float64, n)},
+		{name: "volume", typ: parquetInt64, ints: make([]int64, n)},
+		{name: "open_oi", typ: parquetInt64, ints: make([]int64, n)},
+		{name: "close_oi", typ: parquetInt64, ints: make([]int64, n)},
+	}
+	for i, k := range d.Klines {
+		cols[0].ints[i] = k.ID
+		cols[1].ints[i] = k.Datetime
+		cols[2].floats[i] = k.Open
+		cols[3].floats[i] = k.High
+		cols[4].floats[i] = k.Low
+		cols[5].floats[i] = k.Close
+		cols[6].ints[i] = k.Volume
+		cols[7].ints[i] = k.OpenOI
+		cols[8].ints[i] = k.CloseOI
+	}
+	return writeParquetFile(w, cols, parquetRowGroupRows)
+}
+
+// WriteParquet 把 Tick 序列写成 Parquet 文件, 包含一档盘口与
+// 成交/持仓累计值
+func (d *TickSeriesData) WriteParquet(w io.Writer) error {
+	n := len(d.Ticks)
+	cols := []*parquetColumn{
+		{name: "id", typ: parquetInt64, ints: make([]int64, n)},
+		{name: "datetime", typ: parquetInt64, ints: make([]int64, n)},
+		{name: "last_price", typ: parquetDouble, floats: make([]float64, n)},
+		{name: "highest", typ: parquetDouble, floats: make([]float64, n)},
+		{name: "lowest", typ: parquetDouble, floats: make([]float64, n)},
+		{name: "ask_price1", typ: parquetDouble, floats: make([]float64, n)},
+		{name: "ask_volume1", typ: parquetInt64, ints: make([]int64, n)},
+		{name: "bid_price1", typ: parquetDouble, floats: make([]float64, n)},
+		{name: "bid_volume1", typ: parquetInt64, ints: make([]int64, n)},
+		{name: "volume", typ: parquetInt64, ints: make([]int64, n)},
+		{name: "amount", typ: parquetDouble, floats: make([]float64, n)},
+		{name: "open_interest", typ: parquetInt64, ints: make([]int64, n)},
+	}
+	for i, t := range d.Ticks {
+		cols[0].ints[i] = t.ID
+		cols[1].ints[i] = t.Datetime
+		cols[2].floats[i] = t.LastPrice
+		cols[3].floats[i] = t.Highest
+		cols[4].floats[i] = t.Lowest
+		cols[5].floats[i] = t.AskPrice1
+		cols[6].ints[i] = t.AskVolume1
+		cols[7].floats[i] = t.BidPrice1
+		cols[8].ints[i] = t.BidVolume1
+		cols[9].ints[i] = t.Volume
+		cols[10].floats[i] = t.Amount
+		cols[11].ints[i] = t.OpenInterest
+	}
+	return writeParquetFile(w, cols, parquetRowGroupRows)
+}
@@ -0,0 +1,226 @@
+package shinny
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// 本文件实现一个仅供测试的 thrift compact 解码器, 用来读回导出的
+// Parquet 元数据并校验行数/字段/列值
+
+type compactReader struct {
+	r *bytes.Reader
+}
+
+func (cr *compactReader) readVarint(t *testing.T) uint64 {
+	t.Helper()
+	v, err := binary.ReadUvarint(cr.r)
+	if err != nil {
+		t.Fatalf("read varint: %v", err)
+	}
+	return v
+}
+
+func (cr *compactReader) readZigzag(t *testing.T) int64 {
+	u := cr.readVarint(t)
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+// readStruct 解码一个 struct, 返回 field id 到值的映射
+func (cr *compactReader) readStruct(t *testing.T) map[int16]interface{} {
+	t.Helper()
+	out := make(map[int16]interface{})
+	var last int16
+	for {
+		b, err := cr.r.ReadByte()
+		if err != nil {
+			t.Fatalf("read field header: %v", err)
+		}
+		if b == 0 {
+			return out
+		}
+		typ := b & 0x0F
+		delta := int16(b >> 4)
+		var id int16
+		if delta == 0 {
+			id = int16(cr.readZigzag(t))
+		} else {
+			id = last + delta
+		}
+		last = id
+		out[id] = cr.readValue(t, typ)
+	}
+}
+
+func (cr *compactReader) readValue(t *testing.T, typ byte) interface{} {
+	t.Helper()
+	switch typ {
+	case tcBoolTrue:
+		return true
+	case tcBoolFalse:
+		return false
+	case tcI32, tcI64:
+		return cr.readZigzag(t)
+	case tcBinary:
+		n := cr.readVarint(t)
+		buf := make([]byte, n)
+		if _, err := cr.r.Read(buf); err != nil {
+			t.Fatalf("read binary: %v", err)
+		}
+		return string(buf)
+	case tcList:
+		b, err := cr.r.ReadByte()
+		if err != nil {
+			t.Fatalf("read list header: %v", err)
+		}
+		size := int(b >> 4)
+		elem := b & 0x0F
+		if size == 15 {
+			size = int(cr.readVarint(t))
+		}
+		out := make([]interface{}, size)
+		for i := range out {
+			out[i] = cr.readValue(t, elem)
+		}
+		return out
+	case tcStruct:
+		return cr.readStruct(t)
+	default:
+		t.Fatalf("unexpected thrift type %d", typ)
+		return nil
+	}
+}
+
+// readParquetColumn 从导出文件中读回指定列的全部值
+func readParquetColumn(t *testing.T, file []byte, meta map[int16]interface{}, colIndex int, double bool) []float64 {
+	t.Helper()
+	var out []float64
+	for _, rg := range meta[4].([]interface{}) {
+		chunk := rg.(map[int16]interface{})[1].([]interface{})[colIndex].(map[int16]interface{})
+		colMeta := chunk[3].(map[int16]interface{})
+		offset := colMeta[9].(int64)
+		numValues := colMeta[5].(int64)
+		cr := &compactReader{r: bytes.NewReader(file[offset:])}
+		cr.readStruct(t) // 跳过 page header
+		dataStart := offset + int64(len(file[offset:])-cr.r.Len())
+		for i := int64(0); i < numValues; i++ {
+			bits := binary.LittleEndian.Uint64(file[dataStart+i*8:])
+			if double {
+				out = append(out, math.Float64frombits(bits))
+			} else {
+				out = append(out, float64(int64(bits)))
+			}
+		}
+	}
+	return out
+}
+
+func TestWriteParquetRoundTrip(t *testing.T) {
+	data := &KlineSeriesData{Symbol: "SHFE.cu2601"}
+	for i := 0; i < 25; i++ {
+		data.Klines = append(data.Klines, &Kline{
+			ID: int64(i), Datetime: int64(i) * 60e9,
+			Open: 68000, High: 68100, Low: 67900,
+			Close: float64(68000 + i), Volume: int64(10 + i),
+		})
+	}
+	cols := []*parquetColumn{
+		{name: "id", typ: parquetInt64},
+		{name: "close", typ: parquetDouble},
+	}
+	for _, k := range data.Klines {
+		cols[0].ints = append(cols[0].ints, k.ID)
+		cols[1].floats = append(cols[1].floats, k.Close)
+	}
+
+	var buf bytes.Buffer
+	// 每 10 行一个 row group, 25 行应拆成 3 批
+	if err := writeParquetFile(&buf, cols, 10); err != nil {
+		t.Fatalf("writeParquetFile error: %v", err)
+	}
+	file := buf.Bytes()
+	if !bytes.HasPrefix(file, []byte("PAR1")) || !bytes.HasSuffix(file, []byte("PAR1")) {
+		t.Fatal("missing PAR1 magic")
+	}
+
+	metaLen := binary.LittleEndian.Uint32(file[len(file)-8:])
+	metaStart := len(file) - 8 - int(metaLen)
+	cr := &compactReader{r: bytes.NewReader(file[metaStart : len(file)-8])}
+	meta := cr.readStruct(t)
+
+	if rows := meta[3].(int64); rows != 25 {
+		t.Fatalf("num_rows = %d, want 25", rows)
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != 3 {
+		t.Fatalf("schema elements = %d, want root + 2 columns", len(schema))
+	}
+	names := []string{
+		schema[1].(map[int16]interface{})[4].(string),
+		schema[2].(map[int16]interface{})[4].(string),
+	}
+	if names[0] != "id" || names[1] != "close" {
+		t.Fatalf("schema columns = %v", names)
+	}
+	groups := meta[4].([]interface{})
+	if len(groups) != 3 {
+		t.Fatalf("row groups = %d, want 3", len(groups))
+	}
+
+	ids := readParquetColumn(t, file, meta, 0, false)
+	closes := readParquetColumn(t, file, meta, 1, true)
+	if len(ids) != 25 || len(closes) != 25 {
+		t.Fatalf("read back %d/%d values, want 25", len(ids), len(closes))
+	}
+	for i := 0; i < 25; i++ {
+		if ids[i] != float64(i) || closes[i] != float64(68000+i) {
+			t.Fatalf("row %d = id %v close %v", i, ids[i], closes[i])
+		}
+	}
+}
+
+func TestKlineAndTickWriteParquet(t *testing.T) {
+	kl := &KlineSeriesData{Klines: []*Kline{
+		{ID: 0, Datetime: 0, Open: 1, High: 2, Low: 0.5, Close: 1.5, Volume: 10},
+		{ID: 1, Datetime: 60e9, Open: 1.5, High: 3, Low: 1, Close: 2, Volume: 20},
+	}}
+	var buf bytes.Buffer
+	if err := kl.WriteParquet(&buf); err != nil {
+		t.Fatalf("kline WriteParquet error: %v", err)
+	}
+	meta := parquetMetadata(t, buf.Bytes())
+	if rows := meta[3].(int64); rows != 2 {
+		t.Fatalf("kline num_rows = %d, want 2", rows)
+	}
+	if n := len(meta[2].([]interface{})); n != 10 {
+		t.Fatalf("kline schema elements = %d, want 10", n)
+	}
+
+	tk := &TickSeriesData{Ticks: []*Tick{
+		{ID: 0, LastPrice: 68000, AskPrice1: 68010, BidPrice1: 67990, Volume: 5},
+	}}
+	buf.Reset()
+	if err := tk.WriteParquet(&buf); err != nil {
+		t.Fatalf("tick WriteParquet error: %v", err)
+	}
+	meta = parquetMetadata(t, buf.Bytes())
+	if rows := meta[3].(int64); rows != 1 {
+		t.Fatalf("tick num_rows = %d, want 1", rows)
+	}
+	if n := len(meta[2].([]interface{})); n != 13 {
+		t.Fatalf("tick schema elements = %d, want 13", n)
+	}
+}
+
+func parquetMetadata(t *testing.T, file []byte) map[int16]interface{} {
+	t.Helper()
+	if len(file) < 12 {
+		t.Fatal("parquet file too short")
+	}
+	metaLen := binary.LittleEndian.Uint32(file[len(file)-8:])
+	metaStart := len(file) - 8 - int(metaLen)
+	cr := &compactReader{r: bytes.NewReader(file[metaStart : len(file)-8])}
+	return cr.readStruct(t)
+}
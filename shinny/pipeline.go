@@ -0,0 +1,252 @@
+package shinny
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// IndicatorFunc 指标计算函数: 输入完整 K线序列, 输出与 bars 按下标
+// 对齐的一条数值序列, 窗口不足处为 NaN. 通过 get 可读取同一流水线
+// 中先注册指标的输出, 实现指标级联
+type IndicatorFunc func(bars []*Kline, get func(name string) []float64) []float64
+
+type pipelineStage struct {
+	name string
+	fn   IndicatorFunc
+}
+
+// Pipeline 可组合的指标流水线: 注册若干命名指标后接入 K线订阅,
+// 每根新 bar 按注册顺序依次重算全部指标, 结果存入同名序列.
+// 后注册的指标可引用先注册指标的输出 (如对 RSI 再求均线)
+type Pipeline struct {
+	mu     sync.Mutex
+	stages []pipelineStage
+	series map[string][]float64
+}
+
+func NewPipeline() *Pipeline {
+	return &Pipeline{series: make(map[string][]float64)}
+}
+
+// AddIndicator 注册一个命名指标, 计算顺序与注册顺序一致,
+// 级联指标必须晚于其依赖注册; 重名时返回错误
+func (p *Pipeline) AddIndicator(name string, fn IndicatorFunc) error {
+	if name == "" {
+		return fmt.Errorf("shinny: indicator name required")
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, st := range p.stages {
+		if st.name == name {
+			return fmt.Errorf("shinny: indicator %s already registered", name)
+		}
+	}
+	p.stages = append(p.stages, pipelineStage{name: name, fn: fn})
+	return nil
+}
+
+// Recalc 用给定序列重算全部指标, 通常由订阅回调驱动,
+// 也可直接喂入历史数据离线计算
+func (p *Pipeline) Recalc(bars []*Kline) {
+	p.mu.Lock()
+	stages := append([]pipelineStage{}, p.stages...)
+	p.mu.Unlock()
+
+	results := make(map[string][]float64, len(stages))
+	get := func(name string) []float64 { return results[name] }
+	for _, st := range stages {
+		results[st.name] = st.fn(bars, get)
+	}
+
+	p.mu.Lock()
+	p.series = results
+	p.mu.Unlock()
+}
+
+// GetSeries 读取指标的最新输出序列副本, 未注册或尚未计算时返回 nil
+func (p *Pipeline) GetSeries(name string) []float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s, ok := p.series[name]
+	if !ok {
+		return nil
+	}
+	return append([]float64(nil), s...)
+}
+
+// Attach 把流水线接到 K线订阅: symbol 每产生一根新 bar 即重算
+// 全部指标. 一条流水线只跟踪一个合约的一个周期,
+// 多合约/多周期场景请各建流水线
+func (p *Pipeline) Attach(ss *SeriesSubscription, symbol string) {
+	ss.OnSymbolNewBar(symbol, func(data *KlineSeriesData) {
+		p.Recalc(data.Klines)
+	})
+}
+
+// nanSeries 返回长度为 n 的全 NaN 序列, 作为指标输出的初始值
+func nanSeries(n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.NaN()
+	}
+	return out
+}
+
+// sourceSeries 解析指标的输入: source 为空取收盘价,
+// 否则取同流水线中先注册指标的输出序列
+func sourceSeries(source string, bars []*Kline, get func(string) []float64) []float64 {
+	if source == "" {
+		out := make([]float64, len(bars))
+		for i, k := range bars {
+			out[i] = k.Close
+		}
+		return out
+	}
+	return get(source)
+}
+
+// MA 简单移动平均: source 为空基于收盘价, 否则级联引用先注册指标
+// 的输出. 窗口不足 period 或窗口内含 NaN 时输出 NaN
+func MA(source string, period int) IndicatorFunc {
+	return func(bars []*Kline, get func(string) []float64) []float64 {
+		src := sourceSeries(source, bars, get)
+		out := nanSeries(len(src))
+		if period <= 0 {
+			return out
+		}
+		for i := period - 1; i < len(src); i++ {
+			sum, valid := 0.0, true
+			for j := i - period + 1; j <= i; j++ {
+				if math.IsNaN(src[j]) {
+					valid = false
+					break
+				}
+				sum += src[j]
+			}
+			if valid {
+				out[i] = sum / float64(period)
+			}
+		}
+		return out
+	}
+}
+
+// RSI 相对强弱指标 (Wilder 平滑), 基于收盘价, 首个有效值在下标
+// period 处, 之前为 NaN. 窗口内无跌幅时为 100, 无涨跌时为 50
+func RSI(period int) IndicatorFunc {
+	return func(bars []*Kline, _ func(string) []float64) []float64 {
+		out := nanSeries(len(bars))
+		if period <= 0 || len(bars) <= period {
+			return out
+		}
+		var gain, loss float64
+		for i := 1; i <= period; i++ {
+			d := bars[i].Close - bars[i-1].Close
+			if d > 0 {
+				gain += d
+			} else {
+				loss -= d
+			}
+		}
+		avgGain, avgLoss := gain/float64(period), loss/float64(period)
+		out[period] = rsiValue(avgGain, avgLoss)
+		for i := period + 1; i < len(bars); i++ {
+			d := bars[i].Close - bars[i-1].Close
+			var g, l float64
+			if d > 0 {
+				g = d
+			} else {
+				l = -d
+			}
+			avgGain = (avgGain*float64(period-1) + g) / float64(period)
+			avgLoss = (avgLoss*float64(period-1) + l) / float64(period)
+			out[i] = rsiValue(avgGain, avgLoss)
+		}
+		return out
+	}
+}
+
+func rsiValue(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		if avgGain == 0 {
+			return 50
+		}
+		return 100
+	}
+	return 100 - 100/(1+avgGain/avgLoss)
+}
+
+// ATR 平均真实波幅 (Wilder 平滑): 真实波幅取 高-低 / |高-昨收| /
+// |低-昨收| 的最大值, 首个有效值在下标 period 处
+func ATR(period int) IndicatorFunc {
+	return func(bars []*Kline, _ func(string) []float64) []float64 {
+		out := nanSeries(len(bars))
+		if period <= 0 || len(bars) <= period {
+			return out
+		}
+		tr := make([]float64, len(bars))
+		for i := 1; i < len(bars); i++ {
+			hl := bars[i].High - bars[i].Low
+			hc := math.Abs(bars[i].High - bars[i-1].Close)
+			lc := math.Abs(bars[i].Low - bars[i-1].Close)
+			tr[i] = math.Max(hl, math.Max(hc, lc))
+		}
+		var sum float64
+		for i := 1; i <= period; i++ {
+			sum += tr[i]
+		}
+		atr := sum / float64(period)
+		out[period] = atr
+		for i := period + 1; i < len(bars); i++ {
+			atr = (atr*float64(period-1) + tr[i]) / float64(period)
+			out[i] = atr
+		}
+		return out
+	}
+}
+
+// BOLL 布林带, 返回中轨/上轨/下轨三条指标分别注册: 中轨为 period
+// 期收盘价均线, 上下轨偏离中轨 width 倍总体标准差
+func BOLL(period int, width float64) (mid, top, bottom IndicatorFunc) {
+	band := func(sign float64) IndicatorFunc {
+		return func(bars []*Kline, get func(string) []float64) []float64 {
+			m, std := bollBands(bars, period)
+			out := nanSeries(len(bars))
+			for i := range out {
+				if !math.IsNaN(m[i]) {
+					out[i] = m[i] + sign*width*std[i]
+				}
+			}
+			return out
+		}
+	}
+	mid = func(bars []*Kline, get func(string) []float64) []float64 {
+		m, _ := bollBands(bars, period)
+		return m
+	}
+	return mid, band(1), band(-1)
+}
+
+// bollBands 计算布林带的中轨与滚动标准差, 窗口不足处为 NaN
+func bollBands(bars []*Kline, period int) (mid, std []float64) {
+	mid, std = nanSeries(len(bars)), nanSeries(len(bars))
+	if period <= 0 {
+		return mid, std
+	}
+	for i := period - 1; i < len(bars); i++ {
+		var sum float64
+		for j := i - period + 1; j <= i; j++ {
+			sum += bars[j].Close
+		}
+		m := sum / float64(period)
+		var sq float64
+		for j := i - period + 1; j <= i; j++ {
+			d := bars[j].Close - m
+			sq += d * d
+		}
+		mid[i] = m
+		std[i] = math.Sqrt(sq / float64(period))
+	}
+	return mid, std
+}
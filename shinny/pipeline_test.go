@@ -0,0 +1,168 @@
+package shinny
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func testBars(closes ...float64) []*Kline {
+	bars := make([]*Kline, len(closes))
+	for i, c := range closes {
+		bars[i] = &Kline{ID: int64(i), Close: c, High: c, Low: c}
+	}
+	return bars
+}
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestRSINumericCorrectness(t *testing.T) {
+	p := NewPipeline()
+	if err := p.AddIndicator("rsi", RSI(3)); err != nil {
+		t.Fatalf("AddIndicator error: %v", err)
+	}
+	p.Recalc(testBars(10, 11, 12, 11, 12, 13))
+
+	rsi := p.GetSeries("rsi")
+	if len(rsi) != 6 {
+		t.Fatalf("series length = %d, want 6", len(rsi))
+	}
+	for i := 0; i < 3; i++ {
+		if !math.IsNaN(rsi[i]) {
+			t.Fatalf("rsi[%d] = %v, want NaN during warm-up", i, rsi[i])
+		}
+	}
+	// 手算 Wilder RSI(3): 涨跌 +1,+1,-1,+1,+1
+	// i=3: avgGain=2/3 avgLoss=1/3 -> 100-100/(1+2) = 66.666...
+	// i=4: avgGain=7/9 avgLoss=2/9 -> 100-100/4.5 = 77.777...
+	// i=5: avgGain=23/27 avgLoss=4/27 -> 100-100/6.75 = 85.185...
+	want := []float64{100 - 100.0/3, 100 - 100/4.5, 100 - 100/6.75}
+	for i, w := range want {
+		if !almostEqual(rsi[3+i], w) {
+			t.Fatalf("rsi[%d] = %v, want %v", 3+i, rsi[3+i], w)
+		}
+	}
+}
+
+func TestATRNumericCorrectness(t *testing.T) {
+	bars := []*Kline{
+		{ID: 0, High: 10, Low: 9, Close: 9.5},
+		{ID: 1, High: 11, Low: 9.5, Close: 10.5},
+		{ID: 2, High: 11.5, Low: 10, Close: 11},
+		{ID: 3, High: 12, Low: 10.5, Close: 11.5},
+		{ID: 4, High: 13, Low: 11, Close: 12.5},
+	}
+	p := NewPipeline()
+	if err := p.AddIndicator("atr", ATR(3)); err != nil {
+		t.Fatalf("AddIndicator error: %v", err)
+	}
+	p.Recalc(bars)
+
+	atr := p.GetSeries("atr")
+	for i := 0; i < 3; i++ {
+		if !math.IsNaN(atr[i]) {
+			t.Fatalf("atr[%d] = %v, want NaN during warm-up", i, atr[i])
+		}
+	}
+	// TR = 1.5, 1.5, 1.5, 2 -> 首值 SMA = 1.5, 再 Wilder: (1.5*2+2)/3 = 5/3
+	if !almostEqual(atr[3], 1.5) {
+		t.Fatalf("atr[3] = %v, want 1.5", atr[3])
+	}
+	if !almostEqual(atr[4], 5.0/3) {
+		t.Fatalf("atr[4] = %v, want %v", atr[4], 5.0/3)
+	}
+}
+
+func TestBOLLBands(t *testing.T) {
+	p := NewPipeline()
+	mid, top, bottom := BOLL(3, 2)
+	for name, fn := range map[string]IndicatorFunc{"mid": mid, "top": top, "bottom": bottom} {
+		if err := p.AddIndicator(name, fn); err != nil {
+			t.Fatalf("AddIndicator(%s) error: %v", name, err)
+		}
+	}
+	p.Recalc(testBars(10, 12, 14))
+
+	// 均值 12, 总体标准差 sqrt(8/3)
+	std := math.Sqrt(8.0 / 3)
+	if got := p.GetSeries("mid"); !almostEqual(got[2], 12) {
+		t.Fatalf("mid[2] = %v, want 12", got[2])
+	}
+	if got := p.GetSeries("top"); !almostEqual(got[2], 12+2*std) {
+		t.Fatalf("top[2] = %v, want %v", got[2], 12+2*std)
+	}
+	if got := p.GetSeries("bottom"); !almostEqual(got[2], 12-2*std) {
+		t.Fatalf("bottom[2] = %v, want %v", got[2], 12-2*std)
+	}
+}
+
+func TestPipelineCascade(t *testing.T) {
+	p := NewPipeline()
+	if err := p.AddIndicator("rsi", RSI(3)); err != nil {
+		t.Fatalf("AddIndicator error: %v", err)
+	}
+	// 对 RSI 再求 2 期均线 (信号线), 依赖先注册的 rsi 序列
+	if err := p.AddIndicator("rsi_ma", MA("rsi", 2)); err != nil {
+		t.Fatalf("AddIndicator error: %v", err)
+	}
+	if err := p.AddIndicator("rsi", RSI(5)); err == nil {
+		t.Fatal("duplicate indicator name should be rejected")
+	}
+	p.Recalc(testBars(10, 11, 12, 11, 12, 13))
+
+	rsi := p.GetSeries("rsi")
+	sig := p.GetSeries("rsi_ma")
+	// 前一个窗口含 NaN, 首个有效信号值在下标 4
+	if !math.IsNaN(sig[3]) {
+		t.Fatalf("sig[3] = %v, want NaN", sig[3])
+	}
+	if want := (rsi[3] + rsi[4]) / 2; !almostEqual(sig[4], want) {
+		t.Fatalf("sig[4] = %v, want %v", sig[4], want)
+	}
+	if want := (rsi[4] + rsi[5]) / 2; !almostEqual(sig[5], want) {
+		t.Fatalf("sig[5] = %v, want %v", sig[5], want)
+	}
+}
+
+func TestPipelineAttachRecalcOnNewBar(t *testing.T) {
+	client := newTestClient(t, "futr")
+	sub, err := client.Series().Kline(context.Background(), "SHFE.cu2601", time.Minute, 100)
+	if err != nil {
+		t.Fatalf("Kline error: %v", err)
+	}
+	defer sub.Close()
+
+	p := NewPipeline()
+	if err := p.AddIndicator("ma2", MA("", 2)); err != nil {
+		t.Fatalf("AddIndicator error: %v", err)
+	}
+	p.Attach(sub, "SHFE.cu2601")
+
+	mergeKlines(client.dm, "SHFE.cu2601", time.Minute, 1, map[string]interface{}{
+		"0": map[string]interface{}{"datetime": float64(60e9), "close": 68000.0},
+		"1": map[string]interface{}{"datetime": float64(120e9), "close": 68010.0},
+	})
+	waitMA := func(want float64) {
+		deadline := time.Now().Add(time.Second)
+		for {
+			ma := p.GetSeries("ma2")
+			if n := len(ma); n > 0 && almostEqual(ma[n-1], want) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("ma2 = %v, want last %v", p.GetSeries("ma2"), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitMA(68005)
+
+	// 新 bar 到来自动重算
+	mergeKlines(client.dm, "SHFE.cu2601", time.Minute, 2, map[string]interface{}{
+		"2": map[string]interface{}{"datetime": float64(180e9), "close": 68030.0},
+	})
+	waitMA(68020)
+}
@@ -0,0 +1,70 @@
+package shinny
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// AggregatedPosition 一个品种下所有月份合约的持仓汇总
+type AggregatedPosition struct {
+	// Product 品种代码, 形如 "SHFE.rb"
+	Product string
+	// Symbols 参与汇总的合约代码
+	Symbols []string
+
+	VolumeLong  int64
+	VolumeShort int64
+	// NetVolume 净持仓, 多为正 / 空为负
+	NetVolume int64
+
+	Margin      float64
+	FloatProfit float64
+}
+
+// productOf 从合约原始代码解析品种代码: 去掉尾部的月份数字,
+// 如 rb2505 -> rb, MA505 -> MA
+func productOf(instrumentID string) string {
+	return strings.TrimRight(instrumentID, "0123456789")
+}
+
+// aggregatePositions 把持仓按品种聚合净持仓/总保证金/总浮盈
+func aggregatePositions(positions map[string]*Position) map[string]*AggregatedPosition {
+	out := make(map[string]*AggregatedPosition)
+	for symbol, pos := range positions {
+		product := joinSymbol(pos.ExchangeID, productOf(pos.InstrumentID))
+		agg := out[product]
+		if agg == nil {
+			agg = &AggregatedPosition{Product: product}
+			out[product] = agg
+		}
+		agg.Symbols = append(agg.Symbols, symbol)
+		agg.VolumeLong += pos.VolumeLong
+		agg.VolumeShort += pos.VolumeShort
+		agg.NetVolume += pos.VolumeLong - pos.VolumeShort
+		agg.Margin += pos.Margin
+		agg.FloatProfit += pos.FloatProfit
+	}
+	for _, agg := range out {
+		sort.Strings(agg.Symbols)
+	}
+	return out
+}
+
+// GetPositionsByProduct 返回按品种聚合的持仓汇总, key 为品种代码
+func (s *TradeSession) GetPositionsByProduct(ctx context.Context) (map[string]*AggregatedPosition, error) {
+	positions, err := s.GetPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return aggregatePositions(positions), nil
+}
+
+// GetPositionsByProduct 返回按品种聚合的持仓汇总, key 为品种代码
+func (v *VirtualTrader) GetPositionsByProduct(ctx context.Context) (map[string]*AggregatedPosition, error) {
+	positions, err := v.GetPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return aggregatePositions(positions), nil
+}
@@ -0,0 +1,71 @@
+package shinny
+
+import (
+	"testing"
+)
+
+func TestAggregatePositionsByProduct(t *testing.T) {
+	positions := map[string]*Position{
+		"SHFE.rb2505": {
+			ExchangeID: "SHFE", InstrumentID: "rb2505",
+			VolumeLong: 3, Margin: 30000, FloatProfit: 1500,
+		},
+		"SHFE.rb2510": {
+			ExchangeID: "SHFE", InstrumentID: "rb2510",
+			VolumeShort: 2, Margin: 20000, FloatProfit: -500,
+		},
+		"CZCE.MA505": {
+			ExchangeID: "CZCE", InstrumentID: "MA505",
+			VolumeLong: 1, Margin: 5000, FloatProfit: 100,
+		},
+	}
+
+	out := aggregatePositions(positions)
+	if len(out) != 2 {
+		t.Fatalf("products = %d, want 2", len(out))
+	}
+
+	rb := out["SHFE.rb"]
+	if rb == nil {
+		t.Fatal("SHFE.rb missing")
+	}
+	if rb.VolumeLong != 3 || rb.VolumeShort != 2 || rb.NetVolume != 1 {
+		t.Fatalf("rb volumes = %d/%d net %d", rb.VolumeLong, rb.VolumeShort, rb.NetVolume)
+	}
+	if rb.Margin != 50000 || rb.FloatProfit != 1000 {
+		t.Fatalf("rb margin/profit = %v/%v", rb.Margin, rb.FloatProfit)
+	}
+	if len(rb.Symbols) != 2 || rb.Symbols[0] != "SHFE.rb2505" || rb.Symbols[1] != "SHFE.rb2510" {
+		t.Fatalf("rb symbols = %v", rb.Symbols)
+	}
+
+	ma := out["CZCE.MA"]
+	if ma == nil || ma.NetVolume != 1 || ma.Margin != 5000 {
+		t.Fatalf("CZCE.MA = %+v", ma)
+	}
+}
+
+func TestVirtualTraderGetPositionsByProduct(t *testing.T) {
+	vt := NewVirtualTrader(10000000)
+	vt.UpdateMarketPrice("SHFE.rb2505", 3500)
+	vt.UpdateMarketPrice("SHFE.rb2510", 3400)
+	for _, req := range []*InsertOrderRequest{
+		{Symbol: "SHFE.rb2505", Direction: DirectionBuy, Offset: OffsetOpen,
+			Volume: 2, PriceType: PriceTypeAny},
+		{Symbol: "SHFE.rb2510", Direction: DirectionSell, Offset: OffsetOpen,
+			Volume: 1, PriceType: PriceTypeAny},
+	} {
+		if _, err := vt.InsertOrder(nil, req); err != nil {
+			t.Fatalf("InsertOrder error: %v", err)
+		}
+	}
+
+	out, err := vt.GetPositionsByProduct(nil)
+	if err != nil {
+		t.Fatalf("GetPositionsByProduct error: %v", err)
+	}
+	rb := out["SHFE.rb"]
+	if rb == nil || rb.VolumeLong != 2 || rb.VolumeShort != 1 || rb.NetVolume != 1 {
+		t.Fatalf("SHFE.rb = %+v", rb)
+	}
+}
@@ -0,0 +1,202 @@
+package shinny
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Quote 合约行情报价, 字段与 DIFF 协议 quotes 节点对应
+type Quote struct {
+	InstrumentID string `json:"instrument_id"`
+	ExchangeID   string `json:"exchange_id"`
+	Datetime     string `json:"datetime"`
+
+	LastPrice float64 `json:"last_price"`
+
+	AskPrice1  float64 `json:"ask_price1"`
+	AskVolume1 int64   `json:"ask_volume1"`
+	BidPrice1  float64 `json:"bid_price1"`
+	BidVolume1 int64   `json:"bid_volume1"`
+	AskPrice2  float64 `json:"ask_price2"`
+	AskVolume2 int64   `json:"ask_volume2"`
+	BidPrice2  float64 `json:"bid_price2"`
+	BidVolume2 int64   `json:"bid_volume2"`
+	AskPrice3  float64 `json:"ask_price3"`
+	AskVolume3 int64   `json:"ask_volume3"`
+	BidPrice3  float64 `json:"bid_price3"`
+	BidVolume3 int64   `json:"bid_volume3"`
+	AskPrice4  float64 `json:"ask_price4"`
+	AskVolume4 int64   `json:"ask_volume4"`
+	BidPrice4  float64 `json:"bid_price4"`
+	BidVolume4 int64   `json:"bid_volume4"`
+	AskPrice5  float64 `json:"ask_price5"`
+	AskVolume5 int64   `json:"ask_volume5"`
+	BidPrice5  float64 `json:"bid_price5"`
+	BidVolume5 int64   `json:"bid_volume5"`
+
+	Open    float64 `json:"open"`
+	Close   float64 `json:"close"`
+	Highest float64 `json:"highest"`
+	Lowest  float64 `json:"lowest"`
+	Average float64 `json:"average"`
+
+	Volume       int64   `json:"volume"`
+	Amount       float64 `json:"amount"`
+	OpenInterest int64   `json:"open_interest"`
+
+	Settlement      float64 `json:"settlement"`
+	UpperLimit      float64 `json:"upper_limit"`
+	LowerLimit      float64 `json:"lower_limit"`
+	PreOpenInterest int64   `json:"pre_open_interest"`
+	PreSettlement   float64 `json:"pre_settlement"`
+	PreClose        float64 `json:"pre_close"`
+
+	PriceTick      float64 `json:"price_tick"`
+	PriceDecs      int64   `json:"price_decs"`
+	VolumeMultiple float64 `json:"volume_multiple"`
+
+	MaxLimitOrderVolume  int64 `json:"max_limit_order_volume"`
+	MaxMarketOrderVolume int64 `json:"max_market_order_volume"`
+	MinLimitOrderVolume  int64 `json:"min_limit_order_volume"`
+	MinMarketOrderVolume int64 `json:"min_market_order_volume"`
+
+	StrikePrice    float64 `json:"strike_price"`
+	Expired        bool    `json:"expired"`
+	ExpireDatetime float64 `json:"expire_datetime"`
+
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"change_percent"`
+
+	// Stale 标记行情可能已过期: 所在行情连接断开后为 true,
+	// 重连收到该合约的新数据后清除, 不参与序列化
+	Stale bool `json:"-"`
+
+	// Derived 用户注册的派生指标值, 每次行情更新时重算,
+	// 见 QuoteSubscription.AddDerived, 不参与序列化
+	Derived map[string]float64 `json:"-"`
+}
+
+// IsStale 判断行情是否因连接断开而可能过期, 过期期间策略不应
+// 依据该行情做交易决策
+func (q *Quote) IsStale() bool {
+	return q.Stale
+}
+
+// Symbol 返回 "交易所.合约" 形式的完整合约代码
+func (q *Quote) Symbol() string {
+	if q.ExchangeID == "" {
+		return q.InstrumentID
+	}
+	return q.ExchangeID + "." + q.InstrumentID
+}
+
+// IsValid 判断行情是否已收到有效数据: 刚订阅还未收到服务端数据时
+// 字段全为零值, 最新价非零且时间戳非空才视为有效
+func (q *Quote) IsValid() bool {
+	return q.Datetime != "" && q.LastPrice != 0 && !math.IsNaN(q.LastPrice)
+}
+
+// ToTick 把 Quote 适配为 Tick 结构: 两者的五档盘口/最新价/当日累计
+// 字段语义一致, 转换后可复用按 Tick 编写的盘口处理逻辑.
+// Datetime 从行情时间戳解析为纳秒, 解析失败时为 0; Tick 特有的
+// ID 字段无对应来源, 置 0
+func (q *Quote) ToTick() *Tick {
+	t := &Tick{
+		LastPrice:    q.LastPrice,
+		Average:      q.Average,
+		Highest:      q.Highest,
+		Lowest:       q.Lowest,
+		AskPrice1:    q.AskPrice1,
+		AskVolume1:   q.AskVolume1,
+		BidPrice1:    q.BidPrice1,
+		BidVolume1:   q.BidVolume1,
+		AskPrice2:    q.AskPrice2,
+		AskVolume2:   q.AskVolume2,
+		BidPrice2:    q.BidPrice2,
+		BidVolume2:   q.BidVolume2,
+		AskPrice3:    q.AskPrice3,
+		AskVolume3:   q.AskVolume3,
+		BidPrice3:    q.BidPrice3,
+		BidVolume3:   q.BidVolume3,
+		AskPrice4:    q.AskPrice4,
+		AskVolume4:   q.AskVolume4,
+		BidPrice4:    q.BidPrice4,
+		BidVolume4:   q.BidVolume4,
+		AskPrice5:    q.AskPrice5,
+		AskVolume5:   q.AskVolume5,
+		BidPrice5:    q.BidPrice5,
+		BidVolume5:   q.BidVolume5,
+		Volume:       q.Volume,
+		Amount:       q.Amount,
+		OpenInterest: q.OpenInterest,
+	}
+	if ts, err := parseQuoteDatetime(q.Datetime); err == nil {
+		t.Datetime = ts.UnixNano()
+	}
+	return t
+}
+
+// UpdateChange 根据最新价与昨结算价补算涨跌额与涨跌幅,
+// 昨结算价为 0 或 NaN 时不计算
+func (q *Quote) UpdateChange() {
+	if q.PreSettlement == 0 || math.IsNaN(q.PreSettlement) || math.IsNaN(q.LastPrice) {
+		return
+	}
+	q.Change = q.LastPrice - q.PreSettlement
+	q.ChangePercent = q.Change / q.PreSettlement * 100
+}
+
+// Kline K线数据, 时间为该 bar 起点的纳秒时间戳
+type Kline struct {
+	ID       int64   `json:"id"`
+	Datetime int64   `json:"datetime"`
+	Open     float64 `json:"open"`
+	High     float64 `json:"high"`
+	Low      float64 `json:"low"`
+	Close    float64 `json:"close"`
+	Volume   int64   `json:"volume"`
+	OpenOI   int64   `json:"open_oi"`
+	CloseOI  int64   `json:"close_oi"`
+}
+
+// Tick 逐笔行情数据, Volume/Amount 为当日累计值
+type Tick struct {
+	ID           int64   `json:"id"`
+	Datetime     int64   `json:"datetime"`
+	LastPrice    float64 `json:"last_price"`
+	Average      float64 `json:"average"`
+	Highest      float64 `json:"highest"`
+	Lowest       float64 `json:"lowest"`
+	AskPrice1    float64 `json:"ask_price1"`
+	AskVolume1   int64   `json:"ask_volume1"`
+	BidPrice1    float64 `json:"bid_price1"`
+	BidVolume1   int64   `json:"bid_volume1"`
+	AskPrice2    float64 `json:"ask_price2"`
+	AskVolume2   int64   `json:"ask_volume2"`
+	BidPrice2    float64 `json:"bid_price2"`
+	BidVolume2   int64   `json:"bid_volume2"`
+	AskPrice3    float64 `json:"ask_price3"`
+	AskVolume3   int64   `json:"ask_volume3"`
+	BidPrice3    float64 `json:"bid_price3"`
+	BidVolume3   int64   `json:"bid_volume3"`
+	AskPrice4    float64 `json:"ask_price4"`
+	AskVolume4   int64   `json:"ask_volume4"`
+	BidPrice4    float64 `json:"bid_price4"`
+	BidVolume4   int64   `json:"bid_volume4"`
+	AskPrice5    float64 `json:"ask_price5"`
+	AskVolume5   int64   `json:"ask_volume5"`
+	BidPrice5    float64 `json:"bid_price5"`
+	BidVolume5   int64   `json:"bid_volume5"`
+	Volume       int64   `json:"volume"`
+	Amount       float64 `json:"amount"`
+	OpenInterest int64   `json:"open_interest"`
+}
+
+// ConvertToStruct 把截面里的 map 节点转换为结构体, 通过 JSON 往返实现
+func ConvertToStruct(src interface{}, dst interface{}) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
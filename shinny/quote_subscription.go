@@ -0,0 +1,657 @@
+package shinny
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QuoteSubscription 管理行情订阅集合, 截面变更时把变化的合约
+// 转换为 Quote 结构并分发给回调. 配置多条行情连接时按合约哈希
+// 把订阅拆分到各连接, 各连接的数据仍合并进同一个 DataManager
+type QuoteSubscription struct {
+	wss    []*TqQuoteWebsocket
+	dm     *DataManager
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	symbols map[string]struct{}
+	// refs 各合约的订阅引用计数: 多处订阅同一合约共享底层订阅,
+	// 计数归零时才真正退订
+	refs map[string]int
+	// confirmed 已收到首个 quote 的合约, 表示订阅在服务端确认生效
+	confirmed map[string]struct{}
+	handlers  []func(*Quote)
+	// firstQuoteFns 合约首次出现有效行情时触发一次的回调,
+	// firstSeen 记录已触发过的合约
+	firstQuoteFns []func(*Quote)
+	firstSeen     map[string]struct{}
+	// typedHandlers 区分快照/增量的行情回调: 合约首次收齐有效数据
+	// 时以 snapshot 标志触发, 之后均为增量更新
+	typedHandlers []func(*Quote, bool)
+	// channels 为 SymbolChannel 建立的分发通道
+	channels map[string][]chan *Quote
+	// lastDatetime 最近一次行情更新的时间戳, 用于估算服务器时间
+	lastDatetime string
+	// fields 非空时只反序列化这些字段到 Quote, 其余保持零值
+	fields map[string]struct{}
+
+	// highPriority 核心合约集合: 截面变更时先于其他合约分发,
+	// SymbolChannel 使用更大缓冲, 且不受降频影响
+	highPriority map[string]struct{}
+	// lowPriorityInterval 次要合约的最小分发间隔, 间隔内的更新
+	// 合并为最新一次, 0 表示不降频
+	lowPriorityInterval time.Duration
+	lastLowDispatch     time.Time
+
+	// throttleInterval 每合约的最小推送间隔, 间隔内的更新合并丢弃,
+	// 间隔结束时补推最新值, 保证最后一个值一定送达, 0 表示不节流
+	throttleInterval time.Duration
+	// lastPush / pendingPush 各合约最近一次推送时间与间隔内待补推标记
+	lastPush    map[string]time.Time
+	pendingPush map[string]bool
+
+	// seenQuotesEpoch / seenEpochs 上次分发处理到的路径 epoch,
+	// 异步回调下按进度比较, 不依赖 "当前 epoch" 判断
+	seenQuotesEpoch int64
+	seenEpochs      map[string]int64
+
+	// orderBooks 启用了订单薄维护的合约, 见 EnableOrderBook
+	orderBooks map[string]*OrderBook
+
+	// limitFns / limitStates 涨跌停状态回调与各合约当前状态,
+	// 见 OnLimitStateChange
+	limitFns    map[string][]func(LimitState)
+	limitStates map[string]LimitState
+
+	// stale 因连接断开被标记为过期行情的合约, 收到新数据后清除;
+	// staleFns 为 OnStale 注册的断线回调
+	stale    map[string]struct{}
+	staleFns []func(symbols []string)
+
+	// derivedFns 用户注册的派生指标计算函数, derivedVals 为各合约
+	// 最近一次更新时的计算结果缓存, 见 AddDerived
+	derivedFns  map[string]func(*Quote) float64
+	derivedVals map[string]map[string]float64
+}
+
+// LimitState 合约涨跌停状态
+type LimitState string
+
+const (
+	// LimitStateNormal 未触板
+	LimitStateNormal LimitState = "NORMAL"
+	// LimitStateUp 涨停
+	LimitStateUp LimitState = "UP"
+	// LimitStateDown 跌停
+	LimitStateDown LimitState = "DOWN"
+)
+
+// limitStateTransition 根据最新行情计算涨跌停状态迁移: 触及涨/跌停价
+// 进入 UP/DOWN; 离板回到 NORMAL 需要价格离开停板价至少两跳,
+// 贴板一跳内的反复抖动不触发状态切换 (去抖)
+func limitStateTransition(cur LimitState, q *Quote) LimitState {
+	if !q.IsValid() || q.UpperLimit <= 0 || q.LowerLimit <= 0 {
+		return cur
+	}
+	switch cur {
+	case LimitStateUp:
+		if q.LastPrice <= q.UpperLimit-2*q.PriceTick {
+			return LimitStateNormal
+		}
+		return LimitStateUp
+	case LimitStateDown:
+		if q.LastPrice >= q.LowerLimit+2*q.PriceTick {
+			return LimitStateNormal
+		}
+		return LimitStateDown
+	default:
+		if q.LastPrice >= q.UpperLimit {
+			return LimitStateUp
+		}
+		if q.LastPrice <= q.LowerLimit {
+			return LimitStateDown
+		}
+		return LimitStateNormal
+	}
+}
+
+// highPriorityChanBuffer 高优先级合约通道的缓冲大小, 普通合约为 10
+const highPriorityChanBuffer = 256
+
+func newQuoteSubscription(wss []*TqQuoteWebsocket, dm *DataManager, logger *slog.Logger) *QuoteSubscription {
+	s := &QuoteSubscription{
+		wss:          wss,
+		dm:           dm,
+		logger:       logger,
+		symbols:      make(map[string]struct{}),
+		refs:         make(map[string]int),
+		firstSeen:    make(map[string]struct{}),
+		lastPush:     make(map[string]time.Time),
+		pendingPush:  make(map[string]bool),
+		confirmed:    make(map[string]struct{}),
+		channels:     make(map[string][]chan *Quote),
+		highPriority: make(map[string]struct{}),
+		seenEpochs:   make(map[string]int64),
+		stale:        make(map[string]struct{}),
+	}
+	dm.OnData(s.onData)
+	return s
+}
+
+// AddSymbols 增加订阅合约并重发全量订阅报文. 重复订阅的合约只增加
+// 引用计数, 底层订阅共享
+func (s *QuoteSubscription) AddSymbols(symbols ...string) error {
+	s.mu.Lock()
+	for _, sym := range symbols {
+		s.refs[sym]++
+		s.symbols[sym] = struct{}{}
+	}
+	insLists := s.shardInsListsLocked()
+	s.mu.Unlock()
+	return s.sendSubscribe(insLists)
+}
+
+// RemoveSymbols 移除订阅合约并重发全量订阅报文. 按引用计数处理:
+// 只有最后一个引用取消时才真正退订
+func (s *QuoteSubscription) RemoveSymbols(symbols ...string) error {
+	s.mu.Lock()
+	for _, sym := range symbols {
+		if s.refs[sym] > 1 {
+			s.refs[sym]--
+			continue
+		}
+		delete(s.refs, sym)
+		delete(s.symbols, sym)
+		delete(s.confirmed, sym)
+		delete(s.firstSeen, sym)
+	}
+	insLists := s.shardInsListsLocked()
+	s.mu.Unlock()
+	return s.sendSubscribe(insLists)
+}
+
+// shardFor 按合约名哈希选择承载该合约订阅的连接
+func (s *QuoteSubscription) shardFor(symbol string) int {
+	if len(s.wss) <= 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(symbol))
+	return int(h.Sum32() % uint32(len(s.wss)))
+}
+
+// shardInsListsLocked 把当前订阅集合拆分为每条连接的 ins_list
+func (s *QuoteSubscription) shardInsListsLocked() []string {
+	shards := make([][]string, len(s.wss))
+	for sym := range s.symbols {
+		i := s.shardFor(sym)
+		shards[i] = append(shards[i], sym)
+	}
+	lists := make([]string, len(shards))
+	for i, list := range shards {
+		sort.Strings(list)
+		lists[i] = strings.Join(list, ",")
+	}
+	return lists
+}
+
+// sendSubscribe 把各连接的订阅报文发出, 返回第一个发送错误
+func (s *QuoteSubscription) sendSubscribe(insLists []string) error {
+	var firstErr error
+	for i, ws := range s.wss {
+		if err := ws.SubscribeQuote(insLists[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Symbols 返回当前订阅的合约列表
+func (s *QuoteSubscription) Symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]string, 0, len(s.symbols))
+	for sym := range s.symbols {
+		list = append(list, sym)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// IsSubscribed 判断一个合约是否已确认订阅 (收到过该合约的 quote)
+func (s *QuoteSubscription) IsSubscribed(symbol string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.confirmed[symbol]
+	return ok
+}
+
+// SubscribedSymbols 返回已确认订阅的合约列表
+func (s *QuoteSubscription) SubscribedSymbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]string, 0, len(s.confirmed))
+	for sym := range s.confirmed {
+		list = append(list, sym)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// OnQuote 注册行情回调, 任一订阅合约更新时触发
+func (s *QuoteSubscription) OnQuote(handler func(*Quote)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler)
+}
+
+// OnFirstQuote 注册合约首个有效行情回调: 每个合约只在其 Quote 首次
+// 通过 IsValid 校验时触发一次, 适合等待行情就绪后再启动策略
+func (s *QuoteSubscription) OnFirstQuote(handler func(*Quote)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.firstQuoteFns = append(s.firstQuoteFns, handler)
+}
+
+// OnQuoteWithType 注册区分快照/增量的行情回调: 每个合约首次通过
+// IsValid 校验时以 isSnapshot=true 触发 (策略可先消化完整快照),
+// 之后的更新均为 isSnapshot=false; 数据未收齐前不触发
+func (s *QuoteSubscription) OnQuoteWithType(handler func(q *Quote, isSnapshot bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.typedHandlers = append(s.typedHandlers, handler)
+}
+
+// SetHighPriority 标记核心合约: 截面变更时先于普通合约分发,
+// 之后建立的 Channel 使用更大缓冲, 且不受 SetLowPriorityInterval
+// 降频影响. 应在建立通道前调用
+func (s *QuoteSubscription) SetHighPriority(symbols ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sym := range symbols {
+		s.highPriority[sym] = struct{}{}
+	}
+}
+
+// HighPrioritySymbols 返回已标记高优先级的合约列表
+func (s *QuoteSubscription) HighPrioritySymbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]string, 0, len(s.highPriority))
+	for sym := range s.highPriority {
+		list = append(list, sym)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// SetLowPriorityInterval 设置普通合约的最小分发间隔: 间隔内的多次
+// 更新只分发最新截面, 高优先级合约不受影响, 0 恢复全量分发
+func (s *QuoteSubscription) SetLowPriorityInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lowPriorityInterval = interval
+}
+
+// Channel 返回指定合约的行情通道, 通道写满时丢弃旧值之后的新推送,
+// 高优先级合约使用更大缓冲以避免瞬时消费延迟丢数据
+func (s *QuoteSubscription) Channel(symbol string) <-chan *Quote {
+	s.mu.Lock()
+	buffer := 10
+	if _, ok := s.highPriority[symbol]; ok {
+		buffer = highPriorityChanBuffer
+	}
+	ch := make(chan *Quote, buffer)
+	s.channels[symbol] = append(s.channels[symbol], ch)
+	s.mu.Unlock()
+	return ch
+}
+
+// LatestDatetime 返回最近一次行情更新携带的时间戳, 可能为空
+func (s *QuoteSubscription) LatestDatetime() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastDatetime
+}
+
+// WithFields 设置字段裁剪: 之后返回的 Quote 只填充 fields 列出的
+// 截面字段 (DIFF 字段名, 如 last_price / bid_price1), 其余保持零值,
+// 减少大量合约高频更新时的反序列化开销. 传空恢复全字段
+func (s *QuoteSubscription) WithFields(fields []string) *QuoteSubscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(fields) == 0 {
+		s.fields = nil
+		return s
+	}
+	s.fields = make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		s.fields[f] = struct{}{}
+	}
+	return s
+}
+
+// WithThrottle 设置每合约的最小推送间隔: 间隔内的多次更新合并为
+// 最新一次, 间隔结束时补推, 保证最后一个值一定被推送. 适合 UI 或
+// 低频策略消费高频合约行情, 传 0 恢复全量推送
+func (s *QuoteSubscription) WithThrottle(interval time.Duration) *QuoteSubscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.throttleInterval = interval
+	return s
+}
+
+// throttleAllows 判断一个合约当前是否允许推送: 距上次推送不足
+// 节流间隔时返回 false, 并安排间隔结束后补推最新值
+func (s *QuoteSubscription) throttleAllows(symbol string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.throttleInterval <= 0 {
+		return true
+	}
+	now := time.Now()
+	elapsed := now.Sub(s.lastPush[symbol])
+	if elapsed >= s.throttleInterval {
+		s.lastPush[symbol] = now
+		return true
+	}
+	if !s.pendingPush[symbol] {
+		s.pendingPush[symbol] = true
+		time.AfterFunc(s.throttleInterval-elapsed, func() { s.flushThrottled(symbol) })
+	}
+	return false
+}
+
+// flushThrottled 节流间隔结束后补推合约的最新截面
+func (s *QuoteSubscription) flushThrottled(symbol string) {
+	s.mu.Lock()
+	if !s.pendingPush[symbol] {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.pendingPush, symbol)
+	s.lastPush[symbol] = time.Now()
+	s.mu.Unlock()
+	q, err := s.getQuote(symbol)
+	if err != nil {
+		return
+	}
+	s.deliver(symbol, q)
+}
+
+// getQuote 从截面读取一个合约的 Quote: 取节点深拷贝再转换,
+// 避免序列化期间与下一次合并竞争
+func (s *QuoteSubscription) getQuote(symbol string) (*Quote, error) {
+	raw, ok := s.dm.GetCopyByPath("quotes", symbol)
+	if !ok {
+		return nil, fmt.Errorf("shinny: no quote data for %s", symbol)
+	}
+	s.mu.Lock()
+	fields := s.fields
+	s.mu.Unlock()
+	if len(fields) > 0 {
+		if node, ok := raw.(map[string]interface{}); ok {
+			pruned := make(map[string]interface{}, len(fields))
+			for k, v := range node {
+				if _, keep := fields[k]; keep {
+					pruned[k] = v
+				}
+			}
+			raw = pruned
+		}
+	}
+	q := &Quote{}
+	if err := ConvertToStruct(raw, q); err != nil {
+		return nil, err
+	}
+	if q.InstrumentID == "" {
+		_, q.InstrumentID = splitSymbol(symbol)
+	}
+	if q.ExchangeID == "" {
+		q.ExchangeID, _ = splitSymbol(symbol)
+	}
+	// 服务端未下发涨跌幅时本地补算
+	if q.Change == 0 && q.ChangePercent == 0 {
+		q.UpdateChange()
+	}
+	s.mu.Lock()
+	_, q.Stale = s.stale[symbol]
+	if vals := s.derivedVals[symbol]; len(vals) > 0 {
+		q.Derived = make(map[string]float64, len(vals))
+		for name, v := range vals {
+			q.Derived[name] = v
+		}
+	}
+	s.mu.Unlock()
+	return q, nil
+}
+
+// GetQuote 读取合约当前行情快照
+func (s *QuoteSubscription) GetQuote(symbol string) (*Quote, error) {
+	return s.getQuote(symbol)
+}
+
+// onData 截面变更后分发变化的合约行情: 高优先级合约先分发,
+// 普通合约在配置降频间隔时合并为最新一次
+func (s *QuoteSubscription) onData() {
+	quotesEpoch := s.dm.PathEpoch("quotes")
+	s.mu.Lock()
+	if quotesEpoch == 0 || quotesEpoch <= s.seenQuotesEpoch {
+		s.mu.Unlock()
+		return
+	}
+	s.seenQuotesEpoch = quotesEpoch
+	var highSyms, lowSyms []string
+	for sym := range s.symbols {
+		if _, ok := s.highPriority[sym]; ok {
+			highSyms = append(highSyms, sym)
+		} else {
+			lowSyms = append(lowSyms, sym)
+		}
+	}
+	interval := s.lowPriorityInterval
+	dispatchLow := interval <= 0 || time.Since(s.lastLowDispatch) >= interval
+	if dispatchLow {
+		s.lastLowDispatch = time.Now()
+	}
+	s.mu.Unlock()
+
+	symbols := highSyms
+	if dispatchLow {
+		symbols = append(symbols, lowSyms...)
+	}
+	for _, sym := range symbols {
+		pe := s.dm.PathEpoch("quotes", sym)
+		s.mu.Lock()
+		seen := s.seenEpochs[sym]
+		if pe != 0 && pe > seen {
+			s.seenEpochs[sym] = pe
+		}
+		s.mu.Unlock()
+		if pe == 0 || pe <= seen {
+			continue
+		}
+		if !s.throttleAllows(sym) {
+			continue
+		}
+		q, err := s.getQuote(sym)
+		if err != nil {
+			continue
+		}
+		s.deliver(sym, q)
+	}
+}
+
+// EnableOrderBook 为一批合约启用订单薄维护: 每次行情分发时用五档
+// 盘口更新对应 OrderBook, 重复启用复用已有实例
+func (s *QuoteSubscription) EnableOrderBook(symbols ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.orderBooks == nil {
+		s.orderBooks = make(map[string]*OrderBook)
+	}
+	for _, sym := range symbols {
+		if _, ok := s.orderBooks[sym]; !ok {
+			s.orderBooks[sym] = NewOrderBook(sym)
+		}
+	}
+}
+
+// OrderBook 返回合约的订单薄, 未启用时返回 nil
+func (s *QuoteSubscription) OrderBook(symbol string) *OrderBook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.orderBooks[symbol]
+}
+
+// AddDerived 注册一个派生指标: 每次行情更新时对最新 Quote 计算
+// fn 并缓存, 结果通过 Quote.Derived 或 Derived 读取. 同名注册覆盖
+func (s *QuoteSubscription) AddDerived(name string, fn func(*Quote) float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.derivedFns == nil {
+		s.derivedFns = make(map[string]func(*Quote) float64)
+		s.derivedVals = make(map[string]map[string]float64)
+	}
+	s.derivedFns[name] = fn
+}
+
+// Derived 读取合约某个派生指标最近一次更新时的缓存值,
+// 尚未计算过时第二个返回值为 false
+func (s *QuoteSubscription) Derived(symbol, name string) (float64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.derivedVals[symbol][name]
+	return v, ok
+}
+
+// OnStale 注册行情过期回调: 行情连接断开时以受影响的合约列表触发,
+// 合约在重连收到新数据前 IsStale 为 true
+func (s *QuoteSubscription) OnStale(handler func(symbols []string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.staleFns = append(s.staleFns, handler)
+}
+
+// markStale 把指定连接承载的订阅合约标记为过期并触发 OnStale 回调,
+// 连接断开时由 Client 注册的钩子调用
+func (s *QuoteSubscription) markStale(shard int) {
+	s.mu.Lock()
+	var affected []string
+	for sym := range s.symbols {
+		if s.shardFor(sym) != shard {
+			continue
+		}
+		s.stale[sym] = struct{}{}
+		affected = append(affected, sym)
+	}
+	handlers := append([]func([]string){}, s.staleFns...)
+	s.mu.Unlock()
+	if len(affected) == 0 {
+		return
+	}
+	sort.Strings(affected)
+	for _, h := range handlers {
+		h := h
+		go h(affected)
+	}
+}
+
+// OnLimitStateChange 注册合约涨跌停状态回调: LastPrice 触及
+// UpperLimit/LowerLimit 时以 UP/DOWN 触发, 离板回落后以 NORMAL 触发.
+// 离板判定带两跳迟滞, 贴板抖动不会反复触发. 初始状态视为 NORMAL
+func (s *QuoteSubscription) OnLimitStateChange(symbol string, handler func(state LimitState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.limitFns == nil {
+		s.limitFns = make(map[string][]func(LimitState))
+		s.limitStates = make(map[string]LimitState)
+	}
+	if _, ok := s.limitStates[symbol]; !ok {
+		s.limitStates[symbol] = LimitStateNormal
+	}
+	s.limitFns[symbol] = append(s.limitFns[symbol], handler)
+}
+
+// deliver 把一个合约的最新 Quote 分发给回调与通道
+func (s *QuoteSubscription) deliver(sym string, q *Quote) {
+	// 派生指标在分发前重算, 回调与通道拿到的 Quote 已带最新值;
+	// 用户函数在锁外执行
+	s.mu.Lock()
+	fns := make(map[string]func(*Quote) float64, len(s.derivedFns))
+	for name, fn := range s.derivedFns {
+		fns[name] = fn
+	}
+	s.mu.Unlock()
+	if len(fns) > 0 {
+		vals := make(map[string]float64, len(fns))
+		for name, fn := range fns {
+			vals[name] = fn(q)
+		}
+		q.Derived = vals
+		s.mu.Lock()
+		s.derivedVals[sym] = vals
+		s.mu.Unlock()
+	}
+	s.mu.Lock()
+	s.confirmed[sym] = struct{}{}
+	// 重连后的新数据解除过期标记
+	delete(s.stale, sym)
+	q.Stale = false
+	_, seen := s.firstSeen[sym]
+	isSnapshot := !seen && q.IsValid()
+	var firstFns []func(*Quote)
+	if isSnapshot {
+		s.firstSeen[sym] = struct{}{}
+		firstFns = append([]func(*Quote){}, s.firstQuoteFns...)
+	}
+	handlers := append([]func(*Quote){}, s.handlers...)
+	var typed []func(*Quote, bool)
+	if seen || isSnapshot {
+		typed = append([]func(*Quote, bool){}, s.typedHandlers...)
+	}
+	if q.Datetime != "" && q.Datetime > s.lastDatetime {
+		s.lastDatetime = q.Datetime
+	}
+	chans := append([]chan *Quote(nil), s.channels[sym]...)
+	book := s.orderBooks[sym]
+	var limitCbs []func(LimitState)
+	var limitState LimitState
+	if fns := s.limitFns[sym]; len(fns) > 0 {
+		cur := s.limitStates[sym]
+		limitState = limitStateTransition(cur, q)
+		if limitState != cur {
+			s.limitStates[sym] = limitState
+			limitCbs = append(limitCbs, fns...)
+		}
+	}
+	s.mu.Unlock()
+	if book != nil {
+		book.UpdateFromQuote(q)
+	}
+	for _, h := range limitCbs {
+		h := h
+		go h(limitState)
+	}
+	for _, h := range firstFns {
+		go h(q)
+	}
+	for _, h := range handlers {
+		go h(q)
+	}
+	for _, h := range typed {
+		h := h
+		go h(q, isSnapshot)
+	}
+	for _, ch := range chans {
+		select {
+		case ch <- q:
+		default:
+		}
+	}
+}
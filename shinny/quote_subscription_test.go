@@ -0,0 +1,732 @@
+package shinny
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetQuoteFillsChange(t *testing.T) {
+	client := newTestClient(t, "futr")
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{
+				"last_price":     68100.0,
+				"pre_settlement": 68000.0,
+			},
+		},
+	})
+	q, err := client.GetQuote("SHFE.cu2601")
+	if err != nil {
+		t.Fatalf("GetQuote error: %v", err)
+	}
+	if q.Change != 100 {
+		t.Fatalf("Change = %v, want 100", q.Change)
+	}
+	want := 100.0 / 68000 * 100
+	if math.Abs(q.ChangePercent-want) > 1e-9 {
+		t.Fatalf("ChangePercent = %v, want %v", q.ChangePercent, want)
+	}
+}
+
+func TestGetQuoteChangeSkippedWithoutPreSettlement(t *testing.T) {
+	client := newTestClient(t, "futr")
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"DCE.m2601": map[string]interface{}{"last_price": 3000.0},
+		},
+	})
+	q, err := client.GetQuote("DCE.m2601")
+	if err != nil {
+		t.Fatalf("GetQuote error: %v", err)
+	}
+	if q.Change != 0 || q.ChangePercent != 0 {
+		t.Fatalf("change should stay zero, got %v / %v", q.Change, q.ChangePercent)
+	}
+}
+
+func TestGetQuoteKeepsServerChange(t *testing.T) {
+	client := newTestClient(t, "futr")
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.au2612": map[string]interface{}{
+				"last_price":     500.0,
+				"pre_settlement": 490.0,
+				"change":         10.0,
+				"change_percent": 2.0408,
+			},
+		},
+	})
+	q, _ := client.GetQuote("SHFE.au2612")
+	if q.ChangePercent != 2.0408 {
+		t.Fatalf("server change_percent overwritten: %v", q.ChangePercent)
+	}
+}
+
+func TestWithFieldsPrunesQuote(t *testing.T) {
+	client := newTestClient(t, "futr")
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{
+				"last_price":     68100.0,
+				"pre_settlement": 68000.0,
+				"bid_price1":     68090.0,
+				"ask_price1":     68110.0,
+				"volume":         12345.0,
+				"open_interest":  54321.0,
+			},
+		},
+	})
+	client.quoteSub.WithFields([]string{"last_price", "bid_price1", "ask_price1"})
+
+	q, err := client.GetQuote("SHFE.cu2601")
+	if err != nil {
+		t.Fatalf("GetQuote error: %v", err)
+	}
+	if q.LastPrice != 68100 || q.BidPrice1 != 68090 || q.AskPrice1 != 68110 {
+		t.Fatalf("selected fields missing: %+v", q)
+	}
+	if q.PreSettlement != 0 || q.Volume != 0 || q.OpenInterest != 0 {
+		t.Fatalf("pruned fields should stay zero: %+v", q)
+	}
+
+	// 传空恢复全字段
+	client.quoteSub.WithFields(nil)
+	q, _ = client.GetQuote("SHFE.cu2601")
+	if q.Volume != 12345 || q.PreSettlement != 68000 {
+		t.Fatalf("full fields not restored: %+v", q)
+	}
+}
+
+func TestIsSubscribedConfirmedOnFirstQuote(t *testing.T) {
+	client := newTestClient(t, "futr")
+	if _, err := client.SubscribeQuote(nil, "SHFE.cu2601", "DCE.m2601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+	// 订阅后尚未收到 quote, 均未确认
+	if client.IsSubscribed("SHFE.cu2601") {
+		t.Fatal("subscription should not be confirmed before first quote")
+	}
+
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"last_price": 68000.0},
+		},
+	})
+	deadline := time.Now().Add(time.Second)
+	for !client.IsSubscribed("SHFE.cu2601") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !client.IsSubscribed("SHFE.cu2601") {
+		t.Fatal("subscription not confirmed after first quote")
+	}
+	if client.IsSubscribed("DCE.m2601") {
+		t.Fatal("symbol without quote should stay unconfirmed")
+	}
+	if got := client.SubscribedSymbols(); len(got) != 1 || got[0] != "SHFE.cu2601" {
+		t.Fatalf("SubscribedSymbols = %v", got)
+	}
+
+	// 退订后确认状态清除
+	if err := client.UnsubscribeQuote(nil, "SHFE.cu2601"); err != nil {
+		t.Fatalf("UnsubscribeQuote error: %v", err)
+	}
+	if client.IsSubscribed("SHFE.cu2601") {
+		t.Fatal("unsubscribed symbol should not stay confirmed")
+	}
+}
+
+func TestShardedSubscriptionSplitAndUnify(t *testing.T) {
+	auth := NewTqAuth("tester", "secret")
+	auth.setGrants(map[string]interface{}{"features": []interface{}{"futr"}})
+	client := NewClient(auth, &ClientConfig{MdShards: 3})
+
+	symbols := []string{
+		"SHFE.cu2601", "SHFE.au2612", "DCE.m2601", "DCE.i2601",
+		"CZCE.MA601", "CFFEX.IF2601", "INE.sc2601", "GFEX.si2601",
+	}
+	if _, err := client.SubscribeQuote(nil, symbols...); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+
+	// 每个合约恰好落在一条连接的订阅报文里
+	seen := make(map[string]int)
+	for _, ws := range client.quoteShards {
+		ws.mu.Lock()
+		pkt := ws.subscribeQuote
+		ws.mu.Unlock()
+		if pkt == nil {
+			continue
+		}
+		insList, _ := pkt["ins_list"].(string)
+		if insList == "" {
+			continue
+		}
+		for _, sym := range strings.Split(insList, ",") {
+			seen[sym]++
+		}
+	}
+	for _, sym := range symbols {
+		if seen[sym] != 1 {
+			t.Fatalf("symbol %s subscribed on %d shards, want 1", sym, seen[sym])
+		}
+	}
+
+	// 不同连接收到的数据合并进同一截面
+	client.quoteShards[0].handleMessage(map[string]interface{}{
+		"aid": "rtn_data",
+		"data": []interface{}{map[string]interface{}{
+			"quotes": map[string]interface{}{
+				"SHFE.cu2601": map[string]interface{}{"last_price": 68000.0},
+			},
+		}},
+	})
+	client.quoteShards[len(client.quoteShards)-1].handleMessage(map[string]interface{}{
+		"aid": "rtn_data",
+		"data": []interface{}{map[string]interface{}{
+			"quotes": map[string]interface{}{
+				"DCE.m2601": map[string]interface{}{"last_price": 3000.0},
+			},
+		}},
+	})
+	if q, err := client.GetQuote("SHFE.cu2601"); err != nil || q.LastPrice != 68000 {
+		t.Fatalf("quote from shard 0 missing: %v %v", q, err)
+	}
+	if q, err := client.GetQuote("DCE.m2601"); err != nil || q.LastPrice != 3000 {
+		t.Fatalf("quote from last shard missing: %v %v", q, err)
+	}
+}
+
+func TestHighPriorityChannelNoLoss(t *testing.T) {
+	auth := NewTqAuth("tester", "secret")
+	auth.setGrants(map[string]interface{}{"features": []interface{}{"futr"}})
+	client := NewClient(auth, nil)
+
+	if _, err := client.SubscribeQuoteHighPriority(nil, "SHFE.cu2601"); err != nil {
+		t.Fatalf("SubscribeQuoteHighPriority error: %v", err)
+	}
+	if _, err := client.SubscribeQuote(nil, "DCE.m2601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+	highCh := client.SymbolChannel("SHFE.cu2601")
+	lowCh := client.SymbolChannel("DCE.m2601")
+	// 普通合约降频, 高优先级不受影响
+	client.SetLowPriorityInterval(time.Hour)
+
+	const updates = 100
+	for i := 0; i < updates; i++ {
+		client.dm.MergeData(map[string]interface{}{
+			"quotes": map[string]interface{}{
+				"SHFE.cu2601": map[string]interface{}{"last_price": float64(i)},
+				"DCE.m2601":   map[string]interface{}{"last_price": float64(i)},
+			},
+		})
+		select {
+		case q := <-highCh:
+			if q.LastPrice != float64(i) {
+				t.Fatalf("high priority update %d = %v", i, q.LastPrice)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("high priority update %d lost", i)
+		}
+	}
+
+	// 普通合约被降频, 更新数远少于高优先级
+	time.Sleep(50 * time.Millisecond)
+	var low int
+drain:
+	for {
+		select {
+		case <-lowCh:
+			low++
+		default:
+			break drain
+		}
+	}
+	if low >= updates {
+		t.Fatalf("low priority channel should be throttled, got %d updates", low)
+	}
+}
+
+func TestSubscribeMainContinuousAndIndexSymbols(t *testing.T) {
+	client := newTestClient(t, "futr")
+
+	unauthorized, err := client.SubscribeQuote(nil, "KQ.m@SHFE.rb", "KQ.i@DCE.m")
+	if err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+	if len(unauthorized) != 0 {
+		t.Fatalf("unauthorized = %v, want none", unauthorized)
+	}
+
+	ch := client.SymbolChannel("KQ.m@SHFE.rb")
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"KQ.m@SHFE.rb": map[string]interface{}{
+				"last_price": 3500.0,
+				"datetime":   "2026-08-27 10:00:00.000000",
+			},
+		},
+	})
+
+	q, err := client.GetQuote("KQ.m@SHFE.rb")
+	if err != nil {
+		t.Fatalf("GetQuote error: %v", err)
+	}
+	if q.LastPrice != 3500 {
+		t.Fatalf("LastPrice = %v, want 3500", q.LastPrice)
+	}
+	// 组合代码按 '@' 后的实际合约回填交易所/品种
+	if q.ExchangeID != "SHFE" || q.InstrumentID != "rb" {
+		t.Fatalf("backfilled exchange/instrument = %s/%s", q.ExchangeID, q.InstrumentID)
+	}
+
+	select {
+	case got := <-ch:
+		if got.LastPrice != 3500 {
+			t.Fatalf("channel quote = %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no quote pushed for main continuous symbol")
+	}
+	deadline := time.Now().Add(time.Second)
+	for !client.IsSubscribed("KQ.m@SHFE.rb") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !client.IsSubscribed("KQ.m@SHFE.rb") {
+		t.Fatal("main continuous symbol not confirmed")
+	}
+}
+
+func TestSubscribeQuoteRefCounting(t *testing.T) {
+	client := newTestClient(t, "futr")
+
+	// 两个模块各自订阅同一合约, 共享底层订阅
+	if _, err := client.SubscribeQuote(nil, "SHFE.cu2601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+	if _, err := client.SubscribeQuote(nil, "SHFE.cu2601", "DCE.m2601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+
+	insList := func() string {
+		ws := client.quoteShards[0]
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		if ws.subscribeQuote == nil {
+			return ""
+		}
+		list, _ := ws.subscribeQuote["ins_list"].(string)
+		return list
+	}
+
+	// 第一个模块退订, 另一个仍持有引用, 不真正退订
+	if err := client.UnsubscribeQuote(nil, "SHFE.cu2601"); err != nil {
+		t.Fatalf("UnsubscribeQuote error: %v", err)
+	}
+	if !strings.Contains(insList(), "SHFE.cu2601") {
+		t.Fatalf("symbol unsubscribed while still referenced: %q", insList())
+	}
+
+	// 最后一个引用取消后才真正退订
+	if err := client.UnsubscribeQuote(nil, "SHFE.cu2601"); err != nil {
+		t.Fatalf("UnsubscribeQuote error: %v", err)
+	}
+	if strings.Contains(insList(), "SHFE.cu2601") {
+		t.Fatalf("symbol should be unsubscribed: %q", insList())
+	}
+	if got := client.quoteSub.Symbols(); len(got) != 1 || got[0] != "DCE.m2601" {
+		t.Fatalf("Symbols = %v, want [DCE.m2601]", got)
+	}
+
+	// 多退一次不影响其他合约
+	if err := client.UnsubscribeQuote(nil, "SHFE.cu2601"); err != nil {
+		t.Fatalf("UnsubscribeQuote error: %v", err)
+	}
+	if !strings.Contains(insList(), "DCE.m2601") {
+		t.Fatalf("unrelated symbol dropped: %q", insList())
+	}
+}
+
+func TestQuoteIsValidAndFirstQuote(t *testing.T) {
+	client := newTestClient(t, "futr")
+	if _, err := client.SubscribeQuote(nil, "SHFE.cu2601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+
+	fired := make(chan *Quote, 4)
+	client.OnFirstQuote(func(q *Quote) { fired <- q })
+
+	// 只有合约信息没有成交数据的 quote 视为无效, 不触发回调
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"price_tick": 10.0},
+		},
+	})
+	q, err := client.GetQuote("SHFE.cu2601")
+	if err != nil {
+		t.Fatalf("GetQuote error: %v", err)
+	}
+	if q.IsValid() {
+		t.Fatalf("quote without last_price/datetime should be invalid: %+v", q)
+	}
+	select {
+	case q := <-fired:
+		t.Fatalf("OnFirstQuote fired for invalid quote: %+v", q)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 收到有效数据后触发一次
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{
+				"last_price": 68000.0,
+				"datetime":   "2026-08-27 10:00:00.000000",
+			},
+		},
+	})
+	select {
+	case q := <-fired:
+		if !q.IsValid() || q.LastPrice != 68000 {
+			t.Fatalf("first quote = %+v", q)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnFirstQuote not fired for valid quote")
+	}
+
+	// 后续更新不再触发
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{"last_price": 68010.0},
+		},
+	})
+	select {
+	case q := <-fired:
+		t.Fatalf("OnFirstQuote fired twice: %+v", q)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWithThrottleLimitsPushRate(t *testing.T) {
+	client := newTestClient(t, "futr")
+	if _, err := client.SubscribeQuote(nil, "SHFE.cu2601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+	ch := client.SymbolChannel("SHFE.cu2601")
+	client.quoteSub.WithThrottle(100 * time.Millisecond)
+
+	// 连续快速推送, 间隔内的更新被合并
+	const updates = 20
+	for i := 0; i < updates; i++ {
+		client.dm.MergeData(map[string]interface{}{
+			"quotes": map[string]interface{}{
+				"SHFE.cu2601": map[string]interface{}{"last_price": float64(i)},
+			},
+		})
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var got []*Quote
+	deadline := time.After(time.Second)
+drain:
+	for {
+		select {
+		case q := <-ch:
+			got = append(got, q)
+			// 最后一个值送达即可结束
+			if q.LastPrice == float64(updates-1) {
+				break drain
+			}
+		case <-deadline:
+			break drain
+		}
+	}
+	// 100ms 周期下 ~100ms 的突发最多推送两三次
+	if len(got) == 0 || len(got) > 4 {
+		t.Fatalf("throttled pushes = %d, want 1..4", len(got))
+	}
+	// 保证最后一个值一定被补推
+	if got[len(got)-1].LastPrice != float64(updates-1) {
+		t.Fatalf("last pushed value = %v, want %d", got[len(got)-1].LastPrice, updates-1)
+	}
+}
+
+func TestOnQuoteWithTypeSnapshotThenUpdates(t *testing.T) {
+	client := newTestClient(t, "futr")
+	if _, err := client.SubscribeQuote(context.Background(), "SHFE.cu2601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+
+	type typedEvent struct {
+		price    float64
+		snapshot bool
+	}
+	events := make(chan typedEvent, 16)
+	client.OnQuoteWithType(func(q *Quote, isSnapshot bool) {
+		events <- typedEvent{price: q.LastPrice, snapshot: isSnapshot}
+	})
+
+	await := func(want typedEvent) {
+		t.Helper()
+		select {
+		case got := <-events:
+			if got != want {
+				t.Fatalf("event = %+v, want %+v", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("typed callback not fired, want %+v", want)
+		}
+	}
+	merge := func(fields map[string]interface{}) {
+		client.dm.MergeData(map[string]interface{}{
+			"quotes": map[string]interface{}{"SHFE.cu2601": fields},
+		})
+	}
+
+	// 数据未收齐 (无 datetime) 时不触发
+	merge(map[string]interface{}{"ask_price1": 68010.0})
+	select {
+	case got := <-events:
+		t.Fatalf("premature typed callback: %+v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// 首次收齐完整数据: snapshot
+	merge(map[string]interface{}{"last_price": 68000.0, "datetime": "2026-08-27 10:00:00.000000"})
+	await(typedEvent{price: 68000, snapshot: true})
+
+	// 之后均为增量更新
+	merge(map[string]interface{}{"last_price": 68001.0})
+	await(typedEvent{price: 68001, snapshot: false})
+	merge(map[string]interface{}{"last_price": 68002.0})
+	await(typedEvent{price: 68002, snapshot: false})
+}
+
+func TestQuoteToTick(t *testing.T) {
+	q := &Quote{
+		InstrumentID: "cu2601", ExchangeID: "SHFE",
+		Datetime:  "2026-08-27 10:30:00.500000",
+		LastPrice: 68000, Average: 67950, Highest: 68100, Lowest: 67800,
+		BidPrice1: 67990, BidVolume1: 8, AskPrice1: 68010, AskVolume1: 4,
+		BidPrice5: 67950, BidVolume5: 40, AskPrice5: 68050, AskVolume5: 20,
+		Volume: 12345, Amount: 6.789e8, OpenInterest: 54321,
+	}
+	tick := q.ToTick()
+
+	if tick.LastPrice != q.LastPrice || tick.Highest != q.Highest || tick.Lowest != q.Lowest {
+		t.Fatalf("price fields mismatch: %+v", tick)
+	}
+	if tick.BidPrice1 != 67990 || tick.BidVolume1 != 8 || tick.AskPrice1 != 68010 || tick.AskVolume1 != 4 {
+		t.Fatalf("level1 mismatch: %+v", tick)
+	}
+	if tick.BidPrice5 != 67950 || tick.BidVolume5 != 40 || tick.AskPrice5 != 68050 || tick.AskVolume5 != 20 {
+		t.Fatalf("level5 mismatch: %+v", tick)
+	}
+	if tick.Volume != 12345 || tick.Amount != 6.789e8 || tick.OpenInterest != 54321 {
+		t.Fatalf("cumulative fields mismatch: %+v", tick)
+	}
+	want, err := ParseDatetime(q.Datetime)
+	if err != nil {
+		t.Fatalf("ParseDatetime: %v", err)
+	}
+	if tick.Datetime != want.UnixNano() {
+		t.Fatalf("datetime = %d, want %d", tick.Datetime, want.UnixNano())
+	}
+
+	// 转换结果可直接喂给订单薄等 Tick 处理逻辑
+	b := NewOrderBook(q.Symbol())
+	b.UpdateFromTick(tick)
+	if best := b.BestBid(); best.Price != 67990 {
+		t.Fatalf("order book best bid = %+v", best)
+	}
+
+	// 时间戳无法解析时 Datetime 为 0
+	if tick := (&Quote{Datetime: "bad"}).ToTick(); tick.Datetime != 0 {
+		t.Fatalf("unparsable datetime = %d, want 0", tick.Datetime)
+	}
+}
+
+func TestOnLimitStateChange(t *testing.T) {
+	client := newTestClient(t, "futr")
+	if _, err := client.SubscribeQuote(context.Background(), "SHFE.cu2601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+
+	states := make(chan LimitState, 8)
+	client.quoteSub.OnLimitStateChange("SHFE.cu2601", func(state LimitState) {
+		states <- state
+	})
+
+	push := func(price float64) {
+		client.dm.MergeData(map[string]interface{}{
+			"quotes": map[string]interface{}{
+				"SHFE.cu2601": map[string]interface{}{
+					"datetime":    "2026-08-27 10:00:00.000000",
+					"last_price":  price,
+					"upper_limit": 70000.0,
+					"lower_limit": 60000.0,
+					"price_tick":  10.0,
+				},
+			},
+		})
+	}
+	next := func() LimitState {
+		select {
+		case s := <-states:
+			return s
+		case <-time.After(time.Second):
+			t.Fatal("limit state change not received")
+			return ""
+		}
+	}
+	quiet := func() {
+		select {
+		case s := <-states:
+			t.Fatalf("unexpected state change %q", s)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	push(69990) // 未触板, 无回调
+	quiet()
+	push(70000) // 触及涨停
+	if s := next(); s != LimitStateUp {
+		t.Fatalf("state = %q, want UP", s)
+	}
+	push(69990) // 贴板一跳抖动, 去抖不触发
+	quiet()
+	push(69970) // 离板两跳以上, 回到 NORMAL
+	if s := next(); s != LimitStateNormal {
+		t.Fatalf("state = %q, want NORMAL", s)
+	}
+	push(60000) // 触及跌停
+	if s := next(); s != LimitStateDown {
+		t.Fatalf("state = %q, want DOWN", s)
+	}
+}
+
+func TestStaleQuoteOnDisconnect(t *testing.T) {
+	client := newTestClient(t, "futr")
+	if _, err := client.SubscribeQuote(context.Background(), "SHFE.cu2601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+
+	staleCh := make(chan []string, 1)
+	client.OnStale(func(symbols []string) { staleCh <- symbols })
+	quoteCh := make(chan *Quote, 4)
+	client.OnQuote(func(q *Quote) { quoteCh <- q })
+
+	push := func(price float64) {
+		client.dm.MergeData(map[string]interface{}{
+			"quotes": map[string]interface{}{
+				"SHFE.cu2601": map[string]interface{}{
+					"datetime":   "2026-08-27 10:00:00.000000",
+					"last_price": price,
+				},
+			},
+		})
+	}
+	push(68000)
+	// 等首次分发完成再断线, 避免积压的 deliver 事后清掉过期标记
+	select {
+	case <-quoteCh:
+	case <-time.After(time.Second):
+		t.Fatal("initial quote not delivered")
+	}
+	q, err := client.GetQuote("SHFE.cu2601")
+	if err != nil {
+		t.Fatalf("GetQuote: %v", err)
+	}
+	if q.IsStale() {
+		t.Fatal("quote stale before disconnect")
+	}
+
+	// 模拟连接断开: 触发 Client 注册的断线钩子
+	client.quoteShards[0].onClose()
+
+	select {
+	case symbols := <-staleCh:
+		if len(symbols) != 1 || symbols[0] != "SHFE.cu2601" {
+			t.Fatalf("stale symbols = %v, want [SHFE.cu2601]", symbols)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnStale not triggered")
+	}
+	q, _ = client.GetQuote("SHFE.cu2601")
+	if !q.IsStale() {
+		t.Fatal("quote not marked stale after disconnect")
+	}
+
+	// 重连后的新数据清除过期标记 (deliver 异步, 轮询等待)
+	push(68010)
+	deadline := time.Now().Add(time.Second)
+	for {
+		q, _ = client.GetQuote("SHFE.cu2601")
+		if !q.IsStale() || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if q.IsStale() {
+		t.Fatal("stale flag not cleared after fresh data")
+	}
+}
+
+func TestAddDerivedRecalculatesOnUpdate(t *testing.T) {
+	client := newTestClient(t, "futr")
+	if _, err := client.SubscribeQuote(context.Background(), "SHFE.cu2601"); err != nil {
+		t.Fatalf("SubscribeQuote error: %v", err)
+	}
+
+	// 买卖压力比: 买一量 / (买一量+卖一量)
+	client.quoteSub.AddDerived("pressure", func(q *Quote) float64 {
+		total := q.BidVolume1 + q.AskVolume1
+		if total == 0 {
+			return 0
+		}
+		return float64(q.BidVolume1) / float64(total)
+	})
+
+	push := func(bid, ask float64) {
+		client.dm.MergeData(map[string]interface{}{
+			"quotes": map[string]interface{}{
+				"SHFE.cu2601": map[string]interface{}{
+					"datetime":    "2026-08-27 10:00:00.000000",
+					"last_price":  68000.0,
+					"bid_volume1": bid,
+					"ask_volume1": ask,
+				},
+			},
+		})
+	}
+	waitDerived := func(want float64) {
+		deadline := time.Now().Add(time.Second)
+		for {
+			if v, ok := client.quoteSub.Derived("SHFE.cu2601", "pressure"); ok && v == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				v, ok := client.quoteSub.Derived("SHFE.cu2601", "pressure")
+				t.Fatalf("derived = %v (ok=%v), want %v", v, ok, want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	push(30, 10)
+	waitDerived(0.75)
+	// 行情更新后自动重算
+	push(10, 30)
+	waitDerived(0.25)
+
+	// 快照读取也携带缓存的派生值
+	q, err := client.GetQuote("SHFE.cu2601")
+	if err != nil {
+		t.Fatalf("GetQuote: %v", err)
+	}
+	if q.Derived["pressure"] != 0.25 {
+		t.Fatalf("quote derived = %v, want 0.25", q.Derived)
+	}
+}
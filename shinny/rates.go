@@ -0,0 +1,73 @@
+package shinny
+
+import "fmt"
+
+// MarginRate 合约保证金率明细: 比例字段按合约金额计, per_volume
+// 字段按手固定金额计, 服务端未下发的字段为 0; Margin 为行情节点
+// 的每手保证金额, 可作为比例字段缺失时的后备
+type MarginRate struct {
+	Symbol string `json:"-"`
+
+	LongRatioByMoney  float64 `json:"long_margin_ratio_by_money"`
+	LongPerVolume     float64 `json:"long_margin_ratio_by_volume"`
+	ShortRatioByMoney float64 `json:"short_margin_ratio_by_money"`
+	ShortPerVolume    float64 `json:"short_margin_ratio_by_volume"`
+
+	Margin float64 `json:"margin"`
+}
+
+// CommissionRate 合约手续费率明细, 区分开仓/平仓/平今,
+// 比例字段按成交金额计, per_volume 字段按手固定金额计;
+// Commission 为行情节点的每手手续费额
+type CommissionRate struct {
+	Symbol string `json:"-"`
+
+	OpenRatioByMoney       float64 `json:"open_ratio_by_money"`
+	OpenPerVolume          float64 `json:"open_ratio_by_volume"`
+	CloseRatioByMoney      float64 `json:"close_ratio_by_money"`
+	ClosePerVolume         float64 `json:"close_ratio_by_volume"`
+	CloseTodayRatioByMoney float64 `json:"close_today_ratio_by_money"`
+	CloseTodayPerVolume    float64 `json:"close_today_ratio_by_volume"`
+
+	Commission float64 `json:"commission"`
+}
+
+// quoteNode 读取合约行情节点的深拷贝, 未订阅或无数据时报错;
+// 返回值后续交给 ConvertToStruct 序列化, 必须与合并隔离
+func (c *Client) quoteNode(symbol string) (interface{}, error) {
+	node, ok := c.dm.GetCopyByPath("quotes", symbol)
+	if !ok {
+		return nil, fmt.Errorf("shinny: no quote data for %s", symbol)
+	}
+	return node, nil
+}
+
+// GetMarginRate 返回合约的保证金率明细, 字段来自服务端行情节点,
+// 需先订阅该合约行情
+func (c *Client) GetMarginRate(symbol string) (*MarginRate, error) {
+	node, err := c.quoteNode(symbol)
+	if err != nil {
+		return nil, err
+	}
+	rate := &MarginRate{Symbol: symbol}
+	if err := ConvertToStruct(node, rate); err != nil {
+		return nil, err
+	}
+	rate.Symbol = symbol
+	return rate, nil
+}
+
+// GetCommissionRate 返回合约的手续费率明细 (区分开仓/平仓/平今),
+// 字段来自服务端行情节点, 需先订阅该合约行情
+func (c *Client) GetCommissionRate(symbol string) (*CommissionRate, error) {
+	node, err := c.quoteNode(symbol)
+	if err != nil {
+		return nil, err
+	}
+	rate := &CommissionRate{Symbol: symbol}
+	if err := ConvertToStruct(node, rate); err != nil {
+		return nil, err
+	}
+	rate.Symbol = symbol
+	return rate, nil
+}
@@ -0,0 +1,56 @@
+package shinny
+
+import "testing"
+
+func TestGetMarginAndCommissionRate(t *testing.T) {
+	client := newTestClient(t)
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{
+				"margin":                      22000.0,
+				"commission":                  15.0,
+				"long_margin_ratio_by_money":  0.1,
+				"short_margin_ratio_by_money": 0.12,
+				"open_ratio_by_money":         0.0001,
+				"close_ratio_by_money":        0.0001,
+				"close_today_ratio_by_money":  0.0005,
+				"close_today_ratio_by_volume": 2.0,
+			},
+		},
+	})
+
+	margin, err := client.GetMarginRate("SHFE.cu2601")
+	if err != nil {
+		t.Fatalf("GetMarginRate error: %v", err)
+	}
+	if margin.Symbol != "SHFE.cu2601" || margin.Margin != 22000 {
+		t.Fatalf("margin = %+v", margin)
+	}
+	if margin.LongRatioByMoney != 0.1 || margin.ShortRatioByMoney != 0.12 {
+		t.Fatalf("margin ratios = %v/%v, want 0.1/0.12",
+			margin.LongRatioByMoney, margin.ShortRatioByMoney)
+	}
+	// 服务端未下发的字段保持 0
+	if margin.LongPerVolume != 0 {
+		t.Fatalf("long per-volume = %v, want 0", margin.LongPerVolume)
+	}
+
+	comm, err := client.GetCommissionRate("SHFE.cu2601")
+	if err != nil {
+		t.Fatalf("GetCommissionRate error: %v", err)
+	}
+	if comm.OpenRatioByMoney != 0.0001 || comm.CloseRatioByMoney != 0.0001 {
+		t.Fatalf("open/close ratios = %v/%v", comm.OpenRatioByMoney, comm.CloseRatioByMoney)
+	}
+	// 平今与平仓费率区分开
+	if comm.CloseTodayRatioByMoney != 0.0005 || comm.CloseTodayPerVolume != 2 {
+		t.Fatalf("close today = %v/%v", comm.CloseTodayRatioByMoney, comm.CloseTodayPerVolume)
+	}
+	if comm.Commission != 15 {
+		t.Fatalf("commission = %v, want 15", comm.Commission)
+	}
+
+	if _, err := client.GetMarginRate("SHFE.unknown"); err == nil {
+		t.Fatal("expected error for unsubscribed symbol")
+	}
+}
@@ -0,0 +1,142 @@
+package shinny
+
+import (
+	"context"
+	"fmt"
+)
+
+// needsCloseToday 判断交易所是否区分平今/平昨 (上期所与能源中心
+// 平今仓必须使用 CLOSETODAY)
+func needsCloseToday(exchangeID string) bool {
+	return exchangeID == "SHFE" || exchangeID == "INE"
+}
+
+// rolloverRequests 根据旧合约持仓构造移仓请求序列: 先平旧合约
+// (区分平今/平昨), 再在新合约开等量同方向仓位. priceType 为 LIMIT 时
+// 通过 limitPrice 取各订单的限价
+func rolloverRequests(oldSymbol, newSymbol, priceType string, pos *Position, limitPrice func(symbol, direction string) (float64, error)) ([]*InsertOrderRequest, error) {
+	exchangeID, _ := splitSymbol(oldSymbol)
+	build := func(symbol, direction, offset string, volume int64) (*InsertOrderRequest, error) {
+		req := &InsertOrderRequest{
+			Symbol:    symbol,
+			Direction: direction,
+			Offset:    offset,
+			Volume:    volume,
+			PriceType: priceType,
+		}
+		if priceType == PriceTypeLimit {
+			price, err := limitPrice(symbol, direction)
+			if err != nil {
+				return nil, err
+			}
+			req.LimitPrice = price
+		}
+		return req, nil
+	}
+	appendSide := func(reqs []*InsertOrderRequest, closeDirection, openDirection string, total, today int64) ([]*InsertOrderRequest, error) {
+		his := total - today
+		if needsCloseToday(exchangeID) && today > 0 {
+			req, err := build(oldSymbol, closeDirection, OffsetCloseToday, today)
+			if err != nil {
+				return nil, err
+			}
+			reqs = append(reqs, req)
+			if his > 0 {
+				req, err = build(oldSymbol, closeDirection, OffsetClose, his)
+				if err != nil {
+					return nil, err
+				}
+				reqs = append(reqs, req)
+			}
+		} else {
+			req, err := build(oldSymbol, closeDirection, OffsetClose, total)
+			if err != nil {
+				return nil, err
+			}
+			reqs = append(reqs, req)
+		}
+		req, err := build(newSymbol, openDirection, OffsetOpen, total)
+		if err != nil {
+			return nil, err
+		}
+		return append(reqs, req), nil
+	}
+
+	var reqs []*InsertOrderRequest
+	var err error
+	if pos.VolumeLong > 0 {
+		reqs, err = appendSide(reqs, DirectionSell, DirectionBuy, pos.VolumeLong, pos.VolumeLongToday)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if pos.VolumeShort > 0 {
+		reqs, err = appendSide(reqs, DirectionBuy, DirectionSell, pos.VolumeShort, pos.VolumeShortToday)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("shinny: no position to rollover for %s", oldSymbol)
+	}
+	return reqs, nil
+}
+
+// rolloverVia 读取旧合约持仓并逐笔提交移仓订单, 返回已提交的订单.
+// 中途下单失败时返回已提交部分与错误, 由调用方决定如何补救
+func rolloverVia(ctx context.Context, t Trader, oldSymbol, newSymbol, priceType string, limitPrice func(symbol, direction string) (float64, error)) ([]*Order, error) {
+	positions, err := t.GetPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pos, ok := positions[oldSymbol]
+	if !ok {
+		return nil, fmt.Errorf("shinny: no position to rollover for %s", oldSymbol)
+	}
+	reqs, err := rolloverRequests(oldSymbol, newSymbol, priceType, pos, limitPrice)
+	if err != nil {
+		return nil, err
+	}
+	orders := make([]*Order, 0, len(reqs))
+	for _, req := range reqs {
+		o, err := t.InsertOrder(ctx, req)
+		if err != nil {
+			return orders, fmt.Errorf("shinny: rollover %s -> %s interrupted: %w", oldSymbol, newSymbol, err)
+		}
+		orders = append(orders, o)
+	}
+	return orders, nil
+}
+
+// Rollover 移仓换月: 平掉 oldSymbol 的持仓并在 newSymbol 开等量
+// 同方向仓位, 返回生成的订单. 上期所/能源中心的今仓用平今单处理,
+// priceType 为 LIMIT 时按行情对手价挂限价单
+func (s *TradeSession) Rollover(ctx context.Context, oldSymbol, newSymbol, priceType string) ([]*Order, error) {
+	return rolloverVia(ctx, s, oldSymbol, newSymbol, priceType, func(symbol, direction string) (float64, error) {
+		if s.quoteSnapshot == nil {
+			return 0, fmt.Errorf("shinny: no quote available for rollover limit price of %s", symbol)
+		}
+		q, err := s.quoteSnapshot(symbol)
+		if err != nil {
+			return 0, fmt.Errorf("shinny: no quote available for rollover limit price of %s: %w", symbol, err)
+		}
+		if direction == DirectionBuy {
+			return q.AskPrice1, nil
+		}
+		return q.BidPrice1, nil
+	})
+}
+
+// Rollover 移仓换月: 平掉 oldSymbol 的持仓并在 newSymbol 开等量
+// 同方向仓位, 返回生成的订单. LIMIT 模式使用最新价作为限价
+func (v *VirtualTrader) Rollover(ctx context.Context, oldSymbol, newSymbol, priceType string) ([]*Order, error) {
+	return rolloverVia(ctx, v, oldSymbol, newSymbol, priceType, func(symbol, direction string) (float64, error) {
+		v.mu.Lock()
+		price, ok := v.marketPrices[symbol]
+		v.mu.Unlock()
+		if !ok {
+			return 0, fmt.Errorf("shinny: no market price for rollover limit price of %s", symbol)
+		}
+		return price, nil
+	})
+}
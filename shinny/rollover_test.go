@@ -0,0 +1,97 @@
+package shinny
+
+import (
+	"testing"
+)
+
+func TestVirtualTraderRollover(t *testing.T) {
+	vt := NewVirtualTrader(10000000)
+	vt.UpdateMarketPrice("SHFE.cu2601", 68000)
+
+	// 昨仓 2 手 + 今仓 1 手
+	if _, err := vt.InsertOrder(nil, &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 2, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	}); err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	vt.Settle()
+	if _, err := vt.InsertOrder(nil, &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	}); err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+
+	vt.UpdateMarketPrice("SHFE.cu2602", 68500)
+	orders, err := vt.Rollover(nil, "SHFE.cu2601", "SHFE.cu2602", PriceTypeAny)
+	if err != nil {
+		t.Fatalf("Rollover error: %v", err)
+	}
+	// 上期所分平今/平昨, 加上新合约开仓共 3 笔
+	if len(orders) != 3 {
+		t.Fatalf("orders = %d, want 3", len(orders))
+	}
+	if orders[0].Offset != OffsetCloseToday || orders[0].VolumeOrign != 1 {
+		t.Fatalf("order[0] = %s %d, want CLOSETODAY 1", orders[0].Offset, orders[0].VolumeOrign)
+	}
+	if orders[1].Offset != OffsetClose || orders[1].VolumeOrign != 2 {
+		t.Fatalf("order[1] = %s %d, want CLOSE 2", orders[1].Offset, orders[1].VolumeOrign)
+	}
+	if orders[2].Offset != OffsetOpen || orders[2].Symbol() != "SHFE.cu2602" ||
+		orders[2].Direction != DirectionBuy || orders[2].VolumeOrign != 3 {
+		t.Fatalf("order[2] = %+v, want BUY OPEN SHFE.cu2602 3", orders[2])
+	}
+
+	positions, _ := vt.GetPositions(nil)
+	if old := positions["SHFE.cu2601"]; old != nil && old.VolumeLong != 0 {
+		t.Fatalf("old position not cleared: %+v", old)
+	}
+	neu := positions["SHFE.cu2602"]
+	if neu == nil || neu.VolumeLong != 3 || neu.VolumeLongToday != 3 {
+		t.Fatalf("new position = %+v, want 3 long today", neu)
+	}
+}
+
+func TestTradeSessionRolloverSplitsTodayHis(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"positions": map[string]interface{}{
+					"SHFE.cu2601": map[string]interface{}{
+						"exchange_id":       "SHFE",
+						"instrument_id":     "cu2601",
+						"volume_long":       5.0,
+						"volume_long_today": 2.0,
+						"volume_long_his":   3.0,
+					},
+				},
+			},
+		},
+	})
+
+	orders, err := session.Rollover(nil, "SHFE.cu2601", "SHFE.cu2602", PriceTypeAny)
+	if err != nil {
+		t.Fatalf("Rollover error: %v", err)
+	}
+	if len(orders) != 3 {
+		t.Fatalf("orders = %d, want 3", len(orders))
+	}
+	if orders[0].Offset != OffsetCloseToday || orders[0].VolumeOrign != 2 ||
+		orders[0].Direction != DirectionSell {
+		t.Fatalf("order[0] = %+v, want SELL CLOSETODAY 2", orders[0])
+	}
+	if orders[1].Offset != OffsetClose || orders[1].VolumeOrign != 3 {
+		t.Fatalf("order[1] = %+v, want SELL CLOSE 3", orders[1])
+	}
+	if orders[2].Symbol() != "SHFE.cu2602" || orders[2].Offset != OffsetOpen ||
+		orders[2].VolumeOrign != 5 {
+		t.Fatalf("order[2] = %+v, want OPEN SHFE.cu2602 5", orders[2])
+	}
+
+	// 无持仓的合约直接报错
+	if _, err := session.Rollover(nil, "DCE.m2601", "DCE.m2605", PriceTypeAny); err == nil {
+		t.Fatal("Rollover without position should fail")
+	}
+}
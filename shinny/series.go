@@ -0,0 +1,742 @@
+package shinny
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SeriesAPI 提供 K线 / Tick 序列订阅
+type SeriesAPI struct {
+	client *Client
+}
+
+// Series 返回序列数据接口
+func (c *Client) Series() *SeriesAPI {
+	return &SeriesAPI{client: c}
+}
+
+// KlineSeriesData 一个合约单周期的 K线序列快照
+type KlineSeriesData struct {
+	Symbol   string
+	Duration time.Duration
+	Klines   []*Kline
+	LastID   int64
+}
+
+// barOpen 判断一根 bar 是否仍在实时更新: 只有 id 等于 last_id 的末尾
+// bar 可能未完成, 其时间段 [datetime, datetime+duration) 未结束时视为未完成
+func (d *KlineSeriesData) barOpen(k *Kline, now time.Time) bool {
+	if k.ID != d.LastID || d.Duration <= 0 {
+		return false
+	}
+	return now.Before(TimeFromNano(k.Datetime).Add(d.Duration))
+}
+
+// ClosedBars 返回已完成的 bar, 排除末尾实时更新中的未完成 bar,
+// 用于指标计算时避免把半成品 bar 计入
+func (d *KlineSeriesData) ClosedBars() []*Kline {
+	now := time.Now()
+	out := make([]*Kline, 0, len(d.Klines))
+	for _, k := range d.Klines {
+		if d.barOpen(k, now) {
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
+
+// LastClosedBar 返回最后一根已完成的 bar, 序列为空或只有未完成
+// bar 时返回 nil
+func (d *KlineSeriesData) LastClosedBar() *Kline {
+	bars := d.ClosedBars()
+	if len(bars) == 0 {
+		return nil
+	}
+	return bars[len(bars)-1]
+}
+
+// TypicalPrice 返回每根 bar 的典型价 (最高+最低+收盘)/3,
+// 与 Klines 按下标对齐
+func (d *KlineSeriesData) TypicalPrice() []float64 {
+	out := make([]float64, len(d.Klines))
+	for i, k := range d.Klines {
+		out[i] = (k.High + k.Low + k.Close) / 3
+	}
+	return out
+}
+
+// VWAP 返回滚动 period 根 bar 的成交量加权均价, 权重为 bar 成交量,
+// 价格取典型价. 与 Klines 按下标对齐, 窗口不足 period 根时用已有
+// bar 计算; 窗口内成交量全为 0 时退化为典型价的简单均值
+func (d *KlineSeriesData) VWAP(period int) []float64 {
+	if period <= 0 {
+		period = 1
+	}
+	typical := d.TypicalPrice()
+	out := make([]float64, len(d.Klines))
+	for i := range d.Klines {
+		start := i - period + 1
+		if start < 0 {
+			start = 0
+		}
+		var weighted, volume, plain float64
+		for j := start; j <= i; j++ {
+			weighted += typical[j] * float64(d.Klines[j].Volume)
+			volume += float64(d.Klines[j].Volume)
+			plain += typical[j]
+		}
+		if volume > 0 {
+			out[i] = weighted / volume
+		} else {
+			out[i] = plain / float64(i-start+1)
+		}
+	}
+	return out
+}
+
+// AlignedMultiDuration 多周期对齐结果的一行: 以最低频序列的一根 bar
+// 为基准区间, Bars 按周期收录落在 [Datetime, Datetime+基准周期) 内的 bar
+type AlignedMultiDuration struct {
+	Datetime int64
+	Bars     map[time.Duration][]*Kline
+}
+
+// AlignSeries 把不同周期的 K线序列按时间对齐: 周期最大的序列作为
+// 基准, 每根基准 bar 的时间区间收录其余序列落在区间内的全部 bar
+// (如一根 5 分钟 bar 对应五根 1 分钟 bar), 结果按时间升序
+func AlignSeries(series ...*KlineSeriesData) []AlignedMultiDuration {
+	var base *KlineSeriesData
+	for _, s := range series {
+		if s == nil || len(s.Klines) == 0 {
+			continue
+		}
+		if base == nil || s.Duration > base.Duration {
+			base = s
+		}
+	}
+	if base == nil {
+		return nil
+	}
+	out := make([]AlignedMultiDuration, 0, len(base.Klines))
+	for _, bk := range base.Klines {
+		winStart, winEnd := bk.Datetime, bk.Datetime+base.Duration.Nanoseconds()
+		row := AlignedMultiDuration{
+			Datetime: bk.Datetime,
+			Bars:     make(map[time.Duration][]*Kline, len(series)),
+		}
+		for _, s := range series {
+			if s == nil {
+				continue
+			}
+			for _, k := range s.Klines {
+				if k.Datetime >= winStart && k.Datetime < winEnd {
+					row.Bars[s.Duration] = append(row.Bars[s.Duration], k)
+				}
+			}
+		}
+		out = append(out, row)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Datetime < out[j].Datetime })
+	return out
+}
+
+// TickSeriesData 一个合约的 Tick 序列快照
+type TickSeriesData struct {
+	Symbol string
+	Ticks  []*Tick
+	LastID int64
+}
+
+// VolumeDeltas 返回每个 tick 的增量成交量: Tick.Volume 为当日累计值,
+// 相邻差值即增量; 序列首 tick 或累计值回绕 (跨交易日) 时增量取当
+// tick 的累计值本身
+func (d *TickSeriesData) VolumeDeltas() []int64 {
+	out := make([]int64, len(d.Ticks))
+	for i, t := range d.Ticks {
+		if i == 0 || t.Volume < d.Ticks[i-1].Volume {
+			out[i] = t.Volume
+			continue
+		}
+		out[i] = t.Volume - d.Ticks[i-1].Volume
+	}
+	return out
+}
+
+// OIDeltas 返回每个 tick 的持仓量变化 (可为负), 序列首 tick 无
+// 参照, 变化记为 0
+func (d *TickSeriesData) OIDeltas() []int64 {
+	out := make([]int64, len(d.Ticks))
+	for i, t := range d.Ticks {
+		if i == 0 {
+			continue
+		}
+		out[i] = t.OpenInterest - d.Ticks[i-1].OpenInterest
+	}
+	return out
+}
+
+// SeriesSubscription 一个图表订阅(单合约或多合约对齐), 管理 set_chart 生命周期
+type SeriesSubscription struct {
+	client  *Client
+	chartID string
+	symbols []string
+	// duration 序列周期, 0 表示 Tick
+	duration  time.Duration
+	viewWidth int
+
+	mu           sync.Mutex
+	newBarFns    []func(*KlineSeriesData)
+	barUpdateFns []func(*KlineSeriesData)
+	newTickFns   []func(*TickSeriesData)
+	// symbolBarFns 按合约过滤的新 bar 回调, 见 OnSymbolNewBar
+	symbolBarFns map[string][]func(*KlineSeriesData)
+	// lastIDs 各合约上次通知时的 last_id, 用于识别新 bar
+	lastIDs map[string]int64
+	// seenEpochs 各合约序列路径上次处理到的 epoch, 异步回调下按
+	// 进度比较判断是否有新变更
+	seenEpochs map[string]int64
+	// backfilling 各合约进行中的缺口回补图表, 断线重连后序列出现
+	// 缺口时建立, 补齐后关闭
+	backfilling map[string]bool
+	closed      bool
+
+	logger *slog.Logger
+}
+
+// Kline 订阅单合约 K线序列
+func (api *SeriesAPI) Kline(ctx context.Context, symbol string, duration time.Duration, viewWidth int) (*SeriesSubscription, error) {
+	if err := api.client.auth.HasMdGrants(symbol); err != nil {
+		return nil, err
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("shinny: invalid kline duration %v", duration)
+	}
+	return api.newSubscription(ctx, []string{symbol}, duration, viewWidth)
+}
+
+// GetKlines 同步拉取一个合约最近 count 根 K线: 内部以 count 为
+// view_width 建立图表订阅, 等历史数据下载完成后返回快照并自动取消
+// 订阅, 适合一次性取历史的研究场景
+func (api *SeriesAPI) GetKlines(ctx context.Context, symbol string, duration time.Duration, count int) ([]*Kline, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("shinny: invalid kline count %d", count)
+	}
+	sub, err := api.Kline(ctx, symbol, duration, count)
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Close()
+	if err := sub.WaitSync(ctx); err != nil {
+		return nil, err
+	}
+	data, err := sub.KlineSeries(symbol)
+	if err != nil {
+		return nil, err
+	}
+	klines := data.Klines
+	if len(klines) > count {
+		klines = klines[len(klines)-count:]
+	}
+	return klines, nil
+}
+
+// klineRangeChunk 时间区间查询单片图表加载的 bar 数上限,
+// 超长区间向后滚动分片加载
+const klineRangeChunk = 5000
+
+// KlineByTimeRange 查询 [start, end] 时间区间内的 K线: 用
+// focus_datetime 图表定位到区间起点, 区间超出单片宽度时按最后一根
+// bar 的时间向后滚动加载, 结果精确过滤到时间范围并按 id 升序返回
+func (api *SeriesAPI) KlineByTimeRange(ctx context.Context, symbol string, duration time.Duration, start, end time.Time) ([]*Kline, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("shinny: invalid kline duration %v", duration)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("shinny: invalid time range [%v, %v]", start, end)
+	}
+	if err := api.client.auth.HasMdGrants(symbol); err != nil {
+		return nil, err
+	}
+	startNano, endNano := start.UnixNano(), end.UnixNano()
+	seen := make(map[int64]bool)
+	var out []*Kline
+	focus := start
+	for {
+		sub, err := api.newFocusSubscription(ctx, []string{symbol}, duration, klineRangeChunk, focus)
+		if err != nil {
+			return nil, err
+		}
+		if err := sub.WaitSync(ctx); err != nil {
+			sub.Close()
+			return nil, err
+		}
+		data, err := sub.KlineSeries(symbol)
+		sub.Close()
+		if err != nil {
+			return nil, err
+		}
+		var maxDT int64
+		for _, k := range data.Klines {
+			if k.Datetime > maxDT {
+				maxDT = k.Datetime
+			}
+			if k.Datetime < startNano || k.Datetime > endNano || seen[k.ID] {
+				continue
+			}
+			seen[k.ID] = true
+			out = append(out, k)
+		}
+		if len(data.Klines) == 0 || maxDT >= endNano {
+			break
+		}
+		next := time.Unix(0, maxDT).Add(duration)
+		if !next.After(focus) {
+			// 服务端没有更多数据, 避免原地重复拉取
+			break
+		}
+		focus = next
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+// MultiKline 订阅多合约对齐 K线序列, 以第一个合约为主合约对齐
+func (api *SeriesAPI) MultiKline(ctx context.Context, symbols []string, duration time.Duration, viewWidth int) (*SeriesSubscription, error) {
+	if len(symbols) == 0 {
+		return nil, fmt.Errorf("shinny: no symbols")
+	}
+	if err := api.client.auth.HasMdGrants(symbols...); err != nil {
+		return nil, err
+	}
+	return api.newSubscription(ctx, symbols, duration, viewWidth)
+}
+
+// MultiDurationSubscription 聚合同一合约多个周期的 K线订阅,
+// 内部为每个周期维护独立图表, 生命周期统一管理
+type MultiDurationSubscription struct {
+	symbol string
+
+	mu       sync.Mutex
+	subs     map[time.Duration]*SeriesSubscription
+	handlers []func(duration time.Duration, data *KlineSeriesData)
+}
+
+// KlineMultiDuration 同时订阅一个合约的多个 K线周期
+func (api *SeriesAPI) KlineMultiDuration(ctx context.Context, symbol string, durations []time.Duration, viewWidth int) (*MultiDurationSubscription, error) {
+	if len(durations) == 0 {
+		return nil, fmt.Errorf("shinny: no durations")
+	}
+	m := &MultiDurationSubscription{
+		symbol: symbol,
+		subs:   make(map[time.Duration]*SeriesSubscription, len(durations)),
+	}
+	for _, d := range durations {
+		dur := d
+		sub, err := api.Kline(ctx, symbol, dur, viewWidth)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		sub.OnNewBar(func(data *KlineSeriesData) {
+			m.mu.Lock()
+			handlers := append([]func(time.Duration, *KlineSeriesData){}, m.handlers...)
+			m.mu.Unlock()
+			for _, h := range handlers {
+				h(dur, data)
+			}
+		})
+		m.subs[dur] = sub
+	}
+	return m, nil
+}
+
+// OnNewBar 注册新 bar 回调, duration 标识产生新 bar 的周期
+func (m *MultiDurationSubscription) OnNewBar(handler func(duration time.Duration, data *KlineSeriesData)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+}
+
+// Sub 返回指定周期的底层订阅
+func (m *MultiDurationSubscription) Sub(duration time.Duration) *SeriesSubscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.subs[duration]
+}
+
+// WaitSync 等待全部周期的历史数据下载完成
+func (m *MultiDurationSubscription) WaitSync(ctx context.Context) error {
+	m.mu.Lock()
+	subs := make([]*SeriesSubscription, 0, len(m.subs))
+	for _, s := range m.subs {
+		subs = append(subs, s)
+	}
+	m.mu.Unlock()
+	for _, s := range subs {
+		if err := s.WaitSync(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 取消全部周期的订阅
+func (m *MultiDurationSubscription) Close() error {
+	m.mu.Lock()
+	subs := make([]*SeriesSubscription, 0, len(m.subs))
+	for _, s := range m.subs {
+		subs = append(subs, s)
+	}
+	m.mu.Unlock()
+	var firstErr error
+	for _, s := range subs {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Tick 订阅单合约 Tick 序列
+func (api *SeriesAPI) Tick(ctx context.Context, symbol string, viewWidth int) (*SeriesSubscription, error) {
+	if err := api.client.auth.HasMdGrants(symbol); err != nil {
+		return nil, err
+	}
+	return api.newSubscription(ctx, []string{symbol}, 0, viewWidth)
+}
+
+func (api *SeriesAPI) newSubscription(ctx context.Context, symbols []string, duration time.Duration, viewWidth int) (*SeriesSubscription, error) {
+	return api.newFocusSubscription(ctx, symbols, duration, viewWidth, time.Time{})
+}
+
+// newFocusSubscription 建立图表订阅, focus 非零时带 focus_datetime
+// 定位图表窗口到指定时刻, 用于历史区间查询
+func (api *SeriesAPI) newFocusSubscription(ctx context.Context, symbols []string, duration time.Duration, viewWidth int, focus time.Time) (*SeriesSubscription, error) {
+	ss := &SeriesSubscription{
+		client:      api.client,
+		chartID:     "TQGO_chart_" + RandomStr(8),
+		symbols:     symbols,
+		duration:    duration,
+		viewWidth:   viewWidth,
+		lastIDs:     make(map[string]int64),
+		seenEpochs:  make(map[string]int64),
+		backfilling: make(map[string]bool),
+		logger:      api.client.logger,
+	}
+	pkt := map[string]interface{}{
+		"aid":        "set_chart",
+		"chart_id":   ss.chartID,
+		"ins_list":   strings.Join(symbols, ","),
+		"duration":   duration.Nanoseconds(),
+		"view_width": viewWidth,
+	}
+	if !focus.IsZero() {
+		pkt["focus_datetime"] = focus.UnixNano()
+		pkt["focus_position"] = 0
+	}
+	if err := api.client.quotesWs.SetChart(pkt); err != nil {
+		return nil, err
+	}
+	api.client.dm.OnData(ss.onData)
+	api.client.mu.Lock()
+	api.client.seriesSubs = append(api.client.seriesSubs, ss)
+	api.client.mu.Unlock()
+	return ss, nil
+}
+
+// WaitSync 阻塞等待图表历史数据下载完成
+func (ss *SeriesSubscription) WaitSync(ctx context.Context) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if ss.synced() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// synced 检查 chart 状态: 服务端回报 chart 且 mdhis_more_data 为 false
+func (ss *SeriesSubscription) synced() bool {
+	raw, ok := ss.client.dm.GetCopyByPath("charts", ss.chartID)
+	if !ok {
+		return false
+	}
+	chart, ok := raw.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	if more, ok := chart["more_data"].(bool); ok && more {
+		return false
+	}
+	return true
+}
+
+// OnNewBar 注册新 bar 回调, 任一订阅合约产生新 bar 时触发
+func (ss *SeriesSubscription) OnNewBar(handler func(*KlineSeriesData)) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.newBarFns = append(ss.newBarFns, handler)
+}
+
+// OnSymbolNewBar 注册单合约新 bar 回调: 多合约订阅下只在 symbol
+// 产生新 bar 时触发, 回调数据即该合约的对齐序列
+func (ss *SeriesSubscription) OnSymbolNewBar(symbol string, handler func(*KlineSeriesData)) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.symbolBarFns == nil {
+		ss.symbolBarFns = make(map[string][]func(*KlineSeriesData))
+	}
+	ss.symbolBarFns[symbol] = append(ss.symbolBarFns[symbol], handler)
+}
+
+// OnBarUpdate 注册 bar 更新回调, 最新 bar 数据变化时触发
+func (ss *SeriesSubscription) OnBarUpdate(handler func(*KlineSeriesData)) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.barUpdateFns = append(ss.barUpdateFns, handler)
+}
+
+// OnNewTick 注册新 Tick 回调
+func (ss *SeriesSubscription) OnNewTick(handler func(*TickSeriesData)) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.newTickFns = append(ss.newTickFns, handler)
+}
+
+func (ss *SeriesSubscription) onData() {
+	ss.mu.Lock()
+	if ss.closed {
+		ss.mu.Unlock()
+		return
+	}
+	symbols := append([]string(nil), ss.symbols...)
+	ss.mu.Unlock()
+
+	for _, sym := range symbols {
+		if ss.duration == 0 {
+			ss.dispatchTicks(sym)
+		} else {
+			ss.dispatchKlines(sym)
+		}
+	}
+}
+
+// advanceSeenEpoch 判断合约序列自上次处理后是否有新变更,
+// 有则推进处理进度并返回 true
+func (ss *SeriesSubscription) advanceSeenEpoch(symbol string, pathEpoch int64) bool {
+	if pathEpoch == 0 {
+		return false
+	}
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if pathEpoch <= ss.seenEpochs[symbol] {
+		return false
+	}
+	ss.seenEpochs[symbol] = pathEpoch
+	return true
+}
+
+func (ss *SeriesSubscription) dispatchKlines(symbol string) {
+	durKey := strconv.FormatInt(ss.duration.Nanoseconds(), 10)
+	if !ss.advanceSeenEpoch(symbol, ss.client.dm.PathEpoch("klines", symbol, durKey)) {
+		return
+	}
+	data, err := ss.KlineSeries(symbol)
+	if err != nil {
+		return
+	}
+	ss.mu.Lock()
+	prev, seen := ss.lastIDs[symbol]
+	isNewBar := !seen || data.LastID > prev
+	ss.lastIDs[symbol] = data.LastID
+	newBarFns := append([]func(*KlineSeriesData){}, ss.newBarFns...)
+	newBarFns = append(newBarFns, ss.symbolBarFns[symbol]...)
+	updateFns := append([]func(*KlineSeriesData){}, ss.barUpdateFns...)
+	ss.mu.Unlock()
+
+	if seen {
+		ss.ensureContinuity(symbol, data, prev)
+	}
+	if isNewBar {
+		for _, h := range newBarFns {
+			go h(data)
+		}
+	}
+	for _, h := range updateFns {
+		go h(data)
+	}
+}
+
+// ensureContinuity 断线重连后的序列缺口回补: 本地已处理的最后 bar
+// 与最新截面之间有缺失 id 时, 用 left_kline_id 建立一个一次性图表
+// 拉取缺失区间, 数据按 id 合并进同一截面 (不会与实时流重复),
+// 补齐后关闭回补图表恢复纯实时流
+func (ss *SeriesSubscription) ensureContinuity(symbol string, data *KlineSeriesData, prev int64) {
+	if ss.duration <= 0 || data.LastID < prev {
+		return
+	}
+	present := make(map[int64]bool, len(data.Klines))
+	for _, k := range data.Klines {
+		present[k.ID] = true
+	}
+	var firstMissing, gap int64
+	for id := prev + 1; id < data.LastID; id++ {
+		if !present[id] {
+			if gap == 0 {
+				firstMissing = id
+			}
+			gap++
+		}
+	}
+	backfillID := ss.chartID + "_backfill_" + symbol
+	ss.mu.Lock()
+	active := ss.backfilling[symbol]
+	if gap > 0 && !active {
+		ss.backfilling[symbol] = true
+	} else if gap == 0 && active {
+		delete(ss.backfilling, symbol)
+	}
+	ss.mu.Unlock()
+
+	if gap > 0 && !active {
+		ss.logger.Warn("kline series gap detected, backfilling",
+			"symbol", symbol, "from", firstMissing, "bars", gap)
+		ss.client.quotesWs.SetChart(map[string]interface{}{
+			"aid":           "set_chart",
+			"chart_id":      backfillID,
+			"ins_list":      symbol,
+			"duration":      ss.duration.Nanoseconds(),
+			"view_width":    gap,
+			"left_kline_id": firstMissing,
+		})
+	} else if gap == 0 && active {
+		ss.client.quotesWs.SetChart(map[string]interface{}{
+			"aid":      "set_chart",
+			"chart_id": backfillID,
+			"ins_list": "",
+		})
+	}
+}
+
+func (ss *SeriesSubscription) dispatchTicks(symbol string) {
+	if !ss.advanceSeenEpoch(symbol, ss.client.dm.PathEpoch("ticks", symbol)) {
+		return
+	}
+	data, err := ss.TickSeries(symbol)
+	if err != nil {
+		return
+	}
+	ss.mu.Lock()
+	prev, seen := ss.lastIDs[symbol]
+	isNew := !seen || data.LastID > prev
+	ss.lastIDs[symbol] = data.LastID
+	handlers := append([]func(*TickSeriesData){}, ss.newTickFns...)
+	ss.mu.Unlock()
+	if !isNew {
+		return
+	}
+	for _, h := range handlers {
+		go h(data)
+	}
+}
+
+// KlineSeries 读取一个合约的 K线序列快照, 按 id 升序.
+// 节点取深拷贝, 遍历与序列化不受并发合并影响
+func (ss *SeriesSubscription) KlineSeries(symbol string) (*KlineSeriesData, error) {
+	durKey := strconv.FormatInt(ss.duration.Nanoseconds(), 10)
+	raw, ok := ss.client.dm.GetCopyByPath("klines", symbol, durKey)
+	if !ok {
+		return nil, fmt.Errorf("shinny: no kline data for %s", symbol)
+	}
+	node, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("shinny: unexpected kline node for %s", symbol)
+	}
+	data := &KlineSeriesData{Symbol: symbol, Duration: ss.duration, LastID: toInt64(node["last_id"])}
+	if bars, ok := node["data"].(map[string]interface{}); ok {
+		ids := make([]int64, 0, len(bars))
+		byID := make(map[int64]*Kline, len(bars))
+		for idStr, v := range bars {
+			k := &Kline{}
+			if err := ConvertToStruct(v, k); err != nil {
+				continue
+			}
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				continue
+			}
+			k.ID = id
+			ids = append(ids, id)
+			byID[id] = k
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			data.Klines = append(data.Klines, byID[id])
+		}
+	}
+	return data, nil
+}
+
+// TickSeries 读取一个合约的 Tick 序列快照, 按 id 升序.
+// 节点同样取深拷贝读取
+func (ss *SeriesSubscription) TickSeries(symbol string) (*TickSeriesData, error) {
+	raw, ok := ss.client.dm.GetCopyByPath("ticks", symbol)
+	if !ok {
+		return nil, fmt.Errorf("shinny: no tick data for %s", symbol)
+	}
+	node, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("shinny: unexpected tick node for %s", symbol)
+	}
+	data := &TickSeriesData{Symbol: symbol, LastID: toInt64(node["last_id"])}
+	if ticks, ok := node["data"].(map[string]interface{}); ok {
+		ids := make([]int64, 0, len(ticks))
+		byID := make(map[int64]*Tick, len(ticks))
+		for idStr, v := range ticks {
+			t := &Tick{}
+			if err := ConvertToStruct(v, t); err != nil {
+				continue
+			}
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				continue
+			}
+			t.ID = id
+			ids = append(ids, id)
+			byID[id] = t
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			data.Ticks = append(data.Ticks, byID[id])
+		}
+	}
+	return data, nil
+}
+
+// Close 取消图表订阅
+func (ss *SeriesSubscription) Close() error {
+	ss.mu.Lock()
+	if ss.closed {
+		ss.mu.Unlock()
+		return nil
+	}
+	ss.closed = true
+	ss.mu.Unlock()
+	return ss.client.quotesWs.SetChart(map[string]interface{}{
+		"aid":      "set_chart",
+		"chart_id": ss.chartID,
+		"ins_list": "",
+	})
+}
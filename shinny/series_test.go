@@ -0,0 +1,590 @@
+package shinny
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// mergeKlines 向截面喂入一个合约单周期的 K线数据
+func mergeKlines(dm *DataManager, symbol string, duration time.Duration, lastID int64, bars map[string]interface{}) {
+	durKey := map[string]interface{}{
+		"last_id": float64(lastID),
+		"data":    bars,
+	}
+	dm.MergeData(map[string]interface{}{
+		"klines": map[string]interface{}{
+			symbol: map[string]interface{}{
+				// duration 的纳秒数作为 key
+				formatDurationKey(duration): durKey,
+			},
+		},
+	})
+}
+
+func formatDurationKey(d time.Duration) string {
+	return strconv.FormatInt(d.Nanoseconds(), 10)
+}
+
+func TestKlineMultiDuration(t *testing.T) {
+	client := newTestClient(t, "futr")
+	api := client.Series()
+
+	sub, err := api.KlineMultiDuration(context.Background(), "SHFE.cu2601",
+		[]time.Duration{time.Minute, 5 * time.Minute}, 100)
+	if err != nil {
+		t.Fatalf("KlineMultiDuration error: %v", err)
+	}
+	defer sub.Close()
+
+	var mu sync.Mutex
+	got := make(map[time.Duration]int64)
+	sub.OnNewBar(func(d time.Duration, data *KlineSeriesData) {
+		mu.Lock()
+		got[d] = data.LastID
+		mu.Unlock()
+	})
+
+	bar := map[string]interface{}{
+		"0": map[string]interface{}{"open": 1.0, "high": 2.0, "low": 0.5, "close": 1.5, "volume": 10.0},
+	}
+	mergeKlines(client.dm, "SHFE.cu2601", time.Minute, 0, bar)
+	mergeKlines(client.dm, "SHFE.cu2601", 5*time.Minute, 0, bar)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 {
+		t.Fatalf("new bar callbacks = %v, want both durations", got)
+	}
+	if _, ok := got[time.Minute]; !ok {
+		t.Fatal("missing 1m new bar callback")
+	}
+	if _, ok := got[5*time.Minute]; !ok {
+		t.Fatal("missing 5m new bar callback")
+	}
+}
+
+func TestKlineFromTickAggregation(t *testing.T) {
+	tk := &TickKlineSubscription{
+		symbol:   "SHFE.cu2601",
+		duration: 3 * time.Second,
+		logger:   defaultLogger(),
+	}
+	var newBars atomic.Int64
+	tk.OnNewBar(func(data *KlineSeriesData) { newBars.Add(1) })
+
+	// base 对齐到 3 秒边界
+	base := int64(1700000001) * int64(time.Second)
+	sec := int64(time.Second)
+	tk.feedTicks(&TickSeriesData{Symbol: "SHFE.cu2601", Ticks: []*Tick{
+		{ID: 1, Datetime: base + sec/2, LastPrice: 100, Volume: 10, OpenInterest: 50},
+		{ID: 2, Datetime: base + sec, LastPrice: 102, Volume: 15, OpenInterest: 52},
+		{ID: 3, Datetime: base + 29*sec/10, LastPrice: 99, Volume: 18, OpenInterest: 51},
+		{ID: 4, Datetime: base + 31*sec/10, LastPrice: 101, Volume: 20, OpenInterest: 53},
+	}})
+
+	data := tk.KlineSeries()
+	if len(data.Klines) != 2 {
+		t.Fatalf("bars = %d, want 2", len(data.Klines))
+	}
+	b0 := data.Klines[0]
+	if b0.Datetime != base || b0.Open != 100 || b0.High != 102 || b0.Low != 99 || b0.Close != 99 {
+		t.Fatalf("bar0 = %+v", b0)
+	}
+	// 首笔 tick 无法差分增量, bar0 成交量为后两笔的增量 5+3
+	if b0.Volume != 8 || b0.CloseOI != 51 {
+		t.Fatalf("bar0 volume/oi = %d/%d, want 8/51", b0.Volume, b0.CloseOI)
+	}
+	b1 := data.Klines[1]
+	if b1.Datetime != base+3*sec || b1.Open != 101 || b1.Volume != 2 || b1.CloseOI != 53 {
+		t.Fatalf("bar1 = %+v", b1)
+	}
+
+	// 重复喂同一批 tick 不应重复聚合
+	tk.feedTicks(&TickSeriesData{Symbol: "SHFE.cu2601", Ticks: []*Tick{
+		{ID: 4, Datetime: base + 31*sec/10, LastPrice: 101, Volume: 20, OpenInterest: 53},
+	}})
+	if got := tk.KlineSeries().Klines[1].Volume; got != 2 {
+		t.Fatalf("duplicated feed changed volume to %d", got)
+	}
+	time.Sleep(50 * time.Millisecond)
+	// 同一批 tick 内产生的新 bar 合并为一次回调
+	if got := newBars.Load(); got != 1 {
+		t.Fatalf("new bar callbacks = %d, want 1", got)
+	}
+}
+
+func TestClosedBarsExcludeOpenBar(t *testing.T) {
+	now := time.Now()
+	minute := time.Minute.Nanoseconds()
+	// 最后一根 bar 起点在当前时间, 其时间段尚未结束
+	data := &KlineSeriesData{
+		Symbol:   "SHFE.cu2601",
+		Duration: time.Minute,
+		LastID:   3,
+		Klines: []*Kline{
+			{ID: 1, Datetime: now.UnixNano() - 2*minute, Close: 100},
+			{ID: 2, Datetime: now.UnixNano() - minute, Close: 101},
+			{ID: 3, Datetime: now.UnixNano(), Close: 102},
+		},
+	}
+	closed := data.ClosedBars()
+	if len(closed) != 2 {
+		t.Fatalf("ClosedBars = %d bars, want 2", len(closed))
+	}
+	last := data.LastClosedBar()
+	if last == nil || last.ID != 2 {
+		t.Fatalf("LastClosedBar = %+v, want id 2", last)
+	}
+
+	// 历史数据末尾 bar 时间段已结束, 视为完成
+	data.Klines[2].Datetime = now.UnixNano() - 2*minute
+	if got := len(data.ClosedBars()); got != 3 {
+		t.Fatalf("ClosedBars after bar end = %d, want 3", got)
+	}
+	if last := data.LastClosedBar(); last == nil || last.ID != 3 {
+		t.Fatalf("LastClosedBar after bar end = %+v, want id 3", last)
+	}
+
+	// 序列里只有未完成 bar
+	only := &KlineSeriesData{
+		Duration: time.Minute,
+		LastID:   1,
+		Klines:   []*Kline{{ID: 1, Datetime: now.UnixNano()}},
+	}
+	if only.LastClosedBar() != nil {
+		t.Fatal("LastClosedBar should be nil with only an open bar")
+	}
+}
+
+func TestGetKlinesReturnsRequestedCount(t *testing.T) {
+	client := newTestClient(t, "futr")
+	api := client.Series()
+
+	type result struct {
+		klines []*Kline
+		err    error
+	}
+	done := make(chan result, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go func() {
+		klines, err := api.GetKlines(ctx, "SHFE.cu2601", time.Minute, 3)
+		done <- result{klines, err}
+	}()
+
+	// 等订阅建立后模拟服务端回放: 5 根历史 + 图表同步完成
+	var chartID string
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		client.mu.Lock()
+		if n := len(client.seriesSubs); n > 0 {
+			chartID = client.seriesSubs[n-1].chartID
+		}
+		client.mu.Unlock()
+		if chartID != "" {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if chartID == "" {
+		t.Fatal("subscription not registered")
+	}
+	bars := map[string]interface{}{}
+	for i := 0; i < 5; i++ {
+		bars[strconv.Itoa(i)] = map[string]interface{}{
+			"datetime": float64(i) * float64(time.Minute),
+			"close":    float64(68000 + i),
+		}
+	}
+	mergeKlines(client.dm, "SHFE.cu2601", time.Minute, 4, bars)
+	client.dm.MergeData(map[string]interface{}{
+		"charts": map[string]interface{}{
+			chartID: map[string]interface{}{"more_data": false},
+		},
+	})
+
+	r := <-done
+	if r.err != nil {
+		t.Fatalf("GetKlines error: %v", r.err)
+	}
+	// 服务端多给的历史只保留最近 count 根
+	if len(r.klines) != 3 {
+		t.Fatalf("klines = %d, want 3", len(r.klines))
+	}
+	if r.klines[0].ID != 2 || r.klines[2].ID != 4 {
+		t.Fatalf("kline ids = %d..%d, want 2..4", r.klines[0].ID, r.klines[2].ID)
+	}
+	if r.klines[2].Close != 68004 {
+		t.Fatalf("last close = %v, want 68004", r.klines[2].Close)
+	}
+
+	// 拉取完成后图表订阅自动关闭
+	client.mu.Lock()
+	sub := client.seriesSubs[len(client.seriesSubs)-1]
+	client.mu.Unlock()
+	sub.mu.Lock()
+	closed := sub.closed
+	sub.mu.Unlock()
+	if !closed {
+		t.Fatal("subscription should be closed after GetKlines")
+	}
+}
+
+func TestTickSeriesDeltas(t *testing.T) {
+	data := &TickSeriesData{
+		Symbol: "SHFE.cu2601",
+		Ticks: []*Tick{
+			{ID: 0, Volume: 100, OpenInterest: 5000},
+			{ID: 1, Volume: 130, OpenInterest: 5010},
+			{ID: 2, Volume: 130, OpenInterest: 4990},
+			// 跨交易日累计值回绕
+			{ID: 3, Volume: 20, OpenInterest: 4995},
+		},
+	}
+
+	vols := data.VolumeDeltas()
+	wantVols := []int64{100, 30, 0, 20}
+	for i, want := range wantVols {
+		if vols[i] != want {
+			t.Fatalf("VolumeDeltas[%d] = %d, want %d", i, vols[i], want)
+		}
+	}
+
+	ois := data.OIDeltas()
+	wantOIs := []int64{0, 10, -20, 5}
+	for i, want := range wantOIs {
+		if ois[i] != want {
+			t.Fatalf("OIDeltas[%d] = %d, want %d", i, ois[i], want)
+		}
+	}
+
+	if got := (&TickSeriesData{}).VolumeDeltas(); len(got) != 0 {
+		t.Fatalf("empty series deltas = %v", got)
+	}
+}
+
+func TestKlineBackfillAfterGap(t *testing.T) {
+	client := newTestClient(t, "futr")
+	api := client.Series()
+
+	sub, err := api.Kline(context.Background(), "SHFE.cu2601", time.Minute, 100)
+	if err != nil {
+		t.Fatalf("Kline error: %v", err)
+	}
+	defer sub.Close()
+
+	bars := func(ids ...int64) map[string]interface{} {
+		out := map[string]interface{}{}
+		for _, id := range ids {
+			out[strconv.FormatInt(id, 10)] = map[string]interface{}{
+				"datetime": float64(id) * float64(time.Minute),
+				"close":    float64(68000 + id),
+			}
+		}
+		return out
+	}
+	backfillID := sub.chartID + "_backfill_SHFE.cu2601"
+	backfillChart := func() map[string]interface{} {
+		client.quotesWs.mu.Lock()
+		defer client.quotesWs.mu.Unlock()
+		return client.quotesWs.charts[backfillID]
+	}
+	waitLastID := func(want int64) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+			sub.mu.Lock()
+			got := sub.lastIDs["SHFE.cu2601"]
+			sub.mu.Unlock()
+			if got == want {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatalf("series never reached last_id %d", want)
+	}
+
+	// 正常实时流: id 0..2
+	mergeKlines(client.dm, "SHFE.cu2601", time.Minute, 2, bars(0, 1, 2))
+	waitLastID(2)
+	if backfillChart() != nil {
+		t.Fatal("backfill chart created without gap")
+	}
+
+	// 模拟断线重连后服务端只推最新 bar: id 5..6, 中间 3..4 缺失
+	mergeKlines(client.dm, "SHFE.cu2601", time.Minute, 6, bars(5, 6))
+	waitLastID(6)
+	var chart map[string]interface{}
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		if chart = backfillChart(); chart != nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if chart == nil {
+		t.Fatal("backfill chart not requested after gap")
+	}
+	if chart["left_kline_id"] != int64(3) || chart["view_width"] != int64(2) {
+		t.Fatalf("backfill range = %v width %v, want 3 width 2",
+			chart["left_kline_id"], chart["view_width"])
+	}
+
+	// 回补数据按 id 合并进同一截面, 补齐后关闭回补图表
+	mergeKlines(client.dm, "SHFE.cu2601", time.Minute, 6, bars(3, 4))
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		if backfillChart() == nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if backfillChart() != nil {
+		t.Fatal("backfill chart not closed after gap filled")
+	}
+	data, err := sub.KlineSeries("SHFE.cu2601")
+	if err != nil {
+		t.Fatalf("KlineSeries error: %v", err)
+	}
+	if len(data.Klines) != 7 {
+		t.Fatalf("klines = %d, want 7 continuous bars", len(data.Klines))
+	}
+	for i, k := range data.Klines {
+		if k.ID != int64(i) {
+			t.Fatalf("kline[%d].ID = %d, series not continuous", i, k.ID)
+		}
+	}
+}
+
+func TestVWAPAndTypicalPrice(t *testing.T) {
+	data := &KlineSeriesData{Klines: []*Kline{
+		{ID: 0, High: 12, Low: 8, Close: 10, Volume: 10},
+		{ID: 1, High: 22, Low: 18, Close: 20, Volume: 30},
+		{ID: 2, High: 32, Low: 28, Close: 30, Volume: 0},
+		{ID: 3, High: 42, Low: 38, Close: 40, Volume: 10},
+	}}
+
+	tp := data.TypicalPrice()
+	want := []float64{10, 20, 30, 40}
+	for i := range want {
+		if tp[i] != want[i] {
+			t.Fatalf("typical[%d] = %v, want %v", i, tp[i], want[i])
+		}
+	}
+
+	vwap := data.VWAP(2)
+	// 窗口不足时用已有 bar: bar0 = 10
+	if vwap[0] != 10 {
+		t.Fatalf("vwap[0] = %v, want 10", vwap[0])
+	}
+	// (10*10 + 20*30) / 40 = 17.5
+	if vwap[1] != 17.5 {
+		t.Fatalf("vwap[1] = %v, want 17.5", vwap[1])
+	}
+	// bar2 成交量为 0, 窗口 (bar1, bar2) 由 bar1 的量主导: (20*30+30*0)/30 = 20
+	if vwap[2] != 20 {
+		t.Fatalf("vwap[2] = %v, want 20", vwap[2])
+	}
+	// (30*0 + 40*10) / 10 = 40
+	if vwap[3] != 40 {
+		t.Fatalf("vwap[3] = %v, want 40", vwap[3])
+	}
+
+	// 窗口内全为 0 量时退化为简单均值
+	zero := &KlineSeriesData{Klines: []*Kline{
+		{High: 12, Low: 8, Close: 10},
+		{High: 22, Low: 18, Close: 20},
+	}}
+	if got := zero.VWAP(2); got[1] != 15 {
+		t.Fatalf("zero-volume vwap = %v, want 15", got[1])
+	}
+}
+
+func TestOnSymbolNewBarFiltersBySymbol(t *testing.T) {
+	client := newTestClient(t, "futr")
+	api := client.Series()
+
+	sub, err := api.MultiKline(context.Background(),
+		[]string{"SHFE.cu2601", "SHFE.au2606"}, time.Minute, 100)
+	if err != nil {
+		t.Fatalf("MultiKline error: %v", err)
+	}
+	defer sub.Close()
+
+	var target, all atomic.Int64
+	sub.OnSymbolNewBar("SHFE.cu2601", func(data *KlineSeriesData) {
+		if data.Symbol != "SHFE.cu2601" {
+			t.Errorf("callback symbol = %s, want SHFE.cu2601", data.Symbol)
+		}
+		target.Add(1)
+	})
+	sub.OnNewBar(func(*KlineSeriesData) { all.Add(1) })
+
+	bar := map[string]interface{}{
+		"0": map[string]interface{}{"open": 1.0, "high": 2.0, "low": 0.5, "close": 1.5, "volume": 10.0},
+	}
+	// 仅目标外合约产生新 bar: 过滤回调不触发
+	mergeKlines(client.dm, "SHFE.au2606", time.Minute, 0, bar)
+
+	deadline := time.Now().Add(time.Second)
+	for all.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if all.Load() == 0 {
+		t.Fatal("OnNewBar not fired for SHFE.au2606")
+	}
+	if target.Load() != 0 {
+		t.Fatalf("OnSymbolNewBar fired %d times for other symbol", target.Load())
+	}
+
+	// 目标合约产生新 bar: 过滤回调触发
+	mergeKlines(client.dm, "SHFE.cu2601", time.Minute, 0, bar)
+	deadline = time.Now().Add(time.Second)
+	for target.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if target.Load() != 1 {
+		t.Fatalf("OnSymbolNewBar fired %d times, want 1", target.Load())
+	}
+}
+
+func TestKlineByTimeRange(t *testing.T) {
+	client := newTestClient(t, "futr")
+	api := client.Series()
+
+	day := 24 * time.Hour
+	dayNano := func(d int) float64 {
+		return float64(time.Date(2025, 9, d, 0, 0, 0, 0, time.UTC).UnixNano())
+	}
+
+	// 模拟服务端: 监听 set_chart 报文, 回报图表就绪并下发覆盖区间的日线
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		handled := make(map[string]bool)
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(2 * time.Millisecond):
+			}
+			client.quotesWs.TqWebsocket.mu.Lock()
+			queue := append([][]byte(nil), client.quotesWs.queue...)
+			client.quotesWs.TqWebsocket.mu.Unlock()
+			for _, raw := range queue {
+				var pkt map[string]interface{}
+				if json.Unmarshal(raw, &pkt) != nil || pkt["aid"] != "set_chart" {
+					continue
+				}
+				chartID, _ := pkt["chart_id"].(string)
+				if chartID == "" || handled[chartID] {
+					continue
+				}
+				if _, ok := pkt["focus_datetime"]; !ok {
+					continue
+				}
+				handled[chartID] = true
+				bars := map[string]interface{}{}
+				for i := 0; i < 8; i++ { // 8/30 ~ 9/6
+					bars[strconv.Itoa(i)] = map[string]interface{}{
+						"datetime": dayNano(i) - float64(2*day.Nanoseconds()),
+						"open":     100.0 + float64(i), "close": 101.0 + float64(i),
+						"high": 102.0, "low": 99.0, "volume": 10.0,
+					}
+				}
+				client.dm.MergeData(map[string]interface{}{
+					"charts": map[string]interface{}{
+						chartID: map[string]interface{}{"more_data": false},
+					},
+					"klines": map[string]interface{}{
+						"SHFE.cu2601": map[string]interface{}{
+							formatDurationKey(day): map[string]interface{}{
+								"last_id": 7.0,
+								"data":    bars,
+							},
+						},
+					},
+				})
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2025, 9, 5, 0, 0, 0, 0, time.UTC)
+	klines, err := api.KlineByTimeRange(ctx, "SHFE.cu2601", day, start, end)
+	if err != nil {
+		t.Fatalf("KlineByTimeRange error: %v", err)
+	}
+	// 8/30~9/6 共 8 根, 精确过滤到 9/1~9/5 共 5 根
+	if len(klines) != 5 {
+		t.Fatalf("klines = %d, want 5", len(klines))
+	}
+	if klines[0].Datetime != int64(dayNano(1)) || klines[4].Datetime != int64(dayNano(5)) {
+		t.Fatalf("range bounds = %d..%d", klines[0].Datetime, klines[4].Datetime)
+	}
+	for i := 1; i < len(klines); i++ {
+		if klines[i].ID <= klines[i-1].ID {
+			t.Fatalf("klines not sorted by id: %v", klines)
+		}
+	}
+}
+
+func TestAlignSeries(t *testing.T) {
+	mkSeries := func(d time.Duration, startNano int64, closes ...float64) *KlineSeriesData {
+		data := &KlineSeriesData{Symbol: "SHFE.cu2601", Duration: d}
+		for i, c := range closes {
+			data.Klines = append(data.Klines, &Kline{
+				ID:       int64(i),
+				Datetime: startNano + int64(i)*d.Nanoseconds(),
+				Close:    c,
+			})
+		}
+		return data
+	}
+
+	base := int64(1_000_000_000_000)
+	m1 := mkSeries(time.Minute, base, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	m5 := mkSeries(5*time.Minute, base, 100, 200)
+
+	rows := AlignSeries(m1, m5)
+	if len(rows) != 2 {
+		t.Fatalf("aligned rows = %d, want 2", len(rows))
+	}
+	// 每根 5 分钟 bar 对应五根 1 分钟 bar
+	for i, row := range rows {
+		if row.Datetime != base+int64(i)*5*time.Minute.Nanoseconds() {
+			t.Fatalf("row %d datetime = %d", i, row.Datetime)
+		}
+		if got := len(row.Bars[time.Minute]); got != 5 {
+			t.Fatalf("row %d 1m bars = %d, want 5", i, got)
+		}
+		if got := len(row.Bars[5*time.Minute]); got != 1 {
+			t.Fatalf("row %d 5m bars = %d, want 1", i, got)
+		}
+	}
+	if rows[0].Bars[time.Minute][0].Close != 1 || rows[1].Bars[time.Minute][0].Close != 6 {
+		t.Fatalf("1m bar grouping wrong: %v / %v",
+			rows[0].Bars[time.Minute][0].Close, rows[1].Bars[time.Minute][0].Close)
+	}
+	if rows[1].Bars[5*time.Minute][0].Close != 200 {
+		t.Fatalf("5m bar grouping wrong")
+	}
+
+	// 空输入返回 nil
+	if got := AlignSeries(); got != nil {
+		t.Fatalf("AlignSeries() = %v, want nil", got)
+	}
+}
@@ -0,0 +1,93 @@
+package shinny
+
+import (
+	"strconv"
+	"strings"
+)
+
+// HisSettlement 历史结算单解析结果, 字段值保持结算单原始文本
+type HisSettlement struct {
+	TradingDay string
+	// Account 资金状况, 如 "期初结存" -> "1000000.00"
+	Account map[string]string
+}
+
+// AccountFloat 按数值读取资金状况字段, 兼容结算单常见的千分位
+// ("1,234.56") 与括号负数 ("(1,234.56)" 表示 -1234.56) 格式,
+// 字段缺失或无法解析时返回 0
+func (s *HisSettlement) AccountFloat(key string) float64 {
+	v, ok := s.Account[key]
+	if !ok {
+		return 0
+	}
+	f, _ := parseSettlementNumber(v)
+	return f
+}
+
+// parseSettlementNumber 解析结算单数值文本: 去掉千分位逗号,
+// 括号包裹视为负数
+func parseSettlementNumber(v string) (float64, bool) {
+	v = strings.TrimSpace(v)
+	negative := false
+	if strings.HasPrefix(v, "(") && strings.HasSuffix(v, ")") {
+		negative = true
+		v = v[1 : len(v)-1]
+	}
+	v = strings.ReplaceAll(v, ",", "")
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, false
+	}
+	if negative {
+		f = -f
+	}
+	return f, true
+}
+
+// ParseSettlementContent 解析交易所结算单文本,
+// 提取资金状况段的键值对
+func ParseSettlementContent(content string) (*HisSettlement, error) {
+	s := &HisSettlement{Account: make(map[string]string)}
+	lines := strings.Split(content, "\n")
+	inAccount := false
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.Contains(line, "日期") || strings.Contains(line, "Date") {
+			for _, f := range strings.Fields(line) {
+				if kv := strings.SplitN(f, "：", 2); len(kv) == 2 && strings.Contains(kv[0], "日期") {
+					s.TradingDay = strings.TrimSpace(kv[1])
+				}
+			}
+		}
+		if strings.Contains(line, "资金状况") {
+			inAccount = true
+			continue
+		}
+		if inAccount {
+			if strings.HasPrefix(line, "---") || line == "" {
+				if len(s.Account) > 0 {
+					inAccount = false
+				}
+				continue
+			}
+			for _, seg := range strings.Split(line, "  ") {
+				seg = strings.TrimSpace(seg)
+				if seg == "" {
+					continue
+				}
+				kv := strings.SplitN(seg, "：", 2)
+				if len(kv) != 2 {
+					kv = strings.SplitN(seg, ":", 2)
+				}
+				if len(kv) == 2 {
+					key := strings.TrimSpace(kv[0])
+					if i := strings.Index(key, "/"); i > 0 {
+						key = key[:i]
+					}
+					s.Account[key] = strings.TrimSpace(strings.SplitN(kv[1], "/", 2)[0])
+				}
+			}
+		}
+	}
+	return s, nil
+}
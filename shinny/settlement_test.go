@@ -0,0 +1,60 @@
+package shinny
+
+import "testing"
+
+const sampleSettlement = `
+                                         交易结算单(Settlement Statement)
+客户号 Client ID：12345678          日期：20260826
+---------------------------------------------------------------------------
+资金状况  Account Summary
+---------------------------------------------------------------------------
+期初结存：1,000,000.00        基础保证金：0.00
+出 入 金：0.00                平仓盈亏：(12,345.67)
+持仓盯市盈亏：2,500.00        手 续 费：36.80
+期末结存：990,117.53          客户权益：990,117.53
+---------------------------------------------------------------------------
+`
+
+func TestParseSettlementAccountFloat(t *testing.T) {
+	s, err := ParseSettlementContent(sampleSettlement)
+	if err != nil {
+		t.Fatalf("ParseSettlementContent error: %v", err)
+	}
+	if s.TradingDay != "20260826" {
+		t.Fatalf("TradingDay = %q, want 20260826", s.TradingDay)
+	}
+	if got := s.AccountFloat("期初结存"); got != 1000000 {
+		t.Fatalf("期初结存 = %v, want 1000000", got)
+	}
+	// 括号表示负数
+	if got := s.AccountFloat("平仓盈亏"); got != -12345.67 {
+		t.Fatalf("平仓盈亏 = %v, want -12345.67", got)
+	}
+	if got := s.AccountFloat("手 续 费"); got != 36.8 {
+		t.Fatalf("手续费 = %v, want 36.8", got)
+	}
+	// 缺失字段返回 0
+	if got := s.AccountFloat("不存在"); got != 0 {
+		t.Fatalf("missing key = %v, want 0", got)
+	}
+}
+
+func TestParseSettlementNumber(t *testing.T) {
+	cases := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"1,234.56", 1234.56, true},
+		{"(1,234.56)", -1234.56, true},
+		{"0.00", 0, true},
+		{"-2500", -2500, true},
+		{"abc", 0, false},
+	}
+	for _, c := range cases {
+		got, ok := parseSettlementNumber(c.in)
+		if got != c.want || ok != c.ok {
+			t.Fatalf("parseSettlementNumber(%q) = %v,%v, want %v,%v", c.in, got, ok, c.want, c.ok)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package shinny
+
+// SlippageModel 撮合滑点模型: 回测时在理论成交价基础上按订单方向
+// 偏移, 买单向上 / 卖单向下, 模拟真实成交的不利偏差
+type SlippageModel interface {
+	// SlipPrice 根据订单与理论成交价返回调整后的成交价
+	SlipPrice(o *Order, price float64) float64
+}
+
+// slipTowards 按方向施加不利偏移: 买单加价, 卖单减价
+func slipTowards(direction string, price, offset float64) float64 {
+	if offset <= 0 {
+		return price
+	}
+	if direction == DirectionBuy {
+		return price + offset
+	}
+	return price - offset
+}
+
+// FixedTickSlippage 固定跳数滑点: 每笔成交偏移 Ticks 个最小变动价位
+type FixedTickSlippage struct {
+	Ticks     int
+	PriceTick float64
+}
+
+func (m FixedTickSlippage) SlipPrice(o *Order, price float64) float64 {
+	return slipTowards(o.Direction, price, float64(m.Ticks)*m.PriceTick)
+}
+
+// RatioSlippage 比例滑点: 偏移量为成交价乘以 Ratio, 可按合约
+// 波动率校准 Ratio
+type RatioSlippage struct {
+	Ratio float64
+}
+
+func (m RatioSlippage) SlipPrice(o *Order, price float64) float64 {
+	return slipTowards(o.Direction, price, price*m.Ratio)
+}
+
+// VolumeImpactSlippage 成交量冲击滑点: 偏移量随订单手数线性增长,
+// 每手偏移 ImpactPerLot, 模拟大单对盘口的冲击
+type VolumeImpactSlippage struct {
+	ImpactPerLot float64
+}
+
+func (m VolumeImpactSlippage) SlipPrice(o *Order, price float64) float64 {
+	return slipTowards(o.Direction, price, float64(o.VolumeLeft)*m.ImpactPerLot)
+}
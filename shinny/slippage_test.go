@@ -0,0 +1,59 @@
+package shinny
+
+import (
+	"testing"
+)
+
+// tradePrice 取某订单唯一成交的价格
+func tradePrice(t *testing.T, vt *VirtualTrader, orderID string) float64 {
+	t.Helper()
+	trades, _ := vt.GetTrades(nil)
+	for _, tr := range trades {
+		if tr.OrderID == orderID {
+			return tr.Price
+		}
+	}
+	t.Fatalf("no trade for order %s", orderID)
+	return 0
+}
+
+func TestFixedTickSlippage(t *testing.T) {
+	vt := NewVirtualTrader(10000000, WithSlippage(FixedTickSlippage{Ticks: 2, PriceTick: 10}))
+	vt.UpdateMarketPrice("SHFE.cu2601", 68000)
+
+	buy, err := vt.InsertOrder(nil, &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeAny,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder error: %v", err)
+	}
+	if got := tradePrice(t, vt, buy.OrderID); got != 68020 {
+		t.Fatalf("buy fill price = %v, want 68020", got)
+	}
+
+	sell, err := vt.InsertOrder(nil, &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionSell, Offset: OffsetClose,
+		Volume: 1, PriceType: PriceTypeAny,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder error: %v", err)
+	}
+	if got := tradePrice(t, vt, sell.OrderID); got != 67980 {
+		t.Fatalf("sell fill price = %v, want 67980", got)
+	}
+}
+
+func TestRatioAndVolumeImpactSlippage(t *testing.T) {
+	o := &Order{Direction: DirectionBuy, VolumeLeft: 5}
+	if got := (RatioSlippage{Ratio: 0.001}).SlipPrice(o, 68000); got != 68068 {
+		t.Fatalf("ratio slip = %v, want 68068", got)
+	}
+	if got := (VolumeImpactSlippage{ImpactPerLot: 2}).SlipPrice(o, 68000); got != 68010 {
+		t.Fatalf("volume impact slip = %v, want 68010", got)
+	}
+	o.Direction = DirectionSell
+	if got := (VolumeImpactSlippage{ImpactPerLot: 2}).SlipPrice(o, 68000); got != 67990 {
+		t.Fatalf("sell volume impact slip = %v, want 67990", got)
+	}
+}
@@ -0,0 +1,145 @@
+package shinny
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// spreadViewWidth 价差订阅底层图表的默认宽度
+const spreadViewWidth = 200
+
+// SpreadSubscription 两合约价差序列订阅: 底层是 A/B 两合约的
+// MultiKline 对齐图表, 价差 K线按 binding 对齐关系在本地合成
+type SpreadSubscription struct {
+	sub      *SeriesSubscription
+	symbolA  string
+	symbolB  string
+	ratio    float64
+	duration time.Duration
+
+	mu        sync.Mutex
+	newBarFns []func(*KlineSeriesData)
+	lastID    int64
+	seen      bool
+}
+
+// SpreadSeries 订阅 symbolA/symbolB 的对齐 K线并在本地计算价差序列
+// (A - ratio*B). 对齐依赖服务端多合约图表的 binding 机制: 主合约每根
+// bar 通过 binding 映射到副合约的对应 bar, 两边都有数据的 bar 才进入
+// 价差序列. 高低点按同字段相减合成, 是真实价差高低点的近似
+func (api *SeriesAPI) SpreadSeries(ctx context.Context, symbolA, symbolB string, duration time.Duration, ratio float64) (*SpreadSubscription, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("shinny: invalid spread duration %v", duration)
+	}
+	sub, err := api.MultiKline(ctx, []string{symbolA, symbolB}, duration, spreadViewWidth)
+	if err != nil {
+		return nil, err
+	}
+	sp := &SpreadSubscription{
+		sub:      sub,
+		symbolA:  symbolA,
+		symbolB:  symbolB,
+		ratio:    ratio,
+		duration: duration,
+	}
+	// 主合约产生新 bar 时重算价差, binding 未就绪的 bar 被跳过,
+	// 等下次变更补上
+	sub.OnSymbolNewBar(symbolA, func(*KlineSeriesData) { sp.recompute() })
+	sub.OnBarUpdate(func(data *KlineSeriesData) {
+		if data.Symbol == symbolB {
+			sp.recompute()
+		}
+	})
+	return sp, nil
+}
+
+// OnNewBar 注册价差新 bar 回调, 回调数据的 Symbol 为
+// "A-ratio*B" 形式的合成代码
+func (sp *SpreadSubscription) OnNewBar(handler func(*KlineSeriesData)) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.newBarFns = append(sp.newBarFns, handler)
+}
+
+// Spread 读取当前对齐截面合成的价差序列快照
+func (sp *SpreadSubscription) Spread() (*KlineSeriesData, error) {
+	a, err := sp.sub.KlineSeries(sp.symbolA)
+	if err != nil {
+		return nil, err
+	}
+	b, err := sp.sub.KlineSeries(sp.symbolB)
+	if err != nil {
+		return nil, err
+	}
+	durKey := strconv.FormatInt(sp.duration.Nanoseconds(), 10)
+	rawBind, ok := sp.sub.client.dm.GetCopyByPath("klines", sp.symbolA, durKey, "binding", sp.symbolB)
+	if !ok {
+		return nil, fmt.Errorf("shinny: no binding data for %s/%s, chart not aligned yet", sp.symbolA, sp.symbolB)
+	}
+	binding, ok := rawBind.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("shinny: unexpected binding node for %s/%s", sp.symbolA, sp.symbolB)
+	}
+
+	bByID := make(map[int64]*Kline, len(b.Klines))
+	for _, k := range b.Klines {
+		bByID[k.ID] = k
+	}
+	data := &KlineSeriesData{
+		Symbol:   fmt.Sprintf("%s-%g*%s", sp.symbolA, sp.ratio, sp.symbolB),
+		Duration: sp.duration,
+	}
+	for _, ka := range a.Klines {
+		rawID, ok := binding[strconv.FormatInt(ka.ID, 10)]
+		if !ok {
+			continue
+		}
+		kb, ok := bByID[toInt64(rawID)]
+		if !ok {
+			continue
+		}
+		data.Klines = append(data.Klines, &Kline{
+			ID:       ka.ID,
+			Datetime: ka.Datetime,
+			Open:     ka.Open - sp.ratio*kb.Open,
+			High:     ka.High - sp.ratio*kb.High,
+			Low:      ka.Low - sp.ratio*kb.Low,
+			Close:    ka.Close - sp.ratio*kb.Close,
+		})
+		data.LastID = ka.ID
+	}
+	return data, nil
+}
+
+// recompute 对齐截面变更后重算价差, 末尾 bar 前进时触发新 bar 回调
+func (sp *SpreadSubscription) recompute() {
+	data, err := sp.Spread()
+	if err != nil || len(data.Klines) == 0 {
+		return
+	}
+	sp.mu.Lock()
+	isNewBar := !sp.seen || data.LastID > sp.lastID
+	sp.seen = true
+	sp.lastID = data.LastID
+	handlers := append([]func(*KlineSeriesData){}, sp.newBarFns...)
+	sp.mu.Unlock()
+	if !isNewBar {
+		return
+	}
+	for _, h := range handlers {
+		go h(data)
+	}
+}
+
+// WaitSync 等待底层图表历史数据下载完成
+func (sp *SpreadSubscription) WaitSync(ctx context.Context) error {
+	return sp.sub.WaitSync(ctx)
+}
+
+// Close 取消底层订阅
+func (sp *SpreadSubscription) Close() error {
+	return sp.sub.Close()
+}
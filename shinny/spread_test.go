@@ -0,0 +1,89 @@
+package shinny
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSpreadSeries(t *testing.T) {
+	client := newTestClient(t, "futr")
+	api := client.Series()
+
+	sp, err := api.SpreadSeries(context.Background(), "SHFE.cu2601", "SHFE.cu2602", time.Minute, 1.0)
+	if err != nil {
+		t.Fatalf("SpreadSeries error: %v", err)
+	}
+	defer sp.Close()
+
+	var mu sync.Mutex
+	var got *KlineSeriesData
+	sp.OnNewBar(func(data *KlineSeriesData) {
+		mu.Lock()
+		got = data
+		mu.Unlock()
+	})
+
+	bars := func(vals ...float64) map[string]interface{} {
+		out := map[string]interface{}{}
+		for i, v := range vals {
+			out[strconv.Itoa(i)] = map[string]interface{}{
+				"datetime": float64(i) * 60e9,
+				"open":     v, "high": v + 2, "low": v - 2, "close": v + 1,
+				"volume": 10.0,
+			}
+		}
+		return out
+	}
+	// 主合约带 binding: A 的 bar 0/1 对齐 B 的 bar 0/1
+	client.dm.MergeData(map[string]interface{}{
+		"klines": map[string]interface{}{
+			"SHFE.cu2602": map[string]interface{}{
+				formatDurationKey(time.Minute): map[string]interface{}{
+					"last_id": 1.0,
+					"data":    bars(100, 110),
+				},
+			},
+			"SHFE.cu2601": map[string]interface{}{
+				formatDurationKey(time.Minute): map[string]interface{}{
+					"last_id": 1.0,
+					"data":    bars(150, 180),
+					"binding": map[string]interface{}{
+						"SHFE.cu2602": map[string]interface{}{"0": 0.0, "1": 1.0},
+					},
+				},
+			},
+		},
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		done := got != nil
+		mu.Unlock()
+		if done || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if got == nil {
+		t.Fatal("spread new bar callback not fired")
+	}
+	if len(got.Klines) != 2 || got.LastID != 1 {
+		t.Fatalf("spread klines = %d last_id = %d, want 2/1", len(got.Klines), got.LastID)
+	}
+	// bar0: open 150-100=50, close 151-101=50; bar1: open 180-110=70
+	if got.Klines[0].Open != 50 || got.Klines[0].Close != 50 {
+		t.Fatalf("bar0 = %+v, want open/close 50", got.Klines[0])
+	}
+	if got.Klines[1].Open != 70 || got.Klines[1].High != 70 {
+		t.Fatalf("bar1 = %+v, want open/high 70", got.Klines[1])
+	}
+	if got.Symbol != "SHFE.cu2601-1*SHFE.cu2602" {
+		t.Fatalf("spread symbol = %q", got.Symbol)
+	}
+}
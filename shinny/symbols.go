@@ -0,0 +1,207 @@
+package shinny
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SymbolsServerURL 合约信息服务地址
+var SymbolsServerURL = "https://openmd.shinnytech.com/t/md/symbols/latest.json"
+
+// BrokersServerURL 期货公司交易服务器地址模板
+var BrokersServerURL = "https://files.shinnytech.com/%s.json"
+
+// SymbolInfo 合约基础信息, 来自合约服务的 latest.json
+type SymbolInfo struct {
+	InstrumentID string `json:"instrument_id"`
+	ExchangeID   string `json:"exchange_id"`
+	ProductID    string `json:"product_id"`
+	Class        string `json:"class"`
+
+	PriceTick      float64 `json:"price_tick"`
+	PriceDecs      int64   `json:"price_decs"`
+	VolumeMultiple float64 `json:"volume_multiple"`
+
+	MaxLimitOrderVolume  int64 `json:"max_limit_order_volume"`
+	MaxMarketOrderVolume int64 `json:"max_market_order_volume"`
+	MinLimitOrderVolume  int64 `json:"min_limit_order_volume"`
+	MinMarketOrderVolume int64 `json:"min_market_order_volume"`
+
+	Margin     float64 `json:"margin"`
+	Commission float64 `json:"commission"`
+
+	Expired          bool    `json:"expired"`
+	ExpireDatetime   float64 `json:"expire_datetime"`
+	StrikePrice      float64 `json:"strike_price"`
+	UnderlyingSymbol string  `json:"underlying_symbol"`
+	OptionClass      string  `json:"option_class"`
+
+	TradingDay string `json:"trading_day"`
+}
+
+// Symbol 返回 "交易所.合约" 形式的完整合约代码
+func (s *SymbolInfo) Symbol() string {
+	return s.ExchangeID + "." + s.InstrumentID
+}
+
+const symbolsCacheFile = "tqsdk-go-symbols.json"
+
+// symbolsMemCache 进程内合约信息缓存, 按服务地址共享: 同一进程里的
+// 多个 Client 复用第一次加载的结果, 不重复下载 latest.json.
+// 条目内的 sync.Once 保证并发首次加载只触发一次下载, 其余调用阻塞
+// 等待同一结果; 共享的 map 约定只读, 任何 Client 都不得修改
+var symbolsMemCache = struct {
+	mu      sync.Mutex
+	entries map[string]*symbolsMemEntry
+}{entries: make(map[string]*symbolsMemEntry)}
+
+type symbolsMemEntry struct {
+	once sync.Once
+	info map[string]*SymbolInfo
+	err  error
+}
+
+// symbolsMemEntryFor 取出 (必要时创建) 指定服务地址的缓存条目
+func symbolsMemEntryFor(url string) *symbolsMemEntry {
+	symbolsMemCache.mu.Lock()
+	defer symbolsMemCache.mu.Unlock()
+	entry, ok := symbolsMemCache.entries[url]
+	if !ok {
+		entry = &symbolsMemEntry{}
+		symbolsMemCache.entries[url] = entry
+	}
+	return entry
+}
+
+// loadSymbols 加载全量合约信息: 先查进程内缓存, 未命中时由首个
+// 调用方走 本地缓存文件 -> 网络 的加载链路, 结果对同地址的后续
+// Client 共享. 加载失败不会污染缓存, 条目移除后下次调用重试
+func (c *Client) loadSymbols(ctx context.Context) error {
+	url := SymbolsServerURL
+	entry := symbolsMemEntryFor(url)
+	entry.once.Do(func() {
+		entry.info, entry.err = c.fetchSymbols(ctx, url)
+	})
+	if entry.err != nil {
+		symbolsMemCache.mu.Lock()
+		if symbolsMemCache.entries[url] == entry {
+			delete(symbolsMemCache.entries, url)
+		}
+		symbolsMemCache.mu.Unlock()
+		return entry.err
+	}
+	c.quotesInfo = entry.info
+	return nil
+}
+
+// fetchSymbols 实际执行一次合约信息加载, 优先使用本地缓存文件
+func (c *Client) fetchSymbols(ctx context.Context, url string) (map[string]*SymbolInfo, error) {
+	if info, err := loadSymbolsFromCache(c.cacheDir()); err == nil {
+		return info, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("shinny: fetch symbols failed: %s", resp.Status)
+	}
+	info := make(map[string]*SymbolInfo)
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	if err := saveSymbolsCache(c.cacheDir(), info); err != nil {
+		c.logger.Warn("save symbols cache failed", "error", err)
+	}
+	return info, nil
+}
+
+func (c *Client) cacheDir() string {
+	if c.conf != nil && c.conf.CacheDir != "" {
+		return c.conf.CacheDir
+	}
+	return os.TempDir()
+}
+
+// loadSymbolsFromCache 读取本地缓存, 解析失败视为缓存损坏,
+// 删除后返回错误让调用方回退网络
+func loadSymbolsFromCache(dir string) (map[string]*SymbolInfo, error) {
+	path := filepath.Join(dir, symbolsCacheFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info := make(map[string]*SymbolInfo)
+	if err := json.Unmarshal(data, &info); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("shinny: corrupted symbols cache removed: %w", err)
+	}
+	return info, nil
+}
+
+// saveSymbolsCache 先写进程独有的临时文件再原子 rename 到目标路径,
+// 写入中途崩溃不会留下半截文件, 多进程共享缓存目录也不会互相破坏
+func saveSymbolsCache(dir string, info map[string]*SymbolInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, symbolsCacheFile+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, symbolsCacheFile)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// GetTdUrl 从 files.shinnytech.com 获取期货公司交易服务器地址
+func GetTdUrl(ctx context.Context, broker string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf(BrokersServerURL, broker), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("shinny: fetch broker %s failed: %s", broker, resp.Status)
+	}
+	var body struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.URL == "" {
+		return "", fmt.Errorf("shinny: broker %s has no td url", broker)
+	}
+	return body.URL, nil
+}
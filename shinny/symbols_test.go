@@ -0,0 +1,158 @@
+package shinny
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSymbolsCacheRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	info := map[string]*SymbolInfo{
+		"SHFE.cu2601": {ExchangeID: "SHFE", InstrumentID: "cu2601", PriceTick: 10, VolumeMultiple: 5},
+	}
+	if err := saveSymbolsCache(dir, info); err != nil {
+		t.Fatalf("saveSymbolsCache error: %v", err)
+	}
+	loaded, err := loadSymbolsFromCache(dir)
+	if err != nil {
+		t.Fatalf("loadSymbolsFromCache error: %v", err)
+	}
+	if got := loaded["SHFE.cu2601"]; got == nil || got.PriceTick != 10 {
+		t.Fatalf("loaded = %+v", loaded)
+	}
+	// 目录里不应残留临时文件
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != symbolsCacheFile {
+		t.Fatalf("unexpected cache dir entries: %v", entries)
+	}
+}
+
+func TestCorruptedSymbolsCacheRecovery(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, symbolsCacheFile)
+	// 模拟写到一半崩溃留下的损坏 JSON
+	if err := os.WriteFile(path, []byte(`{"SHFE.cu2601":{"instrument_id":"cu26`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadSymbolsFromCache(dir); err == nil {
+		t.Fatal("expected error on corrupted cache")
+	}
+	// 损坏缓存应被删除, 下次写入后可正常读取
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("corrupted cache not removed, stat err = %v", err)
+	}
+	if err := saveSymbolsCache(dir, map[string]*SymbolInfo{
+		"SHFE.cu2601": {ExchangeID: "SHFE", InstrumentID: "cu2601"},
+	}); err != nil {
+		t.Fatalf("saveSymbolsCache error: %v", err)
+	}
+	if loaded, err := loadSymbolsFromCache(dir); err != nil || len(loaded) != 1 {
+		t.Fatalf("reload after recovery: %v %v", loaded, err)
+	}
+}
+
+func TestSymbolsMemCacheSharedAcrossClients(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprint(w, `{"SHFE.cu2601":{"exchange_id":"SHFE","instrument_id":"cu2601","price_tick":10}}`)
+	}))
+	defer srv.Close()
+
+	orig := SymbolsServerURL
+	SymbolsServerURL = srv.URL
+	defer func() { SymbolsServerURL = orig }()
+
+	// 各 Client 用独立缓存目录, 确保共享只能来自进程内缓存
+	newClient := func() *Client {
+		return NewClient(NewTqAuth("tester", "secret"), &ClientConfig{CacheDir: t.TempDir()})
+	}
+
+	c1, c2 := newClient(), newClient()
+	if err := c1.loadSymbols(context.Background()); err != nil {
+		t.Fatalf("first loadSymbols error: %v", err)
+	}
+	if err := c2.loadSymbols(context.Background()); err != nil {
+		t.Fatalf("second loadSymbols error: %v", err)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("server requests = %d, want 1", n)
+	}
+	// 两个 Client 复用同一份只读数据
+	if c1.quotesInfo["SHFE.cu2601"] == nil || c1.quotesInfo["SHFE.cu2601"] != c2.quotesInfo["SHFE.cu2601"] {
+		t.Fatalf("quotesInfo not shared: %p vs %p", c1.quotesInfo["SHFE.cu2601"], c2.quotesInfo["SHFE.cu2601"])
+	}
+}
+
+func TestSymbolsMemCacheConcurrentFirstLoad(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, `{"DCE.m2601":{"exchange_id":"DCE","instrument_id":"m2601"}}`)
+	}))
+	defer srv.Close()
+
+	orig := SymbolsServerURL
+	SymbolsServerURL = srv.URL
+	defer func() { SymbolsServerURL = orig }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := NewClient(NewTqAuth("tester", "secret"), &ClientConfig{CacheDir: t.TempDir()})
+			if err := c.loadSymbols(context.Background()); err != nil {
+				t.Errorf("loadSymbols error: %v", err)
+				return
+			}
+			if c.quotesInfo["DCE.m2601"] == nil {
+				t.Error("symbol missing after concurrent load")
+			}
+		}()
+	}
+	wg.Wait()
+	if n := requests.Load(); n != 1 {
+		t.Fatalf("server requests = %d, want 1", n)
+	}
+}
+
+func TestSymbolsMemCacheRetriesAfterFailure(t *testing.T) {
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"SHFE.au2612":{"exchange_id":"SHFE","instrument_id":"au2612"}}`)
+	}))
+	defer srv.Close()
+
+	orig := SymbolsServerURL
+	SymbolsServerURL = srv.URL
+	defer func() { SymbolsServerURL = orig }()
+
+	c := NewClient(NewTqAuth("tester", "secret"), &ClientConfig{CacheDir: t.TempDir()})
+	if err := c.loadSymbols(context.Background()); err == nil {
+		t.Fatal("first load should fail")
+	}
+	// 失败不应被缓存, 第二次调用重新下载
+	if err := c.loadSymbols(context.Background()); err != nil {
+		t.Fatalf("retry loadSymbols error: %v", err)
+	}
+	if c.quotesInfo["SHFE.au2612"] == nil {
+		t.Fatal("symbol missing after retry")
+	}
+}
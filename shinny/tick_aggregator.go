@@ -0,0 +1,179 @@
+package shinny
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// TickKlineSubscription 订阅 Tick 并在本地聚合成任意周期的 K线,
+// 用于服务端不提供的周期 (如 3 秒线)
+type TickKlineSubscription struct {
+	symbol   string
+	duration time.Duration
+	tickSub  *SeriesSubscription
+
+	mu   sync.Mutex
+	bars []*Kline
+	// lastTickID 已聚合到的 tick id, 避免重复消费
+	lastTickID int64
+	// lastVolume/lastOI 上一笔 tick 的当日累计值, 相邻差分得到单笔增量
+	lastVolume int64
+	lastOI     int64
+	// seenTick 是否已消费过 tick, 首笔 tick 无法差分, 增量按 0 计
+	seenTick bool
+
+	newBarFns    []func(*KlineSeriesData)
+	barUpdateFns []func(*KlineSeriesData)
+	closed       bool
+
+	logger *slog.Logger
+}
+
+// KlineFromTick 订阅 Tick 并按 duration 在本地合成 K线.
+// bar 边界按自然时间对 duration 取整划分, 与交易时段无关:
+// duration 不能整除交易时段或夜盘跨日时, 边界 bar 按实际覆盖的
+// tick 聚合, 不做补齐
+func (api *SeriesAPI) KlineFromTick(ctx context.Context, symbol string, duration time.Duration, viewWidth int) (*TickKlineSubscription, error) {
+	if duration <= 0 {
+		return nil, fmt.Errorf("shinny: invalid kline duration %v", duration)
+	}
+	tickSub, err := api.Tick(ctx, symbol, viewWidth)
+	if err != nil {
+		return nil, err
+	}
+	tk := &TickKlineSubscription{
+		symbol:   symbol,
+		duration: duration,
+		tickSub:  tickSub,
+		logger:   api.client.logger,
+	}
+	tickSub.OnNewTick(tk.feedTicks)
+	return tk, nil
+}
+
+// feedTicks 消费一批 tick 序列快照, 把未见过的 tick 聚合进 bar
+func (tk *TickKlineSubscription) feedTicks(data *TickSeriesData) {
+	tk.mu.Lock()
+	if tk.closed {
+		tk.mu.Unlock()
+		return
+	}
+	newBars := 0
+	updated := false
+	for _, t := range data.Ticks {
+		if t.ID <= tk.lastTickID && tk.seenTick {
+			continue
+		}
+		volDelta, oiDelta := int64(0), int64(0)
+		if tk.seenTick {
+			volDelta = t.Volume - tk.lastVolume
+			oiDelta = t.OpenInterest - tk.lastOI
+			if volDelta < 0 {
+				// 跨交易日累计量重置
+				volDelta = t.Volume
+			}
+		}
+		barStart := t.Datetime - t.Datetime%tk.duration.Nanoseconds()
+		cur := tk.currentBarLocked()
+		if cur == nil || cur.Datetime != barStart {
+			tk.bars = append(tk.bars, &Kline{
+				ID:       barStart / tk.duration.Nanoseconds(),
+				Datetime: barStart,
+				Open:     t.LastPrice,
+				High:     t.LastPrice,
+				Low:      t.LastPrice,
+				Close:    t.LastPrice,
+				Volume:   volDelta,
+				OpenOI:   t.OpenInterest - oiDelta,
+				CloseOI:  t.OpenInterest,
+			})
+			newBars++
+		} else {
+			if t.LastPrice > cur.High {
+				cur.High = t.LastPrice
+			}
+			if t.LastPrice < cur.Low {
+				cur.Low = t.LastPrice
+			}
+			cur.Close = t.LastPrice
+			cur.Volume += volDelta
+			cur.CloseOI = t.OpenInterest
+		}
+		tk.lastTickID = t.ID
+		tk.lastVolume = t.Volume
+		tk.lastOI = t.OpenInterest
+		tk.seenTick = true
+		updated = true
+	}
+	snapshot := tk.seriesLocked()
+	newBarFns := append([]func(*KlineSeriesData){}, tk.newBarFns...)
+	updateFns := append([]func(*KlineSeriesData){}, tk.barUpdateFns...)
+	tk.mu.Unlock()
+
+	if newBars > 0 {
+		for _, h := range newBarFns {
+			go h(snapshot)
+		}
+	}
+	if updated {
+		for _, h := range updateFns {
+			go h(snapshot)
+		}
+	}
+}
+
+func (tk *TickKlineSubscription) currentBarLocked() *Kline {
+	if len(tk.bars) == 0 {
+		return nil
+	}
+	return tk.bars[len(tk.bars)-1]
+}
+
+func (tk *TickKlineSubscription) seriesLocked() *KlineSeriesData {
+	data := &KlineSeriesData{Symbol: tk.symbol, Duration: tk.duration}
+	for _, b := range tk.bars {
+		c := *b
+		data.Klines = append(data.Klines, &c)
+	}
+	if n := len(tk.bars); n > 0 {
+		data.LastID = tk.bars[n-1].ID
+	}
+	return data
+}
+
+// KlineSeries 返回当前合成的 K线序列快照
+func (tk *TickKlineSubscription) KlineSeries() *KlineSeriesData {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	return tk.seriesLocked()
+}
+
+// OnNewBar 注册新 bar 回调
+func (tk *TickKlineSubscription) OnNewBar(handler func(*KlineSeriesData)) {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	tk.newBarFns = append(tk.newBarFns, handler)
+}
+
+// OnBarUpdate 注册 bar 更新回调
+func (tk *TickKlineSubscription) OnBarUpdate(handler func(*KlineSeriesData)) {
+	tk.mu.Lock()
+	defer tk.mu.Unlock()
+	tk.barUpdateFns = append(tk.barUpdateFns, handler)
+}
+
+// WaitSync 等待底层 Tick 订阅的历史数据下载完成
+func (tk *TickKlineSubscription) WaitSync(ctx context.Context) error {
+	return tk.tickSub.WaitSync(ctx)
+}
+
+// Close 取消底层 Tick 订阅
+func (tk *TickKlineSubscription) Close() error {
+	tk.mu.Lock()
+	tk.closed = true
+	tk.mu.Unlock()
+	return tk.tickSub.Close()
+}
@@ -0,0 +1,1367 @@
+package shinny
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 订单方向 / 开平 / 价格类型 / 状态, 取值与 DIFF 协议一致
+const (
+	DirectionBuy  = "BUY"
+	DirectionSell = "SELL"
+
+	OffsetOpen       = "OPEN"
+	OffsetClose      = "CLOSE"
+	OffsetCloseToday = "CLOSETODAY"
+
+	PriceTypeLimit = "LIMIT"
+	PriceTypeAny   = "ANY"
+
+	StatusAlive    = "ALIVE"
+	StatusFinished = "FINISHED"
+)
+
+// Order 委托单
+type Order struct {
+	OrderID         string  `json:"order_id"`
+	ExchangeOrderID string  `json:"exchange_order_id"`
+	ExchangeID      string  `json:"exchange_id"`
+	InstrumentID    string  `json:"instrument_id"`
+	Direction       string  `json:"direction"`
+	Offset          string  `json:"offset"`
+	VolumeOrign     int64   `json:"volume_orign"`
+	VolumeLeft      int64   `json:"volume_left"`
+	LimitPrice      float64 `json:"limit_price"`
+	PriceType       string  `json:"price_type"`
+	VolumeCondition string  `json:"volume_condition"`
+	TimeCondition   string  `json:"time_condition"`
+	InsertDateTime  int64   `json:"insert_date_time"`
+	Status          string  `json:"status"`
+	LastMsg         string  `json:"last_msg"`
+}
+
+// Symbol 返回 "交易所.合约" 形式的合约代码, 与行情订阅使用的代码一致
+func (o *Order) Symbol() string {
+	return joinSymbol(o.ExchangeID, o.InstrumentID)
+}
+
+// OrderDerivedStatus 细分订单状态: DIFF 协议的 status 只有
+// ALIVE/FINISHED 两态, 这里结合 volume_left 与 last_msg 推导出
+// 策略分流需要的五态
+type OrderDerivedStatus string
+
+const (
+	// OrderSubmitted 已报入, 尚无成交
+	OrderSubmitted OrderDerivedStatus = "SUBMITTED"
+	// OrderPartiallyFilled 部分成交, 剩余仍在挂
+	OrderPartiallyFilled OrderDerivedStatus = "PARTIALLY_FILLED"
+	// OrderFilled 全部成交
+	OrderFilled OrderDerivedStatus = "FILLED"
+	// OrderCancelled 未全成即撤单结束 (含主动撤单与 IOC/FOK 自动撤单)
+	OrderCancelled OrderDerivedStatus = "CANCELLED"
+	// OrderRejected 被柜台或交易所拒绝
+	OrderRejected OrderDerivedStatus = "REJECTED"
+)
+
+// orderRejectKeywords last_msg 中标识拒单的关键词, 柜台实现各异,
+// 按常见措辞匹配
+var orderRejectKeywords = []string{"拒绝", "废单", "错误", "不足", "不合法", "超过", "reject"}
+
+// DerivedStatus 推导细分订单状态. FINISHED 且有剩余时通过 last_msg
+// 区分撤单与拒单: 含 "撤" 判为撤单, 命中拒单关键词判为拒单,
+// 两者都不命中时按撤单处理 (柜台撤单回报措辞最多样)
+func (o *Order) DerivedStatus() OrderDerivedStatus {
+	if o.Status == StatusAlive {
+		if o.VolumeLeft < o.VolumeOrign {
+			return OrderPartiallyFilled
+		}
+		return OrderSubmitted
+	}
+	if o.VolumeLeft == 0 {
+		return OrderFilled
+	}
+	if strings.Contains(o.LastMsg, "撤") {
+		return OrderCancelled
+	}
+	msg := strings.ToLower(o.LastMsg)
+	for _, kw := range orderRejectKeywords {
+		if strings.Contains(msg, kw) {
+			return OrderRejected
+		}
+	}
+	return OrderCancelled
+}
+
+// Trade 成交记录
+type Trade struct {
+	TradeID       string  `json:"trade_id"`
+	OrderID       string  `json:"order_id"`
+	ExchangeID    string  `json:"exchange_id"`
+	InstrumentID  string  `json:"instrument_id"`
+	Direction     string  `json:"direction"`
+	Offset        string  `json:"offset"`
+	Price         float64 `json:"price"`
+	Volume        int64   `json:"volume"`
+	TradeDateTime int64   `json:"trade_date_time"`
+	Commission    float64 `json:"commission"`
+}
+
+// Symbol 返回 "交易所.合约" 形式的合约代码
+func (t *Trade) Symbol() string {
+	return joinSymbol(t.ExchangeID, t.InstrumentID)
+}
+
+// Position 持仓
+type Position struct {
+	ExchangeID   string `json:"exchange_id"`
+	InstrumentID string `json:"instrument_id"`
+
+	VolumeLongToday  int64 `json:"volume_long_today"`
+	VolumeLongHis    int64 `json:"volume_long_his"`
+	VolumeLong       int64 `json:"volume_long"`
+	VolumeShortToday int64 `json:"volume_short_today"`
+	VolumeShortHis   int64 `json:"volume_short_his"`
+	VolumeShort      int64 `json:"volume_short"`
+
+	VolumeLongFrozen  int64 `json:"volume_long_frozen"`
+	VolumeShortFrozen int64 `json:"volume_short_frozen"`
+
+	OpenPriceLong      float64 `json:"open_price_long"`
+	OpenPriceShort     float64 `json:"open_price_short"`
+	PositionPriceLong  float64 `json:"position_price_long"`
+	PositionPriceShort float64 `json:"position_price_short"`
+
+	FloatProfitLong  float64 `json:"float_profit_long"`
+	FloatProfitShort float64 `json:"float_profit_short"`
+	FloatProfit      float64 `json:"float_profit"`
+
+	MarginLong  float64 `json:"margin_long"`
+	MarginShort float64 `json:"margin_short"`
+	Margin      float64 `json:"margin"`
+
+	LastPrice float64 `json:"last_price"`
+}
+
+// Symbol 返回 "交易所.合约" 形式的合约代码
+func (p *Position) Symbol() string {
+	return joinSymbol(p.ExchangeID, p.InstrumentID)
+}
+
+// RecalcFloatProfit 用最新价重算持仓浮盈: 服务端浮盈随交易推送更新,
+// 频率低于行情, 本地重算可得到逐笔行情级别的实时浮盈
+func (p *Position) RecalcFloatProfit(lastPrice, volumeMultiple float64) {
+	p.LastPrice = lastPrice
+	p.FloatProfitLong = (lastPrice - p.OpenPriceLong) * float64(p.VolumeLong) * volumeMultiple
+	p.FloatProfitShort = (p.OpenPriceShort - lastPrice) * float64(p.VolumeShort) * volumeMultiple
+	p.FloatProfit = p.FloatProfitLong + p.FloatProfitShort
+}
+
+// Account 资金账户
+type Account struct {
+	CurrencyID       string  `json:"currency"`
+	PreBalance       float64 `json:"pre_balance"`
+	StaticBalance    float64 `json:"static_balance"`
+	Balance          float64 `json:"balance"`
+	Available        float64 `json:"available"`
+	FloatProfit      float64 `json:"float_profit"`
+	PositionProfit   float64 `json:"position_profit"`
+	CloseProfit      float64 `json:"close_profit"`
+	Margin           float64 `json:"margin"`
+	FrozenMargin     float64 `json:"frozen_margin"`
+	FrozenCommission float64 `json:"frozen_commission"`
+	Commission       float64 `json:"commission"`
+	Deposit          float64 `json:"deposit"`
+	Withdraw         float64 `json:"withdraw"`
+	RiskRatio        float64 `json:"risk_ratio"`
+}
+
+// Notification 服务端通知
+type Notification struct {
+	Type    string `json:"type"`
+	Level   string `json:"level"`
+	Code    int64  `json:"code"`
+	Content string `json:"content"`
+}
+
+// 通知级别
+const (
+	NotifyLevelInfo    = "INFO"
+	NotifyLevelWarning = "WARNING"
+	NotifyLevelError   = "ERROR"
+)
+
+// InsertOrderRequest 下单请求
+type InsertOrderRequest struct {
+	Symbol     string
+	Direction  string
+	Offset     string
+	Volume     int64
+	PriceType  string
+	LimitPrice float64
+	// TTL 客户端侧订单有效期: > 0 时超时仍未结束的挂单自动撤单,
+	// 0 表示不自动撤. 定时器在本地维护, 不随报文上传
+	TTL time.Duration
+}
+
+// Trader 交易接口, 由 TradeSession(实盘) 与 VirtualTrader(模拟) 实现
+type Trader interface {
+	InsertOrder(ctx context.Context, req *InsertOrderRequest) (*Order, error)
+	InsertOrders(ctx context.Context, reqs []*InsertOrderRequest, opts ...BatchOption) ([]*Order, []error)
+	CancelOrder(ctx context.Context, orderID string) error
+	GetAccount(ctx context.Context) (*Account, error)
+	GetPositions(ctx context.Context) (map[string]*Position, error)
+	GetPositionsByProduct(ctx context.Context) (map[string]*AggregatedPosition, error)
+	GetOrders(ctx context.Context) (map[string]*Order, error)
+	GetFrozenVolume(ctx context.Context, symbol, direction string) (int64, error)
+	GetMarginBreakdown(ctx context.Context) (map[string]float64, error)
+	GetTrades(ctx context.Context) (map[string]*Trade, error)
+	GetTradesByOrder(ctx context.Context, orderID string) ([]*Trade, float64, error)
+	GetTradeSummary(ctx context.Context) (*TradeSummary, error)
+	OnOrder(handler func(*Order))
+	OnTrade(handler func(*Trade))
+	OnAccount(handler func(*Account))
+	OnNotification(handler func(*Notification)) (unregister func())
+	OnRiskAlert(threshold float64, handler func(*Account))
+	EnableEquityCurve(interval time.Duration)
+	GetEquityCurve() []EquityPoint
+	Close() error
+}
+
+// riskAlert 风险度预警注册项, 越过阈值只触发一次,
+// 回落到阈值 95% 以下后重新武装, 避免临界抖动反复触发
+type riskAlert struct {
+	threshold float64
+	handler   func(*Account)
+	fired     bool
+}
+
+func (r *riskAlert) shouldFire(ratio float64) bool {
+	if r.fired {
+		if ratio < r.threshold*0.95 {
+			r.fired = false
+		}
+		return false
+	}
+	if ratio >= r.threshold {
+		r.fired = true
+		return true
+	}
+	return false
+}
+
+var (
+	ErrOrderNotFound      = errors.New("shinny: order not found")
+	ErrNotLoggedIn        = errors.New("shinny: trade session not logged in")
+	ErrOrderAlreadyFilled = errors.New("shinny: order already fully filled")
+	ErrRateLimited        = errors.New("shinny: order rate limit exceeded")
+	ErrReadOnly           = errors.New("shinny: trade session is read-only")
+)
+
+// ContextError 带上下文的结构化错误: Op 标识出错的操作或回调,
+// Symbol/OrderID 在与具体合约或订单相关时填写. OnError 回调可以
+// errors.As 取出后按字段定位问题来源
+type ContextError struct {
+	Op      string
+	Symbol  string
+	OrderID string
+	Err     error
+}
+
+func (e *ContextError) Error() string {
+	var b strings.Builder
+	b.WriteString("shinny: ")
+	b.WriteString(e.Op)
+	if e.Symbol != "" || e.OrderID != "" {
+		b.WriteString(" (")
+		if e.Symbol != "" {
+			b.WriteString("symbol=" + e.Symbol)
+		}
+		if e.OrderID != "" {
+			if e.Symbol != "" {
+				b.WriteString(", ")
+			}
+			b.WriteString("order_id=" + e.OrderID)
+		}
+		b.WriteString(")")
+	}
+	if e.Err != nil {
+		b.WriteString(": " + e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *ContextError) Unwrap() error { return e.Err }
+
+// tokenBucket 令牌桶限流器, 按秒匀速补充令牌
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	// capacity 桶容量, 等于每秒补充速率 rate
+	capacity float64
+	rate     float64
+	last     time.Time
+	// now 可替换的时钟, 便于测试
+	now func() time.Time
+}
+
+func newTokenBucket(perSecond int) *tokenBucket {
+	b := &tokenBucket{
+		tokens:   float64(perSecond),
+		capacity: float64(perSecond),
+		rate:     float64(perSecond),
+		now:      time.Now,
+	}
+	b.last = b.now()
+	return b
+}
+
+// take 取走一个令牌, 不足时返回 false 与距下一个令牌的等待时间
+func (b *tokenBucket) take() (ok bool, wait time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// TradeSession 单个交易账户会话, 实现 Trader
+type TradeSession struct {
+	ws     *TqTradeWebsocket
+	dm     *DataManager
+	logger *slog.Logger
+
+	broker string
+	userID string
+
+	mu sync.Mutex
+
+	orderHandlers   []func(*Order)
+	rejectHandlers  []func(*Order, string)
+	tradeHandlers   []func(*Trade)
+	accountHandlers []func(*Account)
+	notifyHandlers  []notifyHandler
+	notifyHandlerID int64
+	errorHandlers   []func(error)
+	riskAlerts      []*riskAlert
+
+	// seenTrades / seenNotify 记录已经推送过的 id, 避免重复回调
+	seenTrades map[string]bool
+	seenNotify map[string]bool
+	// orderEpochs 记录订单内容摘要, 用于判断订单是否有更新
+	orderSnapshot map[string]Order
+	// orderEventChans 各订单的生命周期事件通道, 订单结束后关闭
+	orderEventChans map[string][]chan *OrderEvent
+	// ttlTimers 各订单的 TTL 自动撤单定时器, 订单结束或会话关闭时停止
+	ttlTimers map[string]*time.Timer
+
+	// failFastOrders 为 true 时下单/撤单在断线期间不排队直接失败
+	failFastOrders bool
+	// readOnly 观察模式, 下单/撤单直接返回 ErrReadOnly
+	readOnly bool
+	// autoConfirmSettle 检测到待确认结算单时自动发送 confirm_settlement
+	autoConfirmSettle bool
+	// settleConfirmedDay 记录已自动确认过的交易日, 每个交易日只确认一次
+	settleConfirmedDay string
+
+	// orderIDPrefix 本会话生成 order_id 的前缀, 多策略实例共用账户时用于区分来源
+	orderIDPrefix string
+	orderSeq      atomic.Int64
+
+	// orderLimiter 报撤单限流器, 未配置时不限流
+	orderLimiter *tokenBucket
+
+	// audit 下单/撤单审计日志, 未配置时不记录
+	audit AuditLogger
+
+	// symbolInfo / quoteSnapshot 由 Client 登录时注入, 市价单
+	// 保护性转限价时用于判断合约是否支持市价与读取对手价
+	symbolInfo    func(symbol string) *SymbolInfo
+	quoteSnapshot func(symbol string) (*Quote, error)
+	// resolveSymbol 合约别名解析, 由 Client 注入, 见 RegisterAlias
+	resolveSymbol func(symbol string) string
+	// validateVolume 下单量校验函数, 由 Client 注入;
+	// volumeCheck 为 true 时 InsertOrder 发单前调用
+	validateVolume func(symbol string, volume int64) error
+	volumeCheck    bool
+
+	// equity 权益曲线采样器
+	equity equityRecorder
+
+	closed bool
+	cancel context.CancelFunc
+}
+
+// TradeOption 交易会话选项
+type TradeOption func(*TradeSession)
+
+// WithFailFastOrders 下单/撤单等时效性报文在断线时不排队, 直接返回
+// ErrNotConnected
+func WithFailFastOrders() TradeOption {
+	return func(s *TradeSession) {
+		s.failFastOrders = true
+	}
+}
+
+// WithReadOnly 以观察模式建立会话: 查询与回调正常, InsertOrder/
+// CancelOrder 不发报文直接返回 ErrReadOnly, 避免只读账户误下单
+func WithReadOnly() TradeOption {
+	return func(s *TradeSession) {
+		s.readOnly = true
+	}
+}
+
+// WithAutoConfirmSettlement 登录后检测到待确认结算单时自动发送
+// confirm_settlement 报文, 免去每个交易日首次登录的手工确认,
+// 确认动作会以一条 INFO 级 Notification 推送给 OnNotification
+func WithAutoConfirmSettlement() TradeOption {
+	return func(s *TradeSession) {
+		s.autoConfirmSettle = true
+	}
+}
+
+// WithAuditLogger 启用下单审计: 每笔 InsertOrder/CancelOrder 的请求
+// 参数与结果写入 logger, 满足合规留痕要求
+func WithAuditLogger(logger AuditLogger) TradeOption {
+	return func(s *TradeSession) {
+		s.audit = logger
+	}
+}
+
+// WithOrderRateLimit 启用报撤单限流 (每秒最多 perSecond 次), 避免触发
+// 交易所报撤单频率风控. 超频时 InsertOrder/CancelOrder 默认排队等待令牌,
+// 配合 WithFailFastOrders 则立即返回 ErrRateLimited
+func WithOrderRateLimit(perSecond int) TradeOption {
+	return func(s *TradeSession) {
+		s.orderLimiter = newTokenBucket(perSecond)
+	}
+}
+
+// WithVolumeValidation 下单前按合约表校验下单量 (ValidateVolume),
+// 不满足最小/最大限价单手数限制的请求在发出报文前即返回错误
+func WithVolumeValidation() TradeOption {
+	return func(s *TradeSession) {
+		s.volumeCheck = true
+	}
+}
+
+// WithOrderIDPrefix 设置本会话生成 order_id 的前缀, 默认 "TQGO_"
+func WithOrderIDPrefix(prefix string) TradeOption {
+	return func(s *TradeSession) {
+		s.orderIDPrefix = prefix
+	}
+}
+
+func newTradeSession(ws *TqTradeWebsocket, dm *DataManager, broker, userID string, logger *slog.Logger, opts ...TradeOption) *TradeSession {
+	s := &TradeSession{
+		ws:              ws,
+		dm:              dm,
+		logger:          logger,
+		broker:          broker,
+		userID:          userID,
+		seenTrades:      make(map[string]bool),
+		seenNotify:      make(map[string]bool),
+		orderSnapshot:   make(map[string]Order),
+		orderEventChans: make(map[string][]chan *OrderEvent),
+		ttlTimers:       make(map[string]*time.Timer),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	dm.OnData(s.onData)
+	return s
+}
+
+// path 返回该账户在截面里的业务路径 trade.<user_id>....
+func (s *TradeSession) path(sub ...string) []string {
+	return append([]string{"trade", s.userID}, sub...)
+}
+
+// onData 截面变更后检查订单/成交/账户/通知并分发回调
+func (s *TradeSession) onData() {
+	if s.dm.IsChanging(s.path("orders")...) {
+		if orders, err := s.readOrders(); err == nil {
+			type orderChange struct {
+				order *Order
+				prev  Order
+				seen  bool
+			}
+			s.mu.Lock()
+			var changed []orderChange
+			for id, o := range orders {
+				if prev, ok := s.orderSnapshot[id]; !ok || prev != *o {
+					s.orderSnapshot[id] = *o
+					changed = append(changed, orderChange{order: o, prev: prev, seen: ok})
+				}
+			}
+			handlers := append([]func(*Order){}, s.orderHandlers...)
+			rejectHandlers := append([]func(*Order, string){}, s.rejectHandlers...)
+			s.mu.Unlock()
+			for _, c := range changed {
+				s.emitOrder(handlers, c.order)
+				if isOrderRejected(c.order) {
+					s.emitOrderRejected(rejectHandlers, c.order)
+				}
+				s.dispatchOrderEvent(c.order, c.prev, c.seen)
+			}
+		}
+	}
+	if s.dm.IsChanging(s.path("trades")...) {
+		if trades, err := s.readTrades(); err == nil {
+			s.mu.Lock()
+			var fresh []*Trade
+			for id, t := range trades {
+				if !s.seenTrades[id] {
+					s.seenTrades[id] = true
+					fresh = append(fresh, t)
+				}
+			}
+			handlers := append([]func(*Trade){}, s.tradeHandlers...)
+			s.mu.Unlock()
+			for _, t := range fresh {
+				s.emitTrade(handlers, t)
+			}
+		}
+	}
+	if s.dm.IsChanging(s.path("accounts")...) {
+		if acc, err := s.readAccount(); err == nil {
+			s.mu.Lock()
+			handlers := append([]func(*Account){}, s.accountHandlers...)
+			var alerts []func(*Account)
+			for _, r := range s.riskAlerts {
+				if r.shouldFire(acc.RiskRatio) {
+					alerts = append(alerts, r.handler)
+				}
+			}
+			s.mu.Unlock()
+			for _, h := range handlers {
+				h := h
+				s.safeGo("account", "", "", func() { h(acc) })
+			}
+			for _, h := range alerts {
+				h := h
+				s.safeGo("risk_alert", "", "", func() { h(acc) })
+			}
+		}
+	}
+	if s.dm.IsChanging("notify") {
+		s.dispatchNotify()
+	}
+	if s.autoConfirmSettle && s.dm.IsChanging(s.path("session")...) {
+		s.maybeConfirmSettlement()
+	}
+}
+
+// maybeConfirmSettlement 检查会话节点的结算单确认状态, 发现待确认
+// 且本交易日还未自动确认过时发送 confirm_settlement, 并推送一条
+// INFO 级通知留痕
+func (s *TradeSession) maybeConfirmSettlement() {
+	raw, ok := s.dm.GetByPath(s.path("session")...)
+	if !ok {
+		return
+	}
+	sess, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+	confirmed, ok := sess["settlement_confirmed"].(bool)
+	if !ok || confirmed {
+		return
+	}
+	tradingDay, _ := sess["trading_day"].(string)
+	s.mu.Lock()
+	if s.settleConfirmedDay == tradingDay {
+		s.mu.Unlock()
+		return
+	}
+	s.settleConfirmedDay = tradingDay
+	handlers := notifyFns(s.notifyHandlers)
+	s.mu.Unlock()
+
+	s.ws.Send(map[string]interface{}{"aid": "confirm_settlement"})
+	s.logger.Info("settlement auto confirmed", "trading_day", tradingDay)
+	n := &Notification{
+		Type:    "SETTLEMENT",
+		Level:   NotifyLevelInfo,
+		Content: "结算单已自动确认, 交易日 " + tradingDay,
+	}
+	for _, h := range handlers {
+		h := h
+		s.safeGo("notification", "", "", func() { h(n) })
+	}
+}
+
+// safeGo 异步执行用户回调并捕获 panic: 记录堆栈日志并通过 OnError
+// 上报结构化的 ContextError, 保证单个回调崩溃不影响数据流.
+// symbol/orderID 在回调与具体合约或订单相关时传入, 否则留空
+func (s *TradeSession) safeGo(name, symbol, orderID string, fn func()) {
+	go func() {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			s.logger.Error("trade callback panic",
+				"callback", name, "symbol", symbol, "order_id", orderID,
+				"panic", r, "stack", string(debug.Stack()))
+			s.mu.Lock()
+			handlers := append([]func(error){}, s.errorHandlers...)
+			s.mu.Unlock()
+			err := &ContextError{
+				Op:      name + " callback",
+				Symbol:  symbol,
+				OrderID: orderID,
+				Err:     fmt.Errorf("panic: %v", r),
+			}
+			for _, h := range handlers {
+				func() {
+					defer func() { recover() }()
+					h(err)
+				}()
+			}
+		}()
+		fn()
+	}()
+}
+
+func (s *TradeSession) emitOrder(handlers []func(*Order), o *Order) {
+	for _, h := range handlers {
+		h := h
+		s.safeGo("order", o.Symbol(), o.OrderID, func() { h(o) })
+	}
+}
+
+// isOrderRejected 判断订单是否未全成即结束 (被撤单或被交易所拒绝)
+func isOrderRejected(o *Order) bool {
+	return o.Status == StatusFinished && o.VolumeLeft > 0
+}
+
+func (s *TradeSession) emitOrderRejected(handlers []func(*Order, string), o *Order) {
+	for _, h := range handlers {
+		h := h
+		s.safeGo("order_rejected", o.Symbol(), o.OrderID, func() { h(o, o.LastMsg) })
+	}
+}
+
+// OrderEvent 订单生命周期事件, 每次订单状态变化推送一条
+type OrderEvent struct {
+	OrderID string
+	// Timestamp 事件在本地被观测到的时间
+	Timestamp time.Time
+	Status    string
+	// VolumeLeft 事件发生后的未成交手数
+	VolumeLeft int64
+	// Message 变化描述, 如 报单 / 部分成交 / 全部成交 / 撤单或拒单
+	Message string
+}
+
+// OrderEvents 返回一笔订单的生命周期事件通道: 报单 -> 部成 ->
+// 全成/撤单逐事件推送, 订单结束后通道关闭. 应在下单前或下单后
+// 立即调用, 已结束的订单返回已关闭的通道
+func (s *TradeSession) OrderEvents(orderID string) <-chan *OrderEvent {
+	ch := make(chan *OrderEvent, 16)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if prev, ok := s.orderSnapshot[orderID]; ok && prev.Status == StatusFinished {
+		close(ch)
+		return ch
+	}
+	s.orderEventChans[orderID] = append(s.orderEventChans[orderID], ch)
+	return ch
+}
+
+// describeOrderChange 生成订单状态变化的可读描述
+func describeOrderChange(o *Order, prev Order, seen bool) string {
+	switch {
+	case o.Status == StatusFinished && o.VolumeLeft == 0:
+		return "全部成交"
+	case o.Status == StatusFinished:
+		if o.LastMsg != "" {
+			return "撤单或拒单: " + o.LastMsg
+		}
+		return "撤单或拒单"
+	case !seen:
+		return "报单"
+	case o.VolumeLeft < prev.VolumeLeft:
+		return "部分成交"
+	}
+	return "订单更新"
+}
+
+// scheduleTTLCancel 为订单启动 TTL 定时器, 到期时挂单仍未结束则
+// 自动撤单; 订单先行结束或会话关闭时定时器被停止
+func (s *TradeSession) scheduleTTLCancel(orderID string, ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.ttlTimers[orderID] = time.AfterFunc(ttl, func() {
+		s.mu.Lock()
+		delete(s.ttlTimers, orderID)
+		if o, ok := s.orderSnapshot[orderID]; ok && o.Status == StatusFinished {
+			s.mu.Unlock()
+			return
+		}
+		s.mu.Unlock()
+		s.logger.Info("order ttl expired, cancelling", "order_id", orderID, "ttl", ttl)
+		if err := s.CancelOrder(context.Background(), orderID); err != nil {
+			s.logger.Warn("ttl auto cancel failed", "order_id", orderID, "error", err)
+		}
+	})
+}
+
+// dispatchOrderEvent 向订单事件通道推送一条变化, 订单结束后关闭通道
+func (s *TradeSession) dispatchOrderEvent(o *Order, prev Order, seen bool) {
+	event := &OrderEvent{
+		OrderID:    o.OrderID,
+		Timestamp:  time.Now(),
+		Status:     o.Status,
+		VolumeLeft: o.VolumeLeft,
+		Message:    describeOrderChange(o, prev, seen),
+	}
+	s.mu.Lock()
+	chans := append([]chan *OrderEvent(nil), s.orderEventChans[o.OrderID]...)
+	finished := o.Status == StatusFinished
+	if finished {
+		delete(s.orderEventChans, o.OrderID)
+		if timer := s.ttlTimers[o.OrderID]; timer != nil {
+			timer.Stop()
+			delete(s.ttlTimers, o.OrderID)
+		}
+	}
+	s.mu.Unlock()
+	for _, ch := range chans {
+		select {
+		case ch <- event:
+		default:
+		}
+		if finished {
+			close(ch)
+		}
+	}
+}
+
+func (s *TradeSession) emitTrade(handlers []func(*Trade), t *Trade) {
+	for _, h := range handlers {
+		h := h
+		s.safeGo("trade", t.Symbol(), t.OrderID, func() { h(t) })
+	}
+}
+
+func (s *TradeSession) dispatchNotify() {
+	raw, ok := s.dm.Get("notify")
+	if !ok {
+		return
+	}
+	notifies, ok := raw.(map[string]interface{})
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	var fresh []*Notification
+	for id, v := range notifies {
+		if s.seenNotify[id] {
+			continue
+		}
+		s.seenNotify[id] = true
+		n := &Notification{}
+		if err := ConvertToStruct(v, n); err == nil {
+			fresh = append(fresh, n)
+		}
+	}
+	handlers := notifyFns(s.notifyHandlers)
+	s.mu.Unlock()
+	for _, n := range fresh {
+		n := n
+		for _, h := range handlers {
+			h := h
+			s.safeGo("notification", "", "", func() { h(n) })
+		}
+	}
+}
+
+// InsertOrder 下单, 返回本地生成的委托单
+func (s *TradeSession) InsertOrder(ctx context.Context, req *InsertOrderRequest) (o *Order, err error) {
+	defer func() {
+		e := &AuditEntry{
+			Action:     AuditActionInsert,
+			Symbol:     req.Symbol,
+			Direction:  req.Direction,
+			Offset:     req.Offset,
+			PriceType:  req.PriceType,
+			LimitPrice: req.LimitPrice,
+			Volume:     req.Volume,
+			Result:     auditResult(err),
+		}
+		if o != nil {
+			e.OrderID = o.OrderID
+			e.PriceType = o.PriceType
+			e.LimitPrice = o.LimitPrice
+		}
+		s.auditLog(e)
+	}()
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+	if err := s.acquireOrderToken(ctx); err != nil {
+		return nil, err
+	}
+	if s.resolveSymbol != nil {
+		if resolved := s.resolveSymbol(req.Symbol); resolved != req.Symbol {
+			cp := *req
+			cp.Symbol = resolved
+			req = &cp
+		}
+	}
+	if s.volumeCheck && s.validateVolume != nil {
+		if err := s.validateVolume(req.Symbol, req.Volume); err != nil {
+			return nil, err
+		}
+	}
+	exchangeID, instrumentID := splitSymbol(req.Symbol)
+	priceType := req.PriceType
+	limitPrice := req.LimitPrice
+	if priceType == PriceTypeAny {
+		converted, price, err := s.convertMarketOrder(req)
+		if err != nil {
+			return nil, err
+		}
+		if converted {
+			priceType = PriceTypeLimit
+			limitPrice = price
+		}
+	}
+	orderID := s.nextOrderID()
+	pkt := map[string]interface{}{
+		"aid":              "insert_order",
+		"user_id":          s.userID,
+		"order_id":         orderID,
+		"exchange_id":      exchangeID,
+		"instrument_id":    instrumentID,
+		"direction":        req.Direction,
+		"offset":           req.Offset,
+		"volume":           req.Volume,
+		"price_type":       priceType,
+		"volume_condition": "ANY",
+		"time_condition":   "GFD",
+	}
+	if priceType == PriceTypeLimit {
+		pkt["limit_price"] = limitPrice
+	}
+	if err := s.sendOrderPacket(ctx, pkt); err != nil {
+		return nil, err
+	}
+	if req.TTL > 0 {
+		s.scheduleTTLCancel(orderID, req.TTL)
+	}
+	return &Order{
+		OrderID:      orderID,
+		ExchangeID:   exchangeID,
+		InstrumentID: instrumentID,
+		Direction:    req.Direction,
+		Offset:       req.Offset,
+		VolumeOrign:  req.Volume,
+		VolumeLeft:   req.Volume,
+		LimitPrice:   limitPrice,
+		PriceType:    priceType,
+		Status:       StatusAlive,
+	}, nil
+}
+
+// convertMarketOrder 判断市价单是否需要保护性转限价: 合约不支持市价
+// (max_market_order_volume == 0) 时取行情对手价外加两跳作为限价,
+// 行情不可用时返回错误. 合约信息缺失或支持市价时原样放行
+func (s *TradeSession) convertMarketOrder(req *InsertOrderRequest) (bool, float64, error) {
+	if s.symbolInfo == nil {
+		return false, 0, nil
+	}
+	info := s.symbolInfo(req.Symbol)
+	if info == nil || info.MaxMarketOrderVolume != 0 {
+		return false, 0, nil
+	}
+	if s.quoteSnapshot == nil {
+		return false, 0, fmt.Errorf("shinny: %s does not support market orders and no quote available", req.Symbol)
+	}
+	q, err := s.quoteSnapshot(req.Symbol)
+	if err != nil {
+		return false, 0, fmt.Errorf("shinny: %s does not support market orders and no quote available: %w", req.Symbol, err)
+	}
+	var price float64
+	if req.Direction == DirectionBuy {
+		price = q.AskPrice1 + 2*info.PriceTick
+	} else {
+		price = q.BidPrice1 - 2*info.PriceTick
+	}
+	if price <= 0 {
+		return false, 0, fmt.Errorf("shinny: %s does not support market orders and quote has no counterparty price", req.Symbol)
+	}
+	s.logger.Info("market order converted to protective limit order",
+		"symbol", req.Symbol, "direction", req.Direction, "limit_price", price)
+	return true, price, nil
+}
+
+// CancelOrder 撤单, 发出报文后立即返回
+func (s *TradeSession) CancelOrder(ctx context.Context, orderID string) (err error) {
+	defer func() {
+		s.auditLog(&AuditEntry{Action: AuditActionCancel, OrderID: orderID, Result: auditResult(err)})
+	}()
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	if err := s.acquireOrderToken(ctx); err != nil {
+		return err
+	}
+	return s.sendOrderPacket(ctx, map[string]interface{}{
+		"aid":      "cancel_order",
+		"user_id":  s.userID,
+		"order_id": orderID,
+	})
+}
+
+// CancelOrderSync 撤单并等待确认: 订单变为 FINISHED 视为撤单成功,
+// 订单已全部成交返回 ErrOrderAlreadyFilled, 收到撤单失败通知或
+// ctx 结束时返回相应错误
+func (s *TradeSession) CancelOrderSync(ctx context.Context, orderID string) error {
+	check := func() (done bool, err error) {
+		orders, rerr := s.readOrders()
+		if rerr != nil {
+			return false, rerr
+		}
+		o, ok := orders[orderID]
+		if !ok {
+			return false, nil
+		}
+		if o.Status != StatusFinished {
+			return false, nil
+		}
+		if o.VolumeLeft == 0 {
+			return true, ErrOrderAlreadyFilled
+		}
+		return true, nil
+	}
+	if done, err := check(); done {
+		return err
+	}
+
+	// 捕获撤单失败通知
+	notifyErr := make(chan error, 1)
+	var notifyDone atomic.Bool
+	unregister := s.OnNotification(func(n *Notification) {
+		if notifyDone.Load() || n.Level != NotifyLevelError {
+			return
+		}
+		if strings.Contains(n.Content, orderID) {
+			select {
+			case notifyErr <- fmt.Errorf("shinny: cancel order %s failed: %s", orderID, n.Content):
+			default:
+			}
+		}
+	})
+	// 注销移除回调, notifyDone 兜底拦截已拷贝在途的分发
+	defer unregister()
+	defer notifyDone.Store(true)
+
+	watch := s.dm.Watch(ctx, s.path("orders", orderID)...)
+	if err := s.CancelOrder(ctx, orderID); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if done, err := check(); done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-notifyErr:
+			return err
+		case <-watch:
+		case <-ticker.C:
+		}
+	}
+}
+
+// nextOrderID 生成本会话内唯一的 order_id: 前缀 + 随机串 + 自增序号
+func (s *TradeSession) nextOrderID() string {
+	prefix := s.orderIDPrefix
+	if prefix == "" {
+		prefix = "TQGO_"
+	}
+	return fmt.Sprintf("%s%s_%d", prefix, RandomStr(8), s.orderSeq.Add(1))
+}
+
+// acquireOrderToken 在限流开启时取一个报撤单令牌: fail-fast 模式超频
+// 直接返回 ErrRateLimited, 否则阻塞等待到令牌可用或 ctx 结束
+func (s *TradeSession) acquireOrderToken(ctx context.Context) error {
+	if s.orderLimiter == nil {
+		return nil
+	}
+	for {
+		ok, wait := s.orderLimiter.take()
+		if ok {
+			return nil
+		}
+		if s.failFastOrders {
+			return ErrRateLimited
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// sendOrderPacket 发送下单/撤单报文: ctx 已取消时不发送,
+// failFastOrders 模式下断线直接失败
+func (s *TradeSession) sendOrderPacket(ctx context.Context, pkt map[string]interface{}) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	if s.failFastOrders {
+		return s.ws.SendImmediate(pkt)
+	}
+	return s.ws.Send(pkt)
+}
+
+func (s *TradeSession) readAccount() (*Account, error) {
+	raw, ok := s.dm.GetCopyByPath(s.path("accounts", "CNY")...)
+	if !ok {
+		return nil, ErrNotLoggedIn
+	}
+	acc := &Account{}
+	if err := ConvertToStruct(raw, acc); err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+func (s *TradeSession) readOrders() (map[string]*Order, error) {
+	return readSection[Order](s.dm, s.path("orders"))
+}
+
+func (s *TradeSession) readTrades() (map[string]*Trade, error) {
+	return readSection[Trade](s.dm, s.path("trades"))
+}
+
+func (s *TradeSession) readPositions() (map[string]*Position, error) {
+	return readSection[Position](s.dm, s.path("positions"))
+}
+
+// readSection 把截面里的一个 map 节点整体转换为结构体表:
+// 取节点深拷贝再遍历转换, 避免与并发合并竞争
+func readSection[T any](dm *DataManager, path []string) (map[string]*T, error) {
+	out := make(map[string]*T)
+	raw, ok := dm.GetCopyByPath(path...)
+	if !ok {
+		return out, nil
+	}
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("shinny: unexpected data at %v", path)
+	}
+	for k, v := range section {
+		item := new(T)
+		if err := ConvertToStruct(v, item); err != nil {
+			return nil, err
+		}
+		out[k] = item
+	}
+	return out, nil
+}
+
+func (s *TradeSession) GetAccount(ctx context.Context) (*Account, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	return s.readAccount()
+}
+
+func (s *TradeSession) GetPositions(ctx context.Context) (map[string]*Position, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	return s.readPositions()
+}
+
+func (s *TradeSession) GetOrders(ctx context.Context) (map[string]*Order, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	return s.readOrders()
+}
+
+// GetFrozenVolume 汇总 symbol 上指定方向挂单冻结的平仓手数:
+// 统计未结束平仓单的剩余量, direction 为挂单方向 (SELL 冻结多头,
+// BUY 冻结空头), 可平量 = 持仓量 - 冻结量
+func (s *TradeSession) GetFrozenVolume(ctx context.Context, symbol, direction string) (int64, error) {
+	orders, err := s.GetOrders(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return sumFrozenVolume(orders, symbol, direction), nil
+}
+
+// sumFrozenVolume 统计挂单冻结量: 只计未结束的平仓方向订单
+func sumFrozenVolume(orders map[string]*Order, symbol, direction string) int64 {
+	var frozen int64
+	for _, o := range orders {
+		if o.Status != StatusAlive || o.Offset == OffsetOpen || o.Direction != direction {
+			continue
+		}
+		if joinSymbol(o.ExchangeID, o.InstrumentID) != symbol {
+			continue
+		}
+		frozen += o.VolumeLeft
+	}
+	return frozen
+}
+
+func (s *TradeSession) GetTrades(ctx context.Context) (map[string]*Trade, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	return s.readTrades()
+}
+
+// marginBreakdown 汇总各合约持仓的保证金占用, 无持仓或占用为 0 的
+// 合约不出现在结果里
+func marginBreakdown(positions map[string]*Position) map[string]float64 {
+	out := make(map[string]float64)
+	for symbol, pos := range positions {
+		if pos.Margin != 0 {
+			out[symbol] = pos.Margin
+		}
+	}
+	return out
+}
+
+// GetMarginBreakdown 返回按合约分解的保证金占用, 各项之和等于
+// account.Margin, 用于资金管理
+func (s *TradeSession) GetMarginBreakdown(ctx context.Context) (map[string]float64, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	positions, err := s.readPositions()
+	if err != nil {
+		return nil, err
+	}
+	return marginBreakdown(positions), nil
+}
+
+// tradesByOrder 过滤一笔订单的全部成交并计算成交量加权均价,
+// 成交按时间升序排列 (时间相同按 trade_id)
+func tradesByOrder(trades map[string]*Trade, orderID string) ([]*Trade, float64) {
+	var out []*Trade
+	var amount float64
+	var volume int64
+	for _, t := range trades {
+		if t.OrderID != orderID {
+			continue
+		}
+		out = append(out, t)
+		amount += t.Price * float64(t.Volume)
+		volume += t.Volume
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TradeDateTime != out[j].TradeDateTime {
+			return out[i].TradeDateTime < out[j].TradeDateTime
+		}
+		return out[i].TradeID < out[j].TradeID
+	})
+	var avg float64
+	if volume > 0 {
+		avg = amount / float64(volume)
+	}
+	return out, avg
+}
+
+// GetTradesByOrder 返回一笔订单的全部成交明细与加权成交均价,
+// 订单无成交时返回空列表与 0
+func (s *TradeSession) GetTradesByOrder(ctx context.Context, orderID string) ([]*Trade, float64, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, 0, err
+	}
+	trades, err := s.readTrades()
+	if err != nil {
+		return nil, 0, err
+	}
+	out, avg := tradesByOrder(trades, orderID)
+	return out, avg, nil
+}
+
+func (s *TradeSession) OnOrder(handler func(*Order)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orderHandlers = append(s.orderHandlers, handler)
+}
+
+// OnOrderRejected 注册未全成即结束订单的回调, reason 取自 order.last_msg
+func (s *TradeSession) OnOrderRejected(handler func(*Order, string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejectHandlers = append(s.rejectHandlers, handler)
+}
+
+func (s *TradeSession) OnTrade(handler func(*Trade)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tradeHandlers = append(s.tradeHandlers, handler)
+}
+
+func (s *TradeSession) OnAccount(handler func(*Account)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accountHandlers = append(s.accountHandlers, handler)
+}
+
+// OnRiskAlert 注册风险度预警: 账户更新时 RiskRatio 越过 threshold 触发一次
+func (s *TradeSession) OnRiskAlert(threshold float64, handler func(*Account)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.riskAlerts = append(s.riskAlerts, &riskAlert{threshold: threshold, handler: handler})
+}
+
+// notifyHandler 带注册 id 的通知回调, id 用于注销
+type notifyHandler struct {
+	id int64
+	fn func(*Notification)
+}
+
+// notifyFns 取出回调函数列表, 调用方必须持有对应的锁
+func notifyFns(handlers []notifyHandler) []func(*Notification) {
+	fns := make([]func(*Notification), 0, len(handlers))
+	for _, h := range handlers {
+		fns = append(fns, h.fn)
+	}
+	return fns
+}
+
+// unregisterNotify 按 id 移除一个通知回调, 返回移除后的列表
+func unregisterNotify(handlers []notifyHandler, id int64) []notifyHandler {
+	for i, h := range handlers {
+		if h.id == id {
+			return append(handlers[:i], handlers[i+1:]...)
+		}
+	}
+	return handlers
+}
+
+// OnNotification 注册通知回调, 返回注销函数: 临时监听 (如
+// CancelOrderSync 等待撤单回报) 用完即注销, 避免回调表无限增长
+func (s *TradeSession) OnNotification(handler func(*Notification)) (unregister func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifyHandlerID++
+	id := s.notifyHandlerID
+	s.notifyHandlers = append(s.notifyHandlers, notifyHandler{id: id, fn: handler})
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.notifyHandlers = unregisterNotify(s.notifyHandlers, id)
+	}
+}
+
+// OnNotificationLevel 注册只接收指定级别 (INFO/WARNING/ERROR) 通知
+// 的回调, 其它级别被过滤
+func (s *TradeSession) OnNotificationLevel(level string, handler func(*Notification)) {
+	s.OnNotification(func(n *Notification) {
+		if n.Level == level {
+			handler(n)
+		}
+	})
+}
+
+// OnImportantNotification 注册重要通知回调: 强平/追保等涉及账户安全
+// 的通知单独路由, 不受级别过滤影响
+func (s *TradeSession) OnImportantNotification(handler func(*Notification)) {
+	s.OnNotification(func(n *Notification) {
+		if isImportantNotification(n) {
+			handler(n)
+		}
+	})
+}
+
+// importantNotifyKeywords 判定重要通知的内容关键字
+var importantNotifyKeywords = []string{"强平", "强行平仓", "追保", "追加保证金"}
+
+// isImportantNotification 判断通知是否涉及强平/追保等重要事件:
+// 模拟强平通知按 Type 识别, 服务端通知按内容关键字识别
+func isImportantNotification(n *Notification) bool {
+	if n.Type == "FORCED_LIQUIDATION" {
+		return true
+	}
+	for _, kw := range importantNotifyKeywords {
+		if strings.Contains(n.Content, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// OnError 注册内部错误回调, 目前用于上报用户回调 panic
+func (s *TradeSession) OnError(handler func(error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorHandlers = append(s.errorHandlers, handler)
+}
+
+func (s *TradeSession) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for id, timer := range s.ttlTimers {
+		timer.Stop()
+		delete(s.ttlTimers, id)
+	}
+	s.mu.Unlock()
+	s.equity.stop()
+	err := s.ws.Close()
+	s.dm.Close()
+	return err
+}
+
+// joinSymbol 把交易所与合约拼为 "EXCHANGE.INSTRUMENT",
+// 交易所为空时退化为裸合约代码
+func joinSymbol(exchangeID, instrumentID string) string {
+	if exchangeID == "" {
+		return instrumentID
+	}
+	return exchangeID + "." + instrumentID
+}
+
+// splitSymbol 把 "SHFE.cu2601" 拆为交易所与合约代码; 主连/指数等
+// 组合代码 (如 "KQ.m@SHFE.rb") 按 '@' 后的实际合约拆分
+func splitSymbol(symbol string) (exchangeID, instrumentID string) {
+	if i := strings.IndexByte(symbol, '@'); i >= 0 {
+		return splitSymbol(symbol[i+1:])
+	}
+	for i := 0; i < len(symbol); i++ {
+		if symbol[i] == '.' {
+			return symbol[:i], symbol[i+1:]
+		}
+	}
+	return "", symbol
+}
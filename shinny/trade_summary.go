@@ -0,0 +1,97 @@
+package shinny
+
+import "context"
+
+// SymbolTradeSummary 单合约成交汇总
+type SymbolTradeSummary struct {
+	Symbol string
+	// Volume 总成交手数
+	Volume int64
+	// AvgPrice 按手数加权的成交均价
+	AvgPrice   float64
+	Commission float64
+	// BuyCount / SellCount 买卖方向的成交笔数
+	BuyCount  int
+	SellCount int
+}
+
+// TradeSummary 一个账户的成交汇总, 由 GetTrades 聚合得出
+type TradeSummary struct {
+	// BySymbol 按合约分组的汇总, key 为 "交易所.合约"
+	BySymbol        map[string]*SymbolTradeSummary
+	TradeCount      int
+	TotalVolume     int64
+	TotalCommission float64
+
+	// RealizedProfit 累计平仓盈亏; WinCount/LoseCount 为盈利/亏损的
+	// 平仓笔数, WinRate = WinCount / (WinCount + LoseCount).
+	// 仅 VirtualTrader 能逐笔核算, 实盘会话保持零值
+	RealizedProfit float64
+	WinCount       int
+	LoseCount      int
+	WinRate        float64
+}
+
+// summarizeTrades 聚合成交记录: 按合约统计总量/加权均价/手续费/买卖笔数
+func summarizeTrades(trades map[string]*Trade) *TradeSummary {
+	st := &TradeSummary{BySymbol: make(map[string]*SymbolTradeSummary)}
+	for _, t := range trades {
+		symbol := t.ExchangeID + "." + t.InstrumentID
+		sym := st.BySymbol[symbol]
+		if sym == nil {
+			sym = &SymbolTradeSummary{Symbol: symbol}
+			st.BySymbol[symbol] = sym
+		}
+		// 加权均价: 累加金额后除以累计手数
+		total := sym.AvgPrice*float64(sym.Volume) + t.Price*float64(t.Volume)
+		sym.Volume += t.Volume
+		if sym.Volume > 0 {
+			sym.AvgPrice = total / float64(sym.Volume)
+		}
+		sym.Commission += t.Commission
+		if t.Direction == DirectionBuy {
+			sym.BuyCount++
+		} else {
+			sym.SellCount++
+		}
+		st.TradeCount++
+		st.TotalVolume += t.Volume
+		st.TotalCommission += t.Commission
+	}
+	return st
+}
+
+// GetTradeSummary 返回本会话的成交汇总
+func (s *TradeSession) GetTradeSummary(ctx context.Context) (*TradeSummary, error) {
+	trades, err := s.GetTrades(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeTrades(trades), nil
+}
+
+// GetTradeSummary 返回模拟账户的成交汇总, 额外包含逐笔平仓盈亏与胜率
+func (v *VirtualTrader) GetTradeSummary(ctx context.Context) (*TradeSummary, error) {
+	v.mu.Lock()
+	trades := make(map[string]*Trade, len(v.trades))
+	for k, t := range v.trades {
+		clone := *t
+		trades[k] = &clone
+	}
+	closeResults := append([]float64(nil), v.closeResults...)
+	v.mu.Unlock()
+
+	st := summarizeTrades(trades)
+	for _, profit := range closeResults {
+		st.RealizedProfit += profit
+		if profit > 0 {
+			st.WinCount++
+		} else {
+			st.LoseCount++
+		}
+	}
+	if closes := st.WinCount + st.LoseCount; closes > 0 {
+		st.WinRate = float64(st.WinCount) / float64(closes)
+	}
+	return st, nil
+}
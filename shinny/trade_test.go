@@ -0,0 +1,1566 @@
+package shinny
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestTradeSession 构造一个不连接服务器的交易会话, 测试直接向 dm 喂数据
+func newTestTradeSession(t *testing.T) (*TradeSession, *DataManager) {
+	t.Helper()
+	dm := NewDataManager()
+	ws := NewTqTradeWebsocket(&WebSocketConfig{}, dm, nil)
+	return newTradeSession(ws, dm, "simnow", "user1", defaultLogger()), dm
+}
+
+func TestOrderIDPrefixAndUniqueness(t *testing.T) {
+	dm := NewDataManager()
+	ws := NewTqTradeWebsocket(&WebSocketConfig{}, dm, nil)
+	session := newTradeSession(ws, dm, "simnow", "user1", defaultLogger(), WithOrderIDPrefix("mystrategy_"))
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := session.nextOrderID()
+		if !strings.HasPrefix(id, "mystrategy_") {
+			t.Fatalf("order id %q missing prefix", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicated order id %q", id)
+		}
+		seen[id] = true
+	}
+
+	// 默认前缀保持 TQGO_
+	def := newTradeSession(NewTqTradeWebsocket(&WebSocketConfig{}, dm, nil), dm, "simnow", "user2", defaultLogger())
+	if id := def.nextOrderID(); !strings.HasPrefix(id, "TQGO_") {
+		t.Fatalf("default order id %q missing TQGO_ prefix", id)
+	}
+}
+
+func TestOnOrderRejected(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+
+	var mu sync.Mutex
+	var rejected *Order
+	var reason string
+	session.OnOrderRejected(func(o *Order, r string) {
+		mu.Lock()
+		rejected = o
+		reason = r
+		mu.Unlock()
+	})
+
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"orders": map[string]interface{}{
+					"TQGO_abc": map[string]interface{}{
+						"order_id":     "TQGO_abc",
+						"status":       StatusFinished,
+						"volume_orign": 2.0,
+						"volume_left":  2.0,
+						"last_msg":     "已撤单报单被拒绝",
+					},
+				},
+			},
+		},
+	})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		mu.Lock()
+		done := rejected != nil
+		mu.Unlock()
+		if done || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if rejected == nil {
+		t.Fatal("OnOrderRejected not triggered")
+	}
+	if rejected.OrderID != "TQGO_abc" || reason != "已撤单报单被拒绝" {
+		t.Fatalf("got order %q reason %q", rejected.OrderID, reason)
+	}
+}
+
+func mergeAccount(dm *DataManager, userID string, fields map[string]interface{}) {
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			userID: map[string]interface{}{
+				"accounts": map[string]interface{}{"CNY": fields},
+			},
+		},
+	})
+}
+
+func TestOnRiskAlert(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+
+	var alerts atomic.Int64
+	session.OnRiskAlert(0.8, func(acc *Account) {
+		alerts.Add(1)
+	})
+
+	steps := []float64{0.5, 0.85, 0.86, 0.82, 0.7, 0.9}
+	for _, r := range steps {
+		mergeAccount(dm, "user1", map[string]interface{}{"risk_ratio": r, "balance": 100000.0})
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	// 0.85 触发一次, 0.86/0.82 去抖不触发, 0.7 重新武装, 0.9 再触发
+	if got := alerts.Load(); got != 2 {
+		t.Fatalf("risk alerts = %d, want 2", got)
+	}
+}
+
+func mergeOrder(dm *DataManager, userID, orderID, status string, volumeLeft float64) {
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			userID: map[string]interface{}{
+				"orders": map[string]interface{}{
+					orderID: map[string]interface{}{
+						"order_id":     orderID,
+						"status":       status,
+						"volume_orign": 2.0,
+						"volume_left":  volumeLeft,
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestCancelOrderSyncSuccess(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+	mergeOrder(dm, "user1", "TQGO_x1", StatusAlive, 2)
+
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		done <- session.CancelOrderSync(ctx, "TQGO_x1")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	// 服务端确认撤单: 未全成即结束
+	mergeOrder(dm, "user1", "TQGO_x1", StatusFinished, 2)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("CancelOrderSync error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("CancelOrderSync did not return")
+	}
+}
+
+func TestCancelOrderSyncAlreadyFilled(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+	mergeOrder(dm, "user1", "TQGO_x2", StatusFinished, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := session.CancelOrderSync(ctx, "TQGO_x2"); !errors.Is(err, ErrOrderAlreadyFilled) {
+		t.Fatalf("error = %v, want ErrOrderAlreadyFilled", err)
+	}
+}
+
+func TestOnOrderRejectedNotTriggeredForFilled(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+
+	var mu sync.Mutex
+	triggered := false
+	session.OnOrderRejected(func(o *Order, r string) {
+		mu.Lock()
+		triggered = true
+		mu.Unlock()
+	})
+
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"orders": map[string]interface{}{
+					"TQGO_ok": map[string]interface{}{
+						"order_id":     "TQGO_ok",
+						"status":       StatusFinished,
+						"volume_orign": 2.0,
+						"volume_left":  0.0,
+					},
+				},
+			},
+		},
+	})
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if triggered {
+		t.Fatal("OnOrderRejected should not fire for fully filled order")
+	}
+}
+
+func TestOrderRateLimitFailFast(t *testing.T) {
+	dm := NewDataManager()
+	ws := NewTqTradeWebsocket(&WebSocketConfig{}, dm, nil)
+	session := newTradeSession(ws, dm, "simnow", "user1", defaultLogger(),
+		WithFailFastOrders(), WithOrderRateLimit(2))
+
+	req := &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	}
+	// 前 2 次通过限流 (断线下失败于发送), 第 3 次被限流
+	for i := 0; i < 2; i++ {
+		if _, err := session.InsertOrder(context.Background(), req); !errors.Is(err, ErrNotConnected) {
+			t.Fatalf("call %d error = %v, want ErrNotConnected", i, err)
+		}
+	}
+	if _, err := session.InsertOrder(context.Background(), req); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("error = %v, want ErrRateLimited", err)
+	}
+	if err := session.CancelOrder(context.Background(), "TQGO_x"); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("CancelOrder error = %v, want ErrRateLimited", err)
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTokenBucket(2)
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := b.take(); !ok {
+			t.Fatalf("token %d should be available", i)
+		}
+	}
+	ok, wait := b.take()
+	if ok {
+		t.Fatal("bucket should be empty")
+	}
+	if wait <= 0 || wait > 500*time.Millisecond {
+		t.Fatalf("wait = %v, want (0, 500ms]", wait)
+	}
+	// 过 1 秒补充 2 个令牌, 且不超过桶容量
+	now = now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		if ok, _ := b.take(); !ok {
+			t.Fatalf("refilled token %d should be available", i)
+		}
+	}
+	if ok, _ := b.take(); ok {
+		t.Fatal("bucket should be empty after refill consumed")
+	}
+}
+
+func TestCallbackPanicRecovered(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+
+	var received atomic.Int64
+	var errCount atomic.Int64
+	session.OnOrder(func(o *Order) { panic("boom") })
+	session.OnOrder(func(o *Order) { received.Add(1) })
+	session.OnError(func(err error) {
+		if strings.Contains(err.Error(), "panic") {
+			errCount.Add(1)
+		}
+	})
+
+	mergeOrder(dm, "user1", "TQGO_p1", StatusAlive, 2)
+	mergeOrder(dm, "user1", "TQGO_p2", StatusAlive, 1)
+
+	deadline := time.Now().Add(time.Second)
+	for received.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := received.Load(); got != 2 {
+		t.Fatalf("second handler received %d updates, want 2", got)
+	}
+	if errCount.Load() == 0 {
+		t.Fatal("OnError not notified about callback panic")
+	}
+}
+
+func TestReadOnlySession(t *testing.T) {
+	dm := NewDataManager()
+	ws := NewTqTradeWebsocket(&WebSocketConfig{}, dm, nil)
+	session := newTradeSession(ws, dm, "simnow", "user1", defaultLogger(), WithReadOnly())
+
+	if _, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("InsertOrder error = %v, want ErrReadOnly", err)
+	}
+	if err := session.CancelOrder(context.Background(), "TQGO_x"); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("CancelOrder error = %v, want ErrReadOnly", err)
+	}
+	ws.mu.Lock()
+	queued := len(ws.queue)
+	ws.mu.Unlock()
+	if queued != 0 {
+		t.Fatalf("read-only session queued %d packets", queued)
+	}
+
+	// 查询正常
+	mergeAccount(dm, "user1", map[string]interface{}{"balance": 100000.0})
+	acc, err := session.GetAccount(context.Background())
+	if err != nil || acc.Balance != 100000 {
+		t.Fatalf("GetAccount = %+v, %v", acc, err)
+	}
+	mergeOrder(dm, "user1", "TQGO_r1", StatusAlive, 1)
+	orders, err := session.GetOrders(context.Background())
+	if err != nil || orders["TQGO_r1"] == nil {
+		t.Fatalf("GetOrders = %v, %v", orders, err)
+	}
+}
+
+func TestVirtualTraderDeterministicIDs(t *testing.T) {
+	run := func() []string {
+		vt := NewVirtualTrader(1000000, WithRandSeed(42))
+		vt.UpdateMarketPrice("SHFE.cu2601", 68000)
+		var ids []string
+		for i := 0; i < 5; i++ {
+			o, err := vt.InsertOrder(context.Background(), &InsertOrderRequest{
+				Symbol:    "SHFE.cu2601",
+				Direction: DirectionBuy,
+				Offset:    OffsetOpen,
+				Volume:    1,
+				PriceType: PriceTypeAny,
+			})
+			if err != nil {
+				t.Fatalf("InsertOrder: %v", err)
+			}
+			ids = append(ids, o.OrderID)
+		}
+		trades, _ := vt.GetTrades(context.Background())
+		for id := range trades {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	first := run()
+	second := run()
+	if len(first) != len(second) {
+		t.Fatalf("id count mismatch: %d vs %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("ids not reproducible with fixed seed: %q vs %q", first[i], second[i])
+		}
+	}
+}
+
+func TestInsertOrderConvertsMarketToLimit(t *testing.T) {
+	session, _ := newTestTradeSession(t)
+	session.symbolInfo = func(symbol string) *SymbolInfo {
+		return &SymbolInfo{MaxMarketOrderVolume: 0, PriceTick: 1}
+	}
+	session.quoteSnapshot = func(symbol string) (*Quote, error) {
+		return &Quote{BidPrice1: 2895, AskPrice1: 2898}, nil
+	}
+
+	o, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "CZCE.MA601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeAny,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder error: %v", err)
+	}
+	if o.PriceType != PriceTypeLimit || o.LimitPrice != 2900 {
+		t.Fatalf("buy order not converted: type=%s price=%v", o.PriceType, o.LimitPrice)
+	}
+
+	// 发出的报文应为限价单并带保护限价
+	session.ws.mu.Lock()
+	queue := append([][]byte(nil), session.ws.queue...)
+	session.ws.mu.Unlock()
+	if len(queue) != 1 {
+		t.Fatalf("queued packets = %d, want 1", len(queue))
+	}
+	var pkt map[string]interface{}
+	if err := json.Unmarshal(queue[0], &pkt); err != nil {
+		t.Fatalf("decode packet: %v", err)
+	}
+	if pkt["price_type"] != PriceTypeLimit || pkt["limit_price"] != 2900.0 {
+		t.Fatalf("packet not converted: %v", pkt)
+	}
+
+	// 卖单取买一价减两跳
+	o, err = session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "CZCE.MA601", Direction: DirectionSell, Offset: OffsetClose,
+		Volume: 1, PriceType: PriceTypeAny,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder error: %v", err)
+	}
+	if o.LimitPrice != 2893 {
+		t.Fatalf("sell limit price = %v, want 2893", o.LimitPrice)
+	}
+}
+
+func TestInsertOrderMarketConversionNoQuote(t *testing.T) {
+	session, _ := newTestTradeSession(t)
+	session.symbolInfo = func(symbol string) *SymbolInfo {
+		return &SymbolInfo{MaxMarketOrderVolume: 0, PriceTick: 1}
+	}
+	session.quoteSnapshot = func(symbol string) (*Quote, error) {
+		return nil, errors.New("no quote data")
+	}
+	if _, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "CZCE.MA601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeAny,
+	}); err == nil {
+		t.Fatal("expected error when quote unavailable")
+	}
+
+	// 支持市价的合约不做转换
+	session.symbolInfo = func(symbol string) *SymbolInfo {
+		return &SymbolInfo{MaxMarketOrderVolume: 100}
+	}
+	o, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeAny,
+	})
+	if err != nil || o.PriceType != PriceTypeAny {
+		t.Fatalf("market-capable contract should pass through, got %v %v", o, err)
+	}
+}
+
+func TestGetTradeSummary(t *testing.T) {
+	vt := NewVirtualTrader(1000000, WithRandSeed(1))
+	vt.SetVolumeMultiple("SHFE.cu2601", 5)
+	vt.UpdateMarketPrice("SHFE.cu2601", 100)
+
+	buy := func(price float64, volume int64) {
+		_, err := vt.InsertOrder(context.Background(), &InsertOrderRequest{
+			Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+			Volume: volume, PriceType: PriceTypeAny,
+		})
+		if err != nil {
+			t.Fatalf("InsertOrder: %v", err)
+		}
+	}
+	sell := func(volume int64) {
+		_, err := vt.InsertOrder(context.Background(), &InsertOrderRequest{
+			Symbol: "SHFE.cu2601", Direction: DirectionSell, Offset: OffsetClose,
+			Volume: volume, PriceType: PriceTypeAny,
+		})
+		if err != nil {
+			t.Fatalf("InsertOrder: %v", err)
+		}
+	}
+
+	// 100 开 1 手, 110 开 1 手, 120 平 1 手(盈), 90 平 1 手(亏)
+	buy(100, 1)
+	vt.UpdateMarketPrice("SHFE.cu2601", 110)
+	buy(110, 1)
+	vt.UpdateMarketPrice("SHFE.cu2601", 120)
+	sell(1)
+	vt.UpdateMarketPrice("SHFE.cu2601", 90)
+	sell(1)
+
+	st, err := vt.GetTradeSummary(context.Background())
+	if err != nil {
+		t.Fatalf("GetTradeSummary: %v", err)
+	}
+	if st.TradeCount != 4 || st.TotalVolume != 4 {
+		t.Fatalf("trade count/volume = %d/%d, want 4/4", st.TradeCount, st.TotalVolume)
+	}
+	sym := st.BySymbol["SHFE.cu2601"]
+	if sym == nil {
+		t.Fatal("missing symbol summary")
+	}
+	if sym.BuyCount != 2 || sym.SellCount != 2 {
+		t.Fatalf("buy/sell count = %d/%d, want 2/2", sym.BuyCount, sym.SellCount)
+	}
+	if want := (100.0 + 110 + 120 + 90) / 4; sym.AvgPrice != want {
+		t.Fatalf("AvgPrice = %v, want %v", sym.AvgPrice, want)
+	}
+	// 平仓两笔: 120 平 105 均价盈 75, 90 平 105 均价亏 75
+	if st.WinCount != 1 || st.LoseCount != 1 || st.WinRate != 0.5 {
+		t.Fatalf("win/lose/rate = %d/%d/%v, want 1/1/0.5", st.WinCount, st.LoseCount, st.WinRate)
+	}
+	if st.RealizedProfit != 0 {
+		t.Fatalf("RealizedProfit = %v, want 0", st.RealizedProfit)
+	}
+}
+
+func TestContextCancelledMethods(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := session.InsertOrder(ctx, &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("InsertOrder error = %v, want context.Canceled", err)
+	}
+	session.ws.mu.Lock()
+	queued := len(session.ws.queue)
+	session.ws.mu.Unlock()
+	if queued != 0 {
+		t.Fatal("cancelled insert must not queue a packet")
+	}
+
+	if err := session.CancelOrder(ctx, "any"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("CancelOrder error = %v, want context.Canceled", err)
+	}
+	if _, err := session.GetOrders(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetOrders error = %v, want context.Canceled", err)
+	}
+	if _, err := session.GetAccount(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("GetAccount error = %v, want context.Canceled", err)
+	}
+
+	// 同步等待类方法在等待期间取消时及时返回
+	mergeOrder(dm, "user1", "ord1", StatusAlive, 2)
+	waitCtx, waitCancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- session.CancelOrderSync(waitCtx, "ord1") }()
+	time.Sleep(20 * time.Millisecond)
+	waitCancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("CancelOrderSync error = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("CancelOrderSync did not return after ctx cancel")
+	}
+
+	vt := NewVirtualTrader(100000)
+	if _, err := vt.InsertOrder(ctx, &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("VirtualTrader.InsertOrder error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSymbolMethods(t *testing.T) {
+	o := &Order{ExchangeID: "SHFE", InstrumentID: "cu2601"}
+	if got := o.Symbol(); got != "SHFE.cu2601" {
+		t.Fatalf("Order.Symbol() = %q", got)
+	}
+	tr := &Trade{ExchangeID: "DCE", InstrumentID: "m2601"}
+	if got := tr.Symbol(); got != "DCE.m2601" {
+		t.Fatalf("Trade.Symbol() = %q", got)
+	}
+	p := &Position{ExchangeID: "CZCE", InstrumentID: "MA601"}
+	if got := p.Symbol(); got != "CZCE.MA601" {
+		t.Fatalf("Position.Symbol() = %q", got)
+	}
+	// 交易所为空时退化为裸合约代码
+	if got := (&Order{InstrumentID: "cu2601"}).Symbol(); got != "cu2601" {
+		t.Fatalf("Symbol() without exchange = %q", got)
+	}
+}
+
+func TestInsertOrdersPartialFailure(t *testing.T) {
+	vt := NewVirtualTrader(1000000)
+	vt.UpdateMarketPrice("SHFE.cu2601", 68000)
+
+	reqs := []*InsertOrderRequest{
+		{Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+			Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000},
+		{Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+			Volume: 0, PriceType: PriceTypeLimit, LimitPrice: 68000},
+	}
+	orders, errs := vt.InsertOrders(nil, reqs)
+	if len(orders) != 2 || len(errs) != 2 {
+		t.Fatalf("result length = %d/%d, want 2/2", len(orders), len(errs))
+	}
+	if errs[0] != nil || orders[0] == nil {
+		t.Fatalf("valid order failed: %v", errs[0])
+	}
+	if errs[1] == nil || orders[1] != nil {
+		t.Fatalf("invalid order should fail, got order %v", orders[1])
+	}
+	// 非原子模式: 合法订单照常提交
+	got, _ := vt.GetOrders(nil)
+	if len(got) != 1 {
+		t.Fatalf("submitted orders = %d, want 1", len(got))
+	}
+}
+
+func TestInsertOrdersAtomicAbortsAll(t *testing.T) {
+	vt := NewVirtualTrader(1000000)
+	vt.UpdateMarketPrice("SHFE.cu2601", 68000)
+
+	reqs := []*InsertOrderRequest{
+		{Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+			Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000},
+		{Symbol: "SHFE.cu2601", Direction: "HOLD", Offset: OffsetOpen,
+			Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000},
+	}
+	orders, errs := vt.InsertOrders(nil, reqs, WithAtomicBatch())
+	if orders[0] != nil || orders[1] != nil {
+		t.Fatalf("atomic batch must not submit any order: %v %v", orders[0], orders[1])
+	}
+	if !errors.Is(errs[0], ErrBatchAborted) {
+		t.Fatalf("errs[0] = %v, want ErrBatchAborted", errs[0])
+	}
+	if errs[1] == nil || errors.Is(errs[1], ErrBatchAborted) {
+		t.Fatalf("errs[1] = %v, want validation error", errs[1])
+	}
+	got, _ := vt.GetOrders(nil)
+	if len(got) != 0 {
+		t.Fatalf("submitted orders = %d, want 0", len(got))
+	}
+
+	// 全部通过校验时整批提交
+	reqs[1].Direction = DirectionSell
+	reqs[1].Offset = OffsetClose
+	orders, errs = vt.InsertOrders(nil, reqs, WithAtomicBatch())
+	if errs[0] != nil || errs[1] != nil {
+		t.Fatalf("all-valid atomic batch errors: %v %v", errs[0], errs[1])
+	}
+	if orders[0] == nil || orders[1] == nil {
+		t.Fatal("all-valid atomic batch should return both orders")
+	}
+}
+
+func TestOrderEventsLifecycle(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+	events := session.OrderEvents("ord_ev1")
+
+	next := func() *OrderEvent {
+		t.Helper()
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("event channel closed early")
+			}
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("no order event")
+		}
+		return nil
+	}
+
+	mergeOrder(dm, "user1", "ord_ev1", StatusAlive, 2)
+	e := next()
+	if e.Message != "报单" || e.Status != StatusAlive || e.VolumeLeft != 2 {
+		t.Fatalf("event 1 = %+v", e)
+	}
+
+	mergeOrder(dm, "user1", "ord_ev1", StatusAlive, 1)
+	e = next()
+	if e.Message != "部分成交" || e.VolumeLeft != 1 {
+		t.Fatalf("event 2 = %+v", e)
+	}
+
+	mergeOrder(dm, "user1", "ord_ev1", StatusFinished, 0)
+	e = next()
+	if e.Message != "全部成交" || e.Status != StatusFinished {
+		t.Fatalf("event 3 = %+v", e)
+	}
+
+	// 订单结束后通道关闭
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("unexpected event after finish")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after order finished")
+	}
+
+	// 已结束订单的通道立即关闭
+	if _, ok := <-session.OrderEvents("ord_ev1"); ok {
+		t.Fatal("channel for finished order should be closed")
+	}
+}
+
+func TestAutoConfirmSettlement(t *testing.T) {
+	dm := NewDataManager()
+	ws := NewTqTradeWebsocket(&WebSocketConfig{}, dm, nil)
+	session := newTradeSession(ws, dm, "simnow", "user1", defaultLogger(), WithAutoConfirmSettlement())
+
+	notified := make(chan *Notification, 1)
+	session.OnNotification(func(n *Notification) {
+		select {
+		case notified <- n:
+		default:
+		}
+	})
+
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"session": map[string]interface{}{
+					"trading_day":          "20260827",
+					"settlement_confirmed": false,
+				},
+			},
+		},
+	})
+
+	var n *Notification
+	select {
+	case n = <-notified:
+	case <-time.After(time.Second):
+		t.Fatal("auto confirm notification not received")
+	}
+	if n.Type != "SETTLEMENT" || n.Level != NotifyLevelInfo {
+		t.Fatalf("notification = %+v", n)
+	}
+
+	ws.mu.Lock()
+	queue := append([][]byte(nil), ws.queue...)
+	ws.mu.Unlock()
+	var sent bool
+	for _, pkt := range queue {
+		if strings.Contains(string(pkt), `"aid":"confirm_settlement"`) {
+			sent = true
+		}
+	}
+	if !sent {
+		t.Fatal("confirm_settlement packet not sent")
+	}
+
+	// 同一交易日重复推送不重复确认
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"session": map[string]interface{}{"settlement_confirmed": false},
+			},
+		},
+	})
+	time.Sleep(50 * time.Millisecond)
+	ws.mu.Lock()
+	count := 0
+	for _, pkt := range ws.queue {
+		if strings.Contains(string(pkt), `"aid":"confirm_settlement"`) {
+			count++
+		}
+	}
+	ws.mu.Unlock()
+	if count != 1 {
+		t.Fatalf("confirm_settlement sent %d times, want 1", count)
+	}
+}
+
+func TestOrderTTLAutoCancel(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+
+	order, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 2, PriceType: PriceTypeLimit, LimitPrice: 60000,
+		TTL: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder error: %v", err)
+	}
+
+	hasCancel := func(orderID string) bool {
+		// 队列由内嵌 TqWebsocket 的锁保护, 外层 TqTradeWebsocket.mu 管不到
+		session.ws.TqWebsocket.mu.Lock()
+		defer session.ws.TqWebsocket.mu.Unlock()
+		for _, pkt := range session.ws.queue {
+			if strings.Contains(string(pkt), `"aid":"cancel_order"`) &&
+				strings.Contains(string(pkt), orderID) {
+				return true
+			}
+		}
+		return false
+	}
+	deadline := time.Now().Add(time.Second)
+	for !hasCancel(order.OrderID) && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !hasCancel(order.OrderID) {
+		t.Fatal("ttl expiry did not trigger cancel_order")
+	}
+
+	// 订单先行结束时不撤单
+	filled, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionSell, Offset: OffsetOpen,
+		Volume: 2, PriceType: PriceTypeLimit, LimitPrice: 70000,
+		TTL: 30 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder error: %v", err)
+	}
+	mergeOrder(dm, "user1", filled.OrderID, StatusFinished, 0)
+	time.Sleep(80 * time.Millisecond)
+	if hasCancel(filled.OrderID) {
+		t.Fatal("finished order should not be cancelled by ttl")
+	}
+	session.mu.Lock()
+	remaining := len(session.ttlTimers)
+	session.mu.Unlock()
+	if remaining != 0 {
+		t.Fatalf("ttl timers remaining = %d, want 0", remaining)
+	}
+}
+
+func TestForcedLiquidation(t *testing.T) {
+	vt := NewVirtualTrader(100000, WithRandSeed(7))
+	vt.SetVolumeMultiple("SHFE.cu2601", 10)
+
+	notifyCh := make(chan *Notification, 1)
+	vt.OnNotification(func(n *Notification) { notifyCh <- n })
+
+	vt.UpdateMarketPrice("SHFE.cu2601", 10000)
+	_, err := vt.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeAny,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder: %v", err)
+	}
+
+	// 价格暴跌: 浮亏 91000, 权益 9000 < 保证金 10000, 风险度超 100% 触发强平
+	vt.UpdateMarketPrice("SHFE.cu2601", 900)
+
+	select {
+	case n := <-notifyCh:
+		if n.Type != "FORCED_LIQUIDATION" || n.Level != NotifyLevelWarning {
+			t.Fatalf("notification = %+v, want FORCED_LIQUIDATION/WARNING", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("forced liquidation notification not received")
+	}
+
+	positions, err := vt.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if pos := positions["SHFE.cu2601"]; pos != nil && pos.VolumeLong != 0 {
+		t.Fatalf("VolumeLong = %d, want 0 after liquidation", pos.VolumeLong)
+	}
+
+	orders, err := vt.GetOrders(context.Background())
+	if err != nil {
+		t.Fatalf("GetOrders: %v", err)
+	}
+	found := false
+	for _, o := range orders {
+		if o.Offset == OffsetClose && o.LastMsg == "风险度超限强平" {
+			found = true
+			if o.Status != StatusFinished || o.VolumeLeft != 0 {
+				t.Fatalf("liquidation order not filled: %+v", o)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("no forced liquidation order recorded")
+	}
+
+	acc, err := vt.GetAccount(context.Background())
+	if err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	if acc.Margin != 0 {
+		t.Fatalf("Margin = %v, want 0 after liquidation", acc.Margin)
+	}
+	if want := 9000.0; acc.Balance != want {
+		t.Fatalf("Balance = %v, want %v", acc.Balance, want)
+	}
+}
+
+func TestLiquidationThresholdOption(t *testing.T) {
+	// 阈值 0.5: 风险度超过 50% 即强平
+	vt := NewVirtualTrader(100000, WithRandSeed(8), WithLiquidationThreshold(0.5))
+	vt.SetVolumeMultiple("SHFE.cu2601", 10)
+	vt.UpdateMarketPrice("SHFE.cu2601", 10000)
+	if _, err := vt.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeAny,
+	}); err != nil {
+		t.Fatalf("InsertOrder: %v", err)
+	}
+	// 浮亏 82000, 权益 18000, 风险度 10000/18000 ≈ 0.56 > 0.5
+	vt.UpdateMarketPrice("SHFE.cu2601", 1800)
+
+	positions, err := vt.GetPositions(context.Background())
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	if pos := positions["SHFE.cu2601"]; pos != nil && pos.VolumeLong != 0 {
+		t.Fatalf("VolumeLong = %d, want 0 after liquidation", pos.VolumeLong)
+	}
+}
+
+func TestSymbolAliasResolution(t *testing.T) {
+	client := newTestClient(t, "futr")
+	if err := client.RegisterAlias("myCU", "SHFE.cu2601"); err != nil {
+		t.Fatalf("RegisterAlias: %v", err)
+	}
+	if err := client.RegisterAlias("", "SHFE.cu2601"); err == nil {
+		t.Fatal("empty alias should be rejected")
+	}
+	if got := client.ResolveSymbol("myCU"); got != "SHFE.cu2601" {
+		t.Fatalf("ResolveSymbol(myCU) = %s", got)
+	}
+	// 未注册的输入原样返回
+	if got := client.ResolveSymbol("SHFE.au2606"); got != "SHFE.au2606" {
+		t.Fatalf("ResolveSymbol passthrough = %s", got)
+	}
+
+	// 用别名订阅, 实际订阅标准 symbol
+	if _, err := client.SubscribeQuote(context.Background(), "myCU"); err != nil {
+		t.Fatalf("SubscribeQuote: %v", err)
+	}
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{
+				"last_price": 68000.0,
+				"datetime":   "2026-08-27 10:00:00.000000",
+			},
+		},
+	})
+	// 截面变更异步分发, 轮询等待订阅确认
+	deadline := time.Now().Add(time.Second)
+	for !client.IsSubscribed("SHFE.cu2601") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !client.IsSubscribed("SHFE.cu2601") {
+		t.Fatal("standard symbol not subscribed via alias")
+	}
+	// 订阅查询与退订同样接受别名
+	if !client.IsSubscribed("myCU") {
+		t.Fatal("IsSubscribed(alias) = false, want true")
+	}
+	if client.SymbolChannel("myCU") == nil {
+		t.Fatal("SymbolChannel(alias) = nil")
+	}
+	// GetQuote 同样接受别名
+	q, err := client.GetQuote("myCU")
+	if err != nil || q.InstrumentID != "cu2601" {
+		t.Fatalf("GetQuote(myCU) = %v, %v", q, err)
+	}
+
+	// 用别名下单, 报文携带标准交易所/合约代码
+	session, _ := newTestTradeSession(t)
+	session.resolveSymbol = client.ResolveSymbol
+	if _, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "myCU", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	}); err != nil {
+		t.Fatalf("InsertOrder: %v", err)
+	}
+	session.ws.mu.Lock()
+	queue := append([][]byte(nil), session.ws.queue...)
+	session.ws.mu.Unlock()
+	if len(queue) != 1 {
+		t.Fatalf("queued packets = %d, want 1", len(queue))
+	}
+	var pkt map[string]interface{}
+	if err := json.Unmarshal(queue[0], &pkt); err != nil {
+		t.Fatalf("decode packet: %v", err)
+	}
+	if pkt["exchange_id"] != "SHFE" || pkt["instrument_id"] != "cu2601" {
+		t.Fatalf("packet symbol not resolved: %v", pkt)
+	}
+
+	// 用别名退订, 实际退订标准 symbol
+	if err := client.UnsubscribeQuote(context.Background(), "myCU"); err != nil {
+		t.Fatalf("UnsubscribeQuote: %v", err)
+	}
+	if client.IsSubscribed("SHFE.cu2601") || client.IsSubscribed("myCU") {
+		t.Fatal("still subscribed after unsubscribe by alias")
+	}
+}
+
+func TestNotificationLevelFilterAndRouting(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+
+	errorCh := make(chan *Notification, 4)
+	importantCh := make(chan *Notification, 4)
+	var infoCount atomic.Int64
+	session.OnNotificationLevel(NotifyLevelError, func(n *Notification) { errorCh <- n })
+	session.OnNotificationLevel(NotifyLevelInfo, func(*Notification) { infoCount.Add(1) })
+	session.OnImportantNotification(func(n *Notification) { importantCh <- n })
+
+	dm.MergeData(map[string]interface{}{
+		"notify": map[string]interface{}{
+			"1": map[string]interface{}{
+				"type": "MESSAGE", "level": NotifyLevelInfo, "content": "登录成功",
+			},
+			"2": map[string]interface{}{
+				"type": "MESSAGE", "level": NotifyLevelError, "content": "保证金不足, 请追加保证金",
+			},
+		},
+	})
+
+	select {
+	case n := <-errorCh:
+		if n.Level != NotifyLevelError {
+			t.Fatalf("level = %s, want ERROR", n.Level)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("error level notification not received")
+	}
+	select {
+	case n := <-importantCh:
+		if n.Content != "保证金不足, 请追加保证金" {
+			t.Fatalf("important content = %q", n.Content)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("important notification not routed")
+	}
+	// INFO 回调只收到 INFO, ERROR 回调不重复触发
+	deadline := time.Now().Add(200 * time.Millisecond)
+	for infoCount.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := infoCount.Load(); got != 1 {
+		t.Fatalf("info callbacks = %d, want 1", got)
+	}
+	select {
+	case n := <-errorCh:
+		t.Fatalf("unexpected extra error notification: %+v", n)
+	default:
+	}
+}
+
+func TestIsImportantNotification(t *testing.T) {
+	cases := []struct {
+		n    Notification
+		want bool
+	}{
+		{Notification{Type: "FORCED_LIQUIDATION", Level: NotifyLevelWarning}, true},
+		{Notification{Type: "MESSAGE", Content: "账户将被强行平仓"}, true},
+		{Notification{Type: "MESSAGE", Content: "请及时追保"}, true},
+		{Notification{Type: "MESSAGE", Level: NotifyLevelError, Content: "密码错误"}, false},
+	}
+	for _, c := range cases {
+		if got := isImportantNotification(&c.n); got != c.want {
+			t.Errorf("isImportantNotification(%+v) = %v, want %v", c.n, got, c.want)
+		}
+	}
+}
+
+func TestRecalcFloatProfit(t *testing.T) {
+	p := &Position{
+		ExchangeID: "SHFE", InstrumentID: "cu2601",
+		VolumeLong: 2, OpenPriceLong: 68000,
+		VolumeShort: 1, OpenPriceShort: 68500,
+	}
+	p.RecalcFloatProfit(68200, 5)
+	// 多头 (68200-68000)*2*5 = 2000, 空头 (68500-68200)*1*5 = 1500
+	if p.FloatProfitLong != 2000 || p.FloatProfitShort != 1500 {
+		t.Fatalf("float profit long/short = %v/%v, want 2000/1500", p.FloatProfitLong, p.FloatProfitShort)
+	}
+	if p.FloatProfit != 3500 || p.LastPrice != 68200 {
+		t.Fatalf("float profit = %v last = %v, want 3500/68200", p.FloatProfit, p.LastPrice)
+	}
+}
+
+func TestEnableLivePositionProfit(t *testing.T) {
+	client := newTestClient(t, "futr")
+	session, sdm := newTestTradeSession(t)
+
+	sdm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"positions": map[string]interface{}{
+					"SHFE.cu2601": map[string]interface{}{
+						"exchange_id": "SHFE", "instrument_id": "cu2601",
+						"volume_long": 2.0, "open_price_long": 68000.0,
+					},
+				},
+			},
+		},
+	})
+
+	updates := make(chan *Position, 4)
+	client.EnableLivePositionProfit(session, func(p *Position) { updates <- p })
+
+	client.dm.MergeData(map[string]interface{}{
+		"quotes": map[string]interface{}{
+			"SHFE.cu2601": map[string]interface{}{
+				"last_price":      68200.0,
+				"datetime":        "2026-08-27 10:00:00.000000",
+				"volume_multiple": 5.0,
+			},
+		},
+	})
+
+	select {
+	case p := <-updates:
+		if p.FloatProfit != 2000 {
+			t.Fatalf("recalc float profit = %v, want 2000", p.FloatProfit)
+		}
+		if p.LastPrice != 68200 {
+			t.Fatalf("last price = %v, want 68200", p.LastPrice)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("position update not pushed")
+	}
+	if !client.IsSubscribed("SHFE.cu2601") {
+		t.Fatal("position symbol not auto subscribed")
+	}
+}
+
+func TestGetFrozenVolume(t *testing.T) {
+	vt := NewVirtualTrader(1000000, WithRandSeed(3))
+	vt.SetVolumeMultiple("SHFE.cu2601", 5)
+	vt.UpdateMarketPrice("SHFE.cu2601", 68000)
+
+	// 开多 5 手
+	if _, err := vt.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 5, PriceType: PriceTypeAny,
+	}); err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	// 低于市价的限价平仓单挂起不成交, 冻结 3 手多头
+	if _, err := vt.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionSell, Offset: OffsetClose,
+		Volume: 3, PriceType: PriceTypeLimit, LimitPrice: 70000,
+	}); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	frozen, err := vt.GetFrozenVolume(context.Background(), "SHFE.cu2601", DirectionSell)
+	if err != nil {
+		t.Fatalf("GetFrozenVolume: %v", err)
+	}
+	if frozen != 3 {
+		t.Fatalf("frozen = %d, want 3", frozen)
+	}
+	// 反方向与其它合约不计
+	if f, _ := vt.GetFrozenVolume(context.Background(), "SHFE.cu2601", DirectionBuy); f != 0 {
+		t.Fatalf("buy frozen = %d, want 0", f)
+	}
+	if f, _ := vt.GetFrozenVolume(context.Background(), "SHFE.au2606", DirectionSell); f != 0 {
+		t.Fatalf("other symbol frozen = %d, want 0", f)
+	}
+}
+
+func TestGetFrozenVolumeSession(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"orders": map[string]interface{}{
+					"TQGO_f1": map[string]interface{}{
+						"order_id": "TQGO_f1", "exchange_id": "SHFE", "instrument_id": "cu2601",
+						"direction": DirectionSell, "offset": OffsetClose,
+						"status": StatusAlive, "volume_left": 2.0,
+					},
+					"TQGO_f2": map[string]interface{}{
+						"order_id": "TQGO_f2", "exchange_id": "SHFE", "instrument_id": "cu2601",
+						"direction": DirectionSell, "offset": OffsetCloseToday,
+						"status": StatusAlive, "volume_left": 1.0,
+					},
+					"TQGO_f3": map[string]interface{}{
+						"order_id": "TQGO_f3", "exchange_id": "SHFE", "instrument_id": "cu2601",
+						"direction": DirectionSell, "offset": OffsetClose,
+						"status": StatusFinished, "volume_left": 4.0,
+					},
+				},
+			},
+		},
+	})
+	frozen, err := session.GetFrozenVolume(context.Background(), "SHFE.cu2601", DirectionSell)
+	if err != nil {
+		t.Fatalf("GetFrozenVolume: %v", err)
+	}
+	// 平仓 2 + 平今 1, 已结束订单不计
+	if frozen != 3 {
+		t.Fatalf("frozen = %d, want 3", frozen)
+	}
+}
+
+func TestQueueSimulationDelaysLimitFill(t *testing.T) {
+	vt := NewVirtualTrader(1000000, WithRandSeed(9), WithQueueSimulation())
+
+	// 买一 68000 上已有 30 手挂单, 当日累计成交 100 手
+	vt.FeedTick("SHFE.cu2601", &Tick{
+		LastPrice: 68000, BidPrice1: 68000, BidVolume1: 30,
+		AskPrice1: 68010, AskVolume1: 20, Volume: 100,
+	})
+
+	o, err := vt.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder: %v", err)
+	}
+	// 同价位需排队, 不会立即成交
+	if o.Status != StatusAlive {
+		t.Fatalf("order status = %q, want alive while queued", o.Status)
+	}
+
+	// 该价位只成交了 20 手, 前方还剩 10 手, 继续等待
+	vt.FeedTick("SHFE.cu2601", &Tick{
+		LastPrice: 68000, BidPrice1: 68000, BidVolume1: 10, Volume: 120,
+	})
+	orders, _ := vt.GetOrders(context.Background())
+	if got := orders[o.OrderID]; got.Status != StatusAlive {
+		t.Fatalf("order filled too early: %+v", got)
+	}
+
+	// 再成交 30 手, 前方排队耗尽, 本单成交
+	vt.FeedTick("SHFE.cu2601", &Tick{
+		LastPrice: 68000, BidPrice1: 68000, BidVolume1: 5, Volume: 150,
+	})
+	orders, _ = vt.GetOrders(context.Background())
+	got := orders[o.OrderID]
+	if got.Status != StatusFinished || got.VolumeLeft != 0 {
+		t.Fatalf("order not filled after queue consumed: %+v", got)
+	}
+
+	positions, _ := vt.GetPositions(context.Background())
+	if pos := positions["SHFE.cu2601"]; pos == nil || pos.VolumeLong != 1 {
+		t.Fatalf("position = %+v, want 1 long", pos)
+	}
+
+	// 未启用排队模拟时同价位立即成交
+	plain := NewVirtualTrader(1000000, WithRandSeed(10))
+	plain.FeedTick("SHFE.cu2601", &Tick{LastPrice: 68000, Volume: 100})
+	po, err := plain.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder: %v", err)
+	}
+	if po.Status != StatusFinished {
+		t.Fatalf("plain trader order status = %q, want finished", po.Status)
+	}
+}
+
+func TestContextErrorCarriesFields(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+
+	session.OnOrder(func(o *Order) { panic("boom") })
+	errCh := make(chan error, 1)
+	session.OnError(func(err error) {
+		select {
+		case errCh <- err:
+		default:
+		}
+	})
+
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"orders": map[string]interface{}{
+					"TQGO_ctx1": map[string]interface{}{
+						"order_id": "TQGO_ctx1", "exchange_id": "SHFE",
+						"instrument_id": "cu2601", "status": StatusAlive,
+						"volume_orign": 2.0, "volume_left": 2.0,
+					},
+				},
+			},
+		},
+	})
+
+	select {
+	case err := <-errCh:
+		var ce *ContextError
+		if !errors.As(err, &ce) {
+			t.Fatalf("error type = %T, want *ContextError", err)
+		}
+		if ce.Op != "order callback" {
+			t.Fatalf("Op = %q, want order callback", ce.Op)
+		}
+		if ce.Symbol != "SHFE.cu2601" {
+			t.Fatalf("Symbol = %q, want SHFE.cu2601", ce.Symbol)
+		}
+		if ce.OrderID != "TQGO_ctx1" {
+			t.Fatalf("OrderID = %q, want TQGO_ctx1", ce.OrderID)
+		}
+		if !strings.Contains(err.Error(), "panic") ||
+			!strings.Contains(err.Error(), "SHFE.cu2601") {
+			t.Fatalf("error message missing context: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("OnError not notified")
+	}
+}
+
+func TestGetTradesByOrder(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"trades": map[string]interface{}{
+					// 同一订单分三笔成交, 另一订单的成交不应混入
+					"t1": map[string]interface{}{
+						"trade_id": "t1", "order_id": "TQGO_a",
+						"price": 68000.0, "volume": 2.0, "trade_date_time": 100.0,
+					},
+					"t2": map[string]interface{}{
+						"trade_id": "t2", "order_id": "TQGO_a",
+						"price": 68010.0, "volume": 1.0, "trade_date_time": 300.0,
+					},
+					"t3": map[string]interface{}{
+						"trade_id": "t3", "order_id": "TQGO_a",
+						"price": 68005.0, "volume": 1.0, "trade_date_time": 200.0,
+					},
+					"t4": map[string]interface{}{
+						"trade_id": "t4", "order_id": "TQGO_b",
+						"price": 1.0, "volume": 100.0, "trade_date_time": 50.0,
+					},
+				},
+			},
+		},
+	})
+
+	trades, avg, err := session.GetTradesByOrder(context.Background(), "TQGO_a")
+	if err != nil {
+		t.Fatalf("GetTradesByOrder: %v", err)
+	}
+	if len(trades) != 3 {
+		t.Fatalf("trades = %d, want 3", len(trades))
+	}
+	// 按成交时间升序
+	if trades[0].TradeID != "t1" || trades[1].TradeID != "t3" || trades[2].TradeID != "t2" {
+		t.Fatalf("trade order = %s,%s,%s", trades[0].TradeID, trades[1].TradeID, trades[2].TradeID)
+	}
+	want := (68000.0*2 + 68010.0 + 68005.0) / 4
+	if avg != want {
+		t.Fatalf("avg = %v, want %v", avg, want)
+	}
+
+	// 无成交订单返回空列表与 0
+	trades, avg, err = session.GetTradesByOrder(context.Background(), "TQGO_none")
+	if err != nil || len(trades) != 0 || avg != 0 {
+		t.Fatalf("empty order: trades=%d avg=%v err=%v", len(trades), avg, err)
+	}
+}
+
+func TestVirtualTraderGetTradesByOrder(t *testing.T) {
+	vt := NewVirtualTrader(1000000, WithRandSeed(11))
+	vt.UpdateMarketPrice("SHFE.cu2601", 68000)
+	o, err := vt.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 2, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	})
+	if err != nil {
+		t.Fatalf("InsertOrder: %v", err)
+	}
+	trades, avg, err := vt.GetTradesByOrder(context.Background(), o.OrderID)
+	if err != nil {
+		t.Fatalf("GetTradesByOrder: %v", err)
+	}
+	if len(trades) != 1 || avg != 68000 {
+		t.Fatalf("trades=%d avg=%v, want 1 trade at 68000", len(trades), avg)
+	}
+}
+
+func TestGetMarginBreakdown(t *testing.T) {
+	vt := NewVirtualTrader(1000000, WithRandSeed(12))
+	vt.SetVolumeMultiple("SHFE.cu2601", 5)
+	vt.SetVolumeMultiple("DCE.m2601", 10)
+	vt.UpdateMarketPrice("SHFE.cu2601", 68000)
+	vt.UpdateMarketPrice("DCE.m2601", 3000)
+
+	for _, req := range []*InsertOrderRequest{
+		{Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+			Volume: 1, PriceType: PriceTypeAny},
+		{Symbol: "DCE.m2601", Direction: DirectionSell, Offset: OffsetOpen,
+			Volume: 2, PriceType: PriceTypeAny},
+	} {
+		if _, err := vt.InsertOrder(context.Background(), req); err != nil {
+			t.Fatalf("InsertOrder %s: %v", req.Symbol, err)
+		}
+	}
+
+	breakdown, err := vt.GetMarginBreakdown(context.Background())
+	if err != nil {
+		t.Fatalf("GetMarginBreakdown: %v", err)
+	}
+	if len(breakdown) != 2 {
+		t.Fatalf("breakdown = %v, want 2 symbols", breakdown)
+	}
+	var total float64
+	for _, m := range breakdown {
+		if m <= 0 {
+			t.Fatalf("non-positive margin in breakdown: %v", breakdown)
+		}
+		total += m
+	}
+	acc, err := vt.GetAccount(context.Background())
+	if err != nil {
+		t.Fatalf("GetAccount: %v", err)
+	}
+	// 分解求和等于账户总占用
+	if total != acc.Margin {
+		t.Fatalf("breakdown sum = %v, account margin = %v", total, acc.Margin)
+	}
+}
+
+func TestGetMarginBreakdownSession(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"positions": map[string]interface{}{
+					"SHFE.cu2601": map[string]interface{}{
+						"exchange_id": "SHFE", "instrument_id": "cu2601",
+						"volume_long": 1.0, "margin": 34000.0,
+					},
+					"DCE.m2601": map[string]interface{}{
+						"exchange_id": "DCE", "instrument_id": "m2601",
+						"volume_long": 0.0, "margin": 0.0,
+					},
+				},
+			},
+		},
+	})
+	breakdown, err := session.GetMarginBreakdown(context.Background())
+	if err != nil {
+		t.Fatalf("GetMarginBreakdown: %v", err)
+	}
+	// 占用为 0 的合约不出现
+	if len(breakdown) != 1 || breakdown["SHFE.cu2601"] != 34000 {
+		t.Fatalf("breakdown = %v", breakdown)
+	}
+}
+
+func TestOrderDerivedStatus(t *testing.T) {
+	cases := []struct {
+		name   string
+		status string
+		orign  int64
+		left   int64
+		msg    string
+		want   OrderDerivedStatus
+	}{
+		{"submitted", StatusAlive, 10, 10, "报单成功", OrderSubmitted},
+		{"partially filled", StatusAlive, 10, 4, "", OrderPartiallyFilled},
+		{"filled", StatusFinished, 10, 0, "全部成交", OrderFilled},
+		{"cancelled", StatusFinished, 10, 10, "已撤单", OrderCancelled},
+		{"cancelled after partial fill", StatusFinished, 10, 6, "已撤单报单已提交", OrderCancelled},
+		{"rejected insufficient funds", StatusFinished, 10, 10, "资金不足", OrderRejected},
+		{"rejected market order", StatusFinished, 10, 10, "无法获取行情, 市价单废单", OrderRejected},
+		{"rejected english", StatusFinished, 10, 10, "Order Rejected by exchange", OrderRejected},
+		{"unknown msg defaults to cancelled", StatusFinished, 10, 10, "IOC 剩余未成交部分自动终止", OrderCancelled},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &Order{Status: tc.status, VolumeOrign: tc.orign, VolumeLeft: tc.left, LastMsg: tc.msg}
+			if got := o.DerivedStatus(); got != tc.want {
+				t.Fatalf("DerivedStatus() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestOnOrderDispatchByDerivedStatus(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+
+	statusCh := make(chan OrderDerivedStatus, 8)
+	session.OnOrder(func(o *Order) {
+		statusCh <- o.DerivedStatus()
+	})
+
+	next := func(want OrderDerivedStatus) {
+		select {
+		case got := <-statusCh:
+			if got != want {
+				t.Fatalf("derived status = %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %s", want)
+		}
+	}
+
+	// 报入 -> 部成 -> 撤单结束, 回调按细分状态分流
+	mergeOrder(dm, "user1", "TQGO_ds1", StatusAlive, 2)
+	next(OrderSubmitted)
+	mergeOrder(dm, "user1", "TQGO_ds1", StatusAlive, 1)
+	next(OrderPartiallyFilled)
+	dm.MergeData(map[string]interface{}{
+		"trade": map[string]interface{}{
+			"user1": map[string]interface{}{
+				"orders": map[string]interface{}{
+					"TQGO_ds1": map[string]interface{}{
+						"status":   StatusFinished,
+						"last_msg": "已撤单",
+					},
+				},
+			},
+		},
+	})
+	next(OrderCancelled)
+}
+
+func TestCancelOrderSyncUnregistersNotifyHandler(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+
+	// 循环撤单后临时通知回调应全部注销, 不随调用次数累积
+	for i := 0; i < 5; i++ {
+		orderID := fmt.Sprintf("TQGO_nu%d", i)
+		// 订单已结束, CancelOrderSync 首次检查即返回
+		mergeOrder(dm, "user1", orderID, StatusFinished, 2)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		if err := session.CancelOrderSync(ctx, orderID); err != nil {
+			cancel()
+			t.Fatalf("CancelOrderSync error: %v", err)
+		}
+		cancel()
+	}
+	session.mu.Lock()
+	n := len(session.notifyHandlers)
+	session.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("notify handlers leaked: %d remain after cancels", n)
+	}
+}
+
+func TestOnNotificationUnregister(t *testing.T) {
+	session, dm := newTestTradeSession(t)
+
+	var calls atomic.Int64
+	unregister := session.OnNotification(func(*Notification) { calls.Add(1) })
+	kept := make(chan *Notification, 4)
+	session.OnNotification(func(n *Notification) { kept <- n })
+
+	notify := func(id, content string) {
+		dm.MergeData(map[string]interface{}{
+			"notify": map[string]interface{}{
+				id: map[string]interface{}{
+					"level":   NotifyLevelInfo,
+					"content": content,
+				},
+			},
+		})
+	}
+	notify("1", "first")
+	select {
+	case <-kept:
+	case <-time.After(time.Second):
+		t.Fatal("notification not delivered")
+	}
+	deadline := time.Now().Add(time.Second)
+	for calls.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls = %d, want 1", calls.Load())
+	}
+
+	unregister()
+	unregister() // 幂等
+	notify("2", "second")
+	select {
+	case <-kept:
+	case <-time.After(time.Second):
+		t.Fatal("remaining handler not delivered")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("unregistered handler still called, calls = %d", calls.Load())
+	}
+}
@@ -0,0 +1,143 @@
+package shinny
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// RandomStr 生成长度为 n 的随机字符串, 用于 order_id / chart_id 等
+func RandomStr(n int) string {
+	return RandomStrFrom(rand.New(rand.NewSource(time.Now().UnixNano())), n)
+}
+
+// RandomStrFrom 用指定随机源生成长度为 n 的随机字符串,
+// 固定种子时输出可复现, 用于回测/模拟
+func RandomStrFrom(r *rand.Rand, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	return string(b)
+}
+
+// SplitSymbol 把 "SHFE.rb2505" 拆为交易所与交易所原始代码, 便于和
+// 只用原始代码的外部系统互转; 主连/指数等组合代码按 '@' 后的实际
+// 合约拆分, 无交易所前缀时 exchangeID 为空
+func SplitSymbol(symbol string) (exchangeID, instrumentID string) {
+	return splitSymbol(symbol)
+}
+
+// JoinSymbol 把交易所与原始代码拼为 SDK 使用的完整合约代码,
+// 交易所为空时退化为裸合约代码
+func JoinSymbol(exchangeID, instrumentID string) string {
+	return joinSymbol(exchangeID, instrumentID)
+}
+
+// ctxErr 返回 ctx 的取消错误, 容忍 nil ctx
+func ctxErr(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// ParseDatetime 解析服务端下发的时间戳字符串, 兼容多种格式:
+// "2006-01-02 15:04:05[.ffffff]" / "2006-01-02T15:04:05[.ffffff]" /
+// ISO 8601 带时区. 无时区信息时按本地时区 (交易所时间) 解释
+func ParseDatetime(s string) (time.Time, error) {
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.000000",
+		"2006-01-02 15:04:05.000",
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05.000000",
+		"2006-01-02T15:04:05",
+	} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("shinny: unrecognized datetime %q", s)
+}
+
+// ParseTradingDay 解析 "YYYYMMDD" 形式的交易日, 无法解析时返回零值
+func ParseTradingDay(s string) time.Time {
+	t, err := time.ParseInLocation("20060102", s, time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// TimeFromNano 把 Kline/Tick/Order 等使用的纳秒时间戳转为 time.Time
+func TimeFromNano(ns int64) time.Time {
+	return time.Unix(0, ns)
+}
+
+// NanoFromTime 把 time.Time 转为纳秒时间戳
+func NanoFromTime(t time.Time) int64 {
+	return t.UnixNano()
+}
+
+// parseQuoteDatetime 解析行情时间戳字符串, 如 "2026-08-27 14:59:59.500000"
+func parseQuoteDatetime(s string) (time.Time, error) {
+	return ParseDatetime(s)
+}
+
+func toFloat64(v interface{}) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case float32:
+		return float64(x)
+	case int:
+		return float64(x)
+	case int64:
+		return float64(x)
+	case json.Number:
+		f, _ := x.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(x, 64)
+		return f
+	}
+	return 0
+}
+
+func toInt64(v interface{}) int64 {
+	switch x := v.(type) {
+	case float64:
+		return int64(x)
+	case int:
+		return int64(x)
+	case int64:
+		return x
+	case json.Number:
+		i, _ := x.Int64()
+		return i
+	case string:
+		i, _ := strconv.ParseInt(x, 10, 64)
+		return i
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package shinny
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDatetimeFormats(t *testing.T) {
+	cases := []string{
+		"2026-08-27 14:59:59.500000",
+		"2026-08-27 14:59:59.500",
+		"2026-08-27 14:59:59",
+		"2026-08-27T14:59:59.500000",
+		"2026-08-27T14:59:59",
+	}
+	for _, s := range cases {
+		got, err := ParseDatetime(s)
+		if err != nil {
+			t.Fatalf("ParseDatetime(%q) error: %v", s, err)
+		}
+		if got.Year() != 2026 || got.Month() != 8 || got.Day() != 27 ||
+			got.Hour() != 14 || got.Minute() != 59 || got.Second() != 59 {
+			t.Fatalf("ParseDatetime(%q) = %v", s, got)
+		}
+	}
+
+	if got, err := ParseDatetime("2026-08-27T14:59:59+08:00"); err != nil || got.Hour() != 14 {
+		t.Fatalf("RFC3339 parse = %v, %v", got, err)
+	}
+	if _, err := ParseDatetime("not-a-time"); err == nil {
+		t.Fatal("expected error for invalid datetime")
+	}
+}
+
+func TestParseTradingDay(t *testing.T) {
+	got := ParseTradingDay("20260827")
+	if got.Year() != 2026 || got.Month() != 8 || got.Day() != 27 {
+		t.Fatalf("ParseTradingDay = %v", got)
+	}
+	if !ParseTradingDay("bad").IsZero() {
+		t.Fatal("invalid trading day should return zero time")
+	}
+}
+
+func TestNanoRoundTrip(t *testing.T) {
+	now := time.Now().Round(0)
+	ns := NanoFromTime(now)
+	if got := TimeFromNano(ns); !got.Equal(now) {
+		t.Fatalf("round trip = %v, want %v", got, now)
+	}
+}
+
+func TestSplitJoinSymbol(t *testing.T) {
+	cases := []struct {
+		symbol     string
+		exchange   string
+		instrument string
+	}{
+		{"SHFE.rb2505", "SHFE", "rb2505"},
+		{"CZCE.MA505", "CZCE", "MA505"},
+		{"KQ.m@SHFE.rb", "SHFE", "rb"},
+		{"rb2505", "", "rb2505"},
+	}
+	for _, c := range cases {
+		ex, ins := SplitSymbol(c.symbol)
+		if ex != c.exchange || ins != c.instrument {
+			t.Fatalf("SplitSymbol(%q) = %q/%q, want %q/%q", c.symbol, ex, ins, c.exchange, c.instrument)
+		}
+	}
+	if got := JoinSymbol("SHFE", "rb2505"); got != "SHFE.rb2505" {
+		t.Fatalf("JoinSymbol = %q", got)
+	}
+	if got := JoinSymbol("", "rb2505"); got != "rb2505" {
+		t.Fatalf("JoinSymbol without exchange = %q", got)
+	}
+}
@@ -0,0 +1,721 @@
+package shinny
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// VirtualTrader 本地模拟交易账户, 实现 Trader,
+// 限价单按盘口价立即撮合, 用于回测与策略联调
+type VirtualTrader struct {
+	mu sync.Mutex
+
+	account   *Account
+	positions map[string]*Position
+	orders    map[string]*Order
+	trades    map[string]*Trade
+
+	// marketPrices 记录各合约最新价, 由 UpdateMarketPrice 喂入
+	marketPrices map[string]float64
+	// volumeMultiples 各合约乘数, 默认 10
+	volumeMultiples map[string]float64
+
+	orderHandlers   []func(*Order)
+	rejectHandlers  []func(*Order, string)
+	riskAlerts      []*riskAlert
+	tradeHandlers   []func(*Trade)
+	accountHandlers []func(*Account)
+	notifyHandlers  []notifyHandler
+	notifyHandlerID int64
+
+	orderSeq int64
+	tradeSeq int64
+
+	// rng orderID/tradeID 的随机源, 固定种子时 ID 序列可复现
+	rng *rand.Rand
+
+	// slippage 撮合滑点模型, nil 表示按理论价成交
+	slippage SlippageModel
+
+	// closeResults 逐笔平仓盈亏, 用于 GetTradeSummary 的胜率统计
+	closeResults []float64
+
+	// equity 权益曲线采样器
+	equity equityRecorder
+
+	// liquidationThreshold 强平风险度阈值, <=0 表示 1.0 (风险度 100%)
+	liquidationThreshold float64
+
+	// queueSim 启用限价单排队位置模拟: 同价位挂单要等盘口在该价位
+	// 的累计成交量消耗掉下单时的前方排队量后才成交
+	queueSim bool
+	// queueAhead 各挂单前方剩余排队量
+	queueAhead map[string]int64
+	// lastTickVolume / lastTick 各合约最近一笔 Tick 的累计成交量与
+	// 盘口, 用于计算成交量增量和下单时的价位排队量
+	lastTickVolume map[string]int64
+	lastTick       map[string]*Tick
+}
+
+// VirtualTraderOption 模拟账户选项
+type VirtualTraderOption func(*VirtualTrader)
+
+// WithRandSource 注入 ID 生成使用的随机源, 回测时传入固定种子的
+// rand.Rand 可保证相同输入产生相同的 orderID/tradeID 序列
+func WithRandSource(r *rand.Rand) VirtualTraderOption {
+	return func(v *VirtualTrader) {
+		v.rng = r
+	}
+}
+
+// WithRandSeed 等价于 WithRandSource(rand.New(rand.NewSource(seed)))
+func WithRandSeed(seed int64) VirtualTraderOption {
+	return func(v *VirtualTrader) {
+		v.rng = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithSlippage 注入撮合滑点模型: 成交价在理论价基础上按模型向不利
+// 方向偏移, 使回测成本更接近实盘
+func WithSlippage(m SlippageModel) VirtualTraderOption {
+	return func(v *VirtualTrader) {
+		v.slippage = m
+	}
+}
+
+// WithQueueSimulation 启用限价单排队位置模拟: 与最新价同价位的限价
+// 单不立即成交, 记录下单时盘口在该价位的挂单量为前方排队量, 之后
+// 每笔 FeedTick 的成交量增量按序消耗排队, 排完才成交. 使限价单
+// 回测更保守更接近实盘
+func WithQueueSimulation() VirtualTraderOption {
+	return func(v *VirtualTrader) {
+		v.queueSim = true
+	}
+}
+
+// WithLiquidationThreshold 设置强平风险度阈值: 行情更新或结算后账户
+// 风险度达到 threshold 时按市价强制平仓, 默认 1.0 (即 100%)
+func WithLiquidationThreshold(threshold float64) VirtualTraderOption {
+	return func(v *VirtualTrader) {
+		v.liquidationThreshold = threshold
+	}
+}
+
+func NewVirtualTrader(initBalance float64, opts ...VirtualTraderOption) *VirtualTrader {
+	vt := &VirtualTrader{
+		account: &Account{
+			CurrencyID:    "CNY",
+			PreBalance:    initBalance,
+			StaticBalance: initBalance,
+			Balance:       initBalance,
+			Available:     initBalance,
+		},
+		positions:       make(map[string]*Position),
+		orders:          make(map[string]*Order),
+		trades:          make(map[string]*Trade),
+		marketPrices:    make(map[string]float64),
+		volumeMultiples: make(map[string]float64),
+		queueAhead:      make(map[string]int64),
+		lastTickVolume:  make(map[string]int64),
+		lastTick:        make(map[string]*Tick),
+	}
+	for _, opt := range opts {
+		opt(vt)
+	}
+	if vt.rng == nil {
+		vt.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return vt
+}
+
+// SetVolumeMultiple 设置合约乘数
+func (v *VirtualTrader) SetVolumeMultiple(symbol string, multiple float64) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.volumeMultiples[symbol] = multiple
+}
+
+func (v *VirtualTrader) volumeMultiple(symbol string) float64 {
+	if m, ok := v.volumeMultiples[symbol]; ok && m > 0 {
+		return m
+	}
+	return 10
+}
+
+// UpdateMarketPrice 更新合约最新价, 重算浮盈并尝试撮合挂单
+func (v *VirtualTrader) UpdateMarketPrice(symbol string, price float64) {
+	v.mu.Lock()
+	v.marketPrices[symbol] = price
+	if pos, ok := v.positions[symbol]; ok {
+		v.recalcPosition(symbol, pos, price)
+	}
+	v.recalcAccountLocked()
+	matched := v.matchOrdersLocked(symbol, price)
+	matched = append(matched, v.liquidateLocked()...)
+	acc := *v.account
+	accountHandlers := append([]func(*Account){}, v.accountHandlers...)
+	var alerts []func(*Account)
+	for _, r := range v.riskAlerts {
+		if r.shouldFire(acc.RiskRatio) {
+			alerts = append(alerts, r.handler)
+		}
+	}
+	v.mu.Unlock()
+
+	for _, o := range matched {
+		v.emitOrder(o)
+	}
+	for _, h := range accountHandlers {
+		go h(&acc)
+	}
+	for _, h := range alerts {
+		go h(&acc)
+	}
+}
+
+// FeedTick 喂入一笔逐笔行情并驱动撮合. 启用排队模拟时, 先按 Tick
+// 累计成交量的增量消耗与最新价同价位挂单的前方排队量, 排队消耗完的
+// 挂单在随后的撮合中成交
+func (v *VirtualTrader) FeedTick(symbol string, t *Tick) {
+	v.mu.Lock()
+	if v.queueSim {
+		delta := t.Volume - v.lastTickVolume[symbol]
+		if delta < 0 { // 累计值回绕 (跨交易日), 视为从零重新累计
+			delta = t.Volume
+		}
+		v.lastTickVolume[symbol] = t.Volume
+		v.lastTick[symbol] = t
+		if delta > 0 {
+			for id, o := range v.orders {
+				if o.Status != StatusAlive || o.PriceType != PriceTypeLimit {
+					continue
+				}
+				if o.ExchangeID+"."+o.InstrumentID != symbol || t.LastPrice != o.LimitPrice {
+					continue
+				}
+				if q := v.queueAhead[id]; q > 0 {
+					v.queueAhead[id] = q - delta
+				}
+			}
+		}
+	}
+	v.mu.Unlock()
+	v.UpdateMarketPrice(symbol, t.LastPrice)
+}
+
+// depthAtLocked 返回最近 Tick 盘口在指定价位的挂单量: 买单看买盘档,
+// 卖单看卖盘档, 无行情或价位不在五档内时为 0
+func (v *VirtualTrader) depthAtLocked(symbol, direction string, price float64) int64 {
+	t := v.lastTick[symbol]
+	if t == nil {
+		return 0
+	}
+	var levels []PriceLevel
+	if direction == DirectionBuy {
+		levels = []PriceLevel{
+			{t.BidPrice1, t.BidVolume1}, {t.BidPrice2, t.BidVolume2},
+			{t.BidPrice3, t.BidVolume3}, {t.BidPrice4, t.BidVolume4},
+			{t.BidPrice5, t.BidVolume5},
+		}
+	} else {
+		levels = []PriceLevel{
+			{t.AskPrice1, t.AskVolume1}, {t.AskPrice2, t.AskVolume2},
+			{t.AskPrice3, t.AskVolume3}, {t.AskPrice4, t.AskVolume4},
+			{t.AskPrice5, t.AskVolume5},
+		}
+	}
+	for _, l := range levels {
+		if l.Price == price {
+			return l.Volume
+		}
+	}
+	return 0
+}
+
+func (v *VirtualTrader) recalcPosition(symbol string, pos *Position, price float64) {
+	mult := v.volumeMultiple(symbol)
+	pos.LastPrice = price
+	pos.FloatProfitLong = (price - pos.OpenPriceLong) * float64(pos.VolumeLong) * mult
+	pos.FloatProfitShort = (pos.OpenPriceShort - price) * float64(pos.VolumeShort) * mult
+	pos.FloatProfit = pos.FloatProfitLong + pos.FloatProfitShort
+}
+
+func (v *VirtualTrader) recalcAccountLocked() {
+	var floatProfit, margin float64
+	for _, pos := range v.positions {
+		floatProfit += pos.FloatProfit
+		margin += pos.Margin
+	}
+	v.account.FloatProfit = floatProfit
+	v.account.Margin = margin
+	v.account.Balance = v.account.StaticBalance + floatProfit + v.account.CloseProfit - v.account.Commission
+	v.account.Available = v.account.Balance - margin
+	if v.account.Balance > 0 {
+		v.account.RiskRatio = margin / v.account.Balance
+	}
+}
+
+// matchOrdersLocked 用最新价撮合指定合约的挂单, 返回状态变化的订单
+func (v *VirtualTrader) matchOrdersLocked(symbol string, price float64) []*Order {
+	var matched []*Order
+	for _, o := range v.orders {
+		if o.Status != StatusAlive || o.ExchangeID+"."+o.InstrumentID != symbol {
+			continue
+		}
+		if o.PriceType == PriceTypeLimit {
+			if o.Direction == DirectionBuy && price > o.LimitPrice {
+				continue
+			}
+			if o.Direction == DirectionSell && price < o.LimitPrice {
+				continue
+			}
+			// 排队模拟下, 同价位挂单要等前方排队消耗完
+			if v.queueSim && price == o.LimitPrice && v.queueAhead[o.OrderID] > 0 {
+				continue
+			}
+		}
+		fillPrice := price
+		if o.PriceType == PriceTypeLimit {
+			fillPrice = o.LimitPrice
+		}
+		v.fillOrderLocked(o, fillPrice)
+		matched = append(matched, o)
+	}
+	return matched
+}
+
+// InsertOrder 下单, 有对手价时立即撮合, 否则挂单等待 UpdateMarketPrice
+func (v *VirtualTrader) InsertOrder(ctx context.Context, req *InsertOrderRequest) (*Order, error) {
+	if err := ctxErr(ctx); err != nil {
+		return nil, err
+	}
+	exchangeID, instrumentID := splitSymbol(req.Symbol)
+	if req.Volume <= 0 {
+		return nil, fmt.Errorf("shinny: invalid order volume %d", req.Volume)
+	}
+	v.mu.Lock()
+	v.orderSeq++
+	o := &Order{
+		OrderID:        fmt.Sprintf("TQGO_SIM_%s_%d", RandomStrFrom(v.rng, 8), v.orderSeq),
+		ExchangeID:     exchangeID,
+		InstrumentID:   instrumentID,
+		Direction:      req.Direction,
+		Offset:         req.Offset,
+		VolumeOrign:    req.Volume,
+		VolumeLeft:     req.Volume,
+		LimitPrice:     req.LimitPrice,
+		PriceType:      req.PriceType,
+		Status:         StatusAlive,
+		InsertDateTime: time.Now().UnixNano(),
+	}
+	if req.Offset != OffsetOpen {
+		if err := v.checkCloseableLocked(req.Symbol, req.Direction, req.Volume); err != nil {
+			v.mu.Unlock()
+			return nil, err
+		}
+	}
+	v.orders[o.OrderID] = o
+
+	price, hasPrice := v.marketPrices[req.Symbol]
+	if req.PriceType == PriceTypeAny {
+		if !hasPrice {
+			o.Status = StatusFinished
+			o.LastMsg = "无法获取行情, 市价单废单"
+			v.mu.Unlock()
+			v.emitOrder(o)
+			return o, nil
+		}
+		v.fillOrderLocked(o, price)
+	} else if hasPrice {
+		crossed := (o.Direction == DirectionBuy && price < o.LimitPrice) ||
+			(o.Direction == DirectionSell && price > o.LimitPrice)
+		atLimit := price == o.LimitPrice
+		if crossed || (atLimit && !v.queueSim) {
+			v.fillOrderLocked(o, o.LimitPrice)
+		}
+	}
+	if v.queueSim && o.Status == StatusAlive && o.PriceType == PriceTypeLimit {
+		// 记录下单时该价位的挂单量作为前方排队量
+		v.queueAhead[o.OrderID] = v.depthAtLocked(req.Symbol, o.Direction, o.LimitPrice)
+	}
+	v.mu.Unlock()
+
+	v.emitOrder(o)
+	return o, nil
+}
+
+func (v *VirtualTrader) checkCloseableLocked(symbol, direction string, volume int64) error {
+	pos := v.positions[symbol]
+	if pos == nil {
+		return fmt.Errorf("shinny: no position to close for %s", symbol)
+	}
+	// 平多用 SELL, 平空用 BUY
+	if direction == DirectionSell && pos.VolumeLong < volume {
+		return fmt.Errorf("shinny: close volume %d exceeds long position %d", volume, pos.VolumeLong)
+	}
+	if direction == DirectionBuy && pos.VolumeShort < volume {
+		return fmt.Errorf("shinny: close volume %d exceeds short position %d", volume, pos.VolumeShort)
+	}
+	return nil
+}
+
+// fillOrderLocked 按 price 全量成交订单, 生成成交记录并更新持仓资金.
+// 配置滑点模型时先按模型调整成交价
+func (v *VirtualTrader) fillOrderLocked(o *Order, price float64) {
+	if v.slippage != nil {
+		price = v.slippage.SlipPrice(o, price)
+	}
+	v.tradeSeq++
+	t := &Trade{
+		TradeID:       fmt.Sprintf("%s|%s%d", o.OrderID, RandomStrFrom(v.rng, 4), v.tradeSeq),
+		OrderID:       o.OrderID,
+		ExchangeID:    o.ExchangeID,
+		InstrumentID:  o.InstrumentID,
+		Direction:     o.Direction,
+		Offset:        o.Offset,
+		Price:         price,
+		Volume:        o.VolumeLeft,
+		TradeDateTime: time.Now().UnixNano(),
+	}
+	v.trades[t.TradeID] = t
+	volume := o.VolumeLeft
+	o.VolumeLeft = 0
+	o.Status = StatusFinished
+	delete(v.queueAhead, o.OrderID)
+
+	symbol := o.ExchangeID + "." + o.InstrumentID
+	pos := v.positions[symbol]
+	if pos == nil {
+		pos = &Position{ExchangeID: o.ExchangeID, InstrumentID: o.InstrumentID}
+		v.positions[symbol] = pos
+	}
+	mult := v.volumeMultiple(symbol)
+	if o.Offset == OffsetOpen {
+		if o.Direction == DirectionBuy {
+			total := pos.OpenPriceLong*float64(pos.VolumeLong) + price*float64(volume)
+			pos.VolumeLongToday += volume
+			pos.VolumeLong += volume
+			pos.OpenPriceLong = total / float64(pos.VolumeLong)
+		} else {
+			total := pos.OpenPriceShort*float64(pos.VolumeShort) + price*float64(volume)
+			pos.VolumeShortToday += volume
+			pos.VolumeShort += volume
+			pos.OpenPriceShort = total / float64(pos.VolumeShort)
+		}
+		pos.Margin = (float64(pos.VolumeLong) + float64(pos.VolumeShort)) * price * mult * 0.1
+	} else {
+		if o.Direction == DirectionSell { // 平多
+			closeProfit := (price - pos.OpenPriceLong) * float64(volume) * mult
+			v.account.CloseProfit += closeProfit
+			v.closeResults = append(v.closeResults, closeProfit)
+			if pos.VolumeLongToday >= volume {
+				pos.VolumeLongToday -= volume
+			} else {
+				pos.VolumeLongHis -= volume - pos.VolumeLongToday
+				pos.VolumeLongToday = 0
+			}
+			pos.VolumeLong -= volume
+		} else { // 平空
+			closeProfit := (pos.OpenPriceShort - price) * float64(volume) * mult
+			v.account.CloseProfit += closeProfit
+			v.closeResults = append(v.closeResults, closeProfit)
+			if pos.VolumeShortToday >= volume {
+				pos.VolumeShortToday -= volume
+			} else {
+				pos.VolumeShortHis -= volume - pos.VolumeShortToday
+				pos.VolumeShortToday = 0
+			}
+			pos.VolumeShort -= volume
+		}
+		pos.Margin = (float64(pos.VolumeLong) + float64(pos.VolumeShort)) * price * mult * 0.1
+	}
+	v.recalcPosition(symbol, pos, price)
+	v.recalcAccountLocked()
+	v.emitTradeLocked(t)
+}
+
+func (v *VirtualTrader) emitOrder(o *Order) {
+	v.mu.Lock()
+	handlers := append([]func(*Order){}, v.orderHandlers...)
+	rejectHandlers := append([]func(*Order, string){}, v.rejectHandlers...)
+	clone := *o
+	v.mu.Unlock()
+	for _, h := range handlers {
+		go h(&clone)
+	}
+	if isOrderRejected(&clone) {
+		for _, h := range rejectHandlers {
+			go h(&clone, clone.LastMsg)
+		}
+	}
+}
+
+func (v *VirtualTrader) emitTradeLocked(t *Trade) {
+	handlers := append([]func(*Trade){}, v.tradeHandlers...)
+	clone := *t
+	for _, h := range handlers {
+		go h(&clone)
+	}
+}
+
+func (v *VirtualTrader) emitNotifyLocked(n *Notification) {
+	handlers := notifyFns(v.notifyHandlers)
+	for _, h := range handlers {
+		go h(n)
+	}
+}
+
+// liquidateLocked 风险度达到阈值时的强平模拟: 按保证金占用从大到小
+// 逐个合约以市价全平, 直到风险度回落到阈值以下或无仓可平.
+// 返回生成的强平订单, 由调用方在解锁后通过 emitOrder 派发
+func (v *VirtualTrader) liquidateLocked() []*Order {
+	threshold := v.liquidationThreshold
+	if threshold <= 0 {
+		threshold = 1.0
+	}
+	var out []*Order
+	for len(v.positions) > 0 {
+		if v.account.Balance > 0 && v.account.RiskRatio < threshold {
+			break
+		}
+		var symbol string
+		var pos *Position
+		for s, p := range v.positions {
+			if p.VolumeLong+p.VolumeShort == 0 {
+				continue
+			}
+			if _, ok := v.marketPrices[s]; !ok {
+				continue
+			}
+			if pos == nil || p.Margin > pos.Margin {
+				symbol, pos = s, p
+			}
+		}
+		if pos == nil {
+			break
+		}
+		price := v.marketPrices[symbol]
+		direction, volume := DirectionSell, pos.VolumeLong // 强平多头
+		if pos.VolumeShort > 0 {
+			direction, volume = DirectionBuy, pos.VolumeShort // 强平空头
+		}
+		v.orderSeq++
+		o := &Order{
+			OrderID:        fmt.Sprintf("TQGO_SIM_%s_%d", RandomStrFrom(v.rng, 8), v.orderSeq),
+			ExchangeID:     pos.ExchangeID,
+			InstrumentID:   pos.InstrumentID,
+			Direction:      direction,
+			Offset:         OffsetClose,
+			VolumeOrign:    volume,
+			VolumeLeft:     volume,
+			PriceType:      PriceTypeAny,
+			Status:         StatusAlive,
+			LastMsg:        "风险度超限强平",
+			InsertDateTime: time.Now().UnixNano(),
+		}
+		v.orders[o.OrderID] = o
+		v.fillOrderLocked(o, price)
+		out = append(out, o)
+		v.emitNotifyLocked(&Notification{
+			Type:    "FORCED_LIQUIDATION",
+			Level:   NotifyLevelWarning,
+			Content: fmt.Sprintf("风险度超限强平: %s 平仓 %d 手", symbol, volume),
+		})
+	}
+	return out
+}
+
+// CancelOrder 撤单, 订单已结束时返回错误
+func (v *VirtualTrader) CancelOrder(ctx context.Context, orderID string) error {
+	if err := ctxErr(ctx); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	o, ok := v.orders[orderID]
+	if !ok {
+		v.mu.Unlock()
+		return ErrOrderNotFound
+	}
+	if o.Status == StatusFinished {
+		v.mu.Unlock()
+		return fmt.Errorf("shinny: order %s already finished", orderID)
+	}
+	o.Status = StatusFinished
+	o.LastMsg = "已撤单"
+	delete(v.queueAhead, orderID)
+	v.mu.Unlock()
+	v.emitOrder(o)
+	return nil
+}
+
+func (v *VirtualTrader) GetAccount(ctx context.Context) (*Account, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	acc := *v.account
+	return &acc, nil
+}
+
+func (v *VirtualTrader) GetPositions(ctx context.Context) (map[string]*Position, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	out := make(map[string]*Position, len(v.positions))
+	for k, p := range v.positions {
+		clone := *p
+		out[k] = &clone
+	}
+	return out, nil
+}
+
+func (v *VirtualTrader) GetOrders(ctx context.Context) (map[string]*Order, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	out := make(map[string]*Order, len(v.orders))
+	for k, o := range v.orders {
+		clone := *o
+		out[k] = &clone
+	}
+	return out, nil
+}
+
+// GetFrozenVolume 汇总 symbol 上指定方向挂单冻结的平仓手数
+func (v *VirtualTrader) GetFrozenVolume(ctx context.Context, symbol, direction string) (int64, error) {
+	orders, err := v.GetOrders(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return sumFrozenVolume(orders, symbol, direction), nil
+}
+
+func (v *VirtualTrader) GetTrades(ctx context.Context) (map[string]*Trade, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	out := make(map[string]*Trade, len(v.trades))
+	for k, t := range v.trades {
+		clone := *t
+		out[k] = &clone
+	}
+	return out, nil
+}
+
+// GetMarginBreakdown 返回按合约分解的保证金占用, 各项之和等于
+// account.Margin
+func (v *VirtualTrader) GetMarginBreakdown(ctx context.Context) (map[string]float64, error) {
+	positions, err := v.GetPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return marginBreakdown(positions), nil
+}
+
+// GetTradesByOrder 返回一笔订单的全部成交明细与加权成交均价
+func (v *VirtualTrader) GetTradesByOrder(ctx context.Context, orderID string) ([]*Trade, float64, error) {
+	trades, err := v.GetTrades(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	out, avg := tradesByOrder(trades, orderID)
+	return out, avg, nil
+}
+
+func (v *VirtualTrader) OnOrder(handler func(*Order)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.orderHandlers = append(v.orderHandlers, handler)
+}
+
+// OnOrderRejected 注册未全成即结束订单的回调, reason 取自 order.last_msg
+func (v *VirtualTrader) OnOrderRejected(handler func(*Order, string)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.rejectHandlers = append(v.rejectHandlers, handler)
+}
+
+func (v *VirtualTrader) OnTrade(handler func(*Trade)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.tradeHandlers = append(v.tradeHandlers, handler)
+}
+
+func (v *VirtualTrader) OnAccount(handler func(*Account)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.accountHandlers = append(v.accountHandlers, handler)
+}
+
+// OnRiskAlert 注册风险度预警: 账户更新时 RiskRatio 越过 threshold 触发一次
+func (v *VirtualTrader) OnRiskAlert(threshold float64, handler func(*Account)) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.riskAlerts = append(v.riskAlerts, &riskAlert{threshold: threshold, handler: handler})
+}
+
+// OnNotification 注册通知回调, 返回注销函数, 语义与 TradeSession 一致
+func (v *VirtualTrader) OnNotification(handler func(*Notification)) (unregister func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.notifyHandlerID++
+	id := v.notifyHandlerID
+	v.notifyHandlers = append(v.notifyHandlers, notifyHandler{id: id, fn: handler})
+	return func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.notifyHandlers = unregisterNotify(v.notifyHandlers, id)
+	}
+}
+
+// OnNotificationLevel 注册只接收指定级别通知的回调
+func (v *VirtualTrader) OnNotificationLevel(level string, handler func(*Notification)) {
+	v.OnNotification(func(n *Notification) {
+		if n.Level == level {
+			handler(n)
+		}
+	})
+}
+
+// OnImportantNotification 注册强平/追保等重要通知的回调
+func (v *VirtualTrader) OnImportantNotification(handler func(*Notification)) {
+	v.OnNotification(func(n *Notification) {
+		if isImportantNotification(n) {
+			handler(n)
+		}
+	})
+}
+
+// Settle 日终结算: 浮盈转入静态权益, 今仓转昨仓
+func (v *VirtualTrader) Settle() {
+	v.mu.Lock()
+	v.account.StaticBalance = v.account.Balance
+	v.account.PreBalance = v.account.Balance
+	v.account.CloseProfit = 0
+	v.account.Commission = 0
+	for symbol, pos := range v.positions {
+		pos.VolumeLongHis += pos.VolumeLongToday
+		pos.VolumeLongToday = 0
+		pos.VolumeShortHis += pos.VolumeShortToday
+		pos.VolumeShortToday = 0
+		if price, ok := v.marketPrices[symbol]; ok {
+			pos.OpenPriceLong = price
+			pos.OpenPriceShort = price
+		}
+		if pos.VolumeLong == 0 && pos.VolumeShort == 0 {
+			delete(v.positions, symbol)
+		}
+	}
+	v.recalcAccountLocked()
+	liquidated := v.liquidateLocked()
+	v.mu.Unlock()
+	for _, o := range liquidated {
+		v.emitOrder(o)
+	}
+}
+
+func (v *VirtualTrader) Close() error {
+	v.equity.stop()
+	return nil
+}
+
+var _ Trader = (*VirtualTrader)(nil)
+var _ Trader = (*TradeSession)(nil)
@@ -0,0 +1,524 @@
+package shinny
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WebSocketStatus 连接状态
+type WebSocketStatus int32
+
+const (
+	StatusIdle WebSocketStatus = iota
+	StatusConnecting
+	StatusOpen
+	StatusClosed
+)
+
+var ErrNotConnected = errors.New("shinny: websocket not connected")
+
+// ErrSendFailed 时效性报文写入失败, 报文未进入发送队列,
+// 上层用 errors.Is 判定后自行决策 (重下/放弃)
+var ErrSendFailed = errors.New("shinny: websocket send failed")
+
+// maxSendRetries 非时效性报文单次 Send 内的最大写入尝试次数,
+// 全部失败后报文转入发送队列等重连后重发
+const maxSendRetries = 3
+
+// Version SDK 版本号
+const Version = "0.1.0"
+
+// UserAgent 访问行情/交易/认证服务的 User-Agent, 可按需覆盖
+var UserAgent = "tqsdk-go/" + Version
+
+// BaseHeader 返回访问行情/交易服务器的公共请求头
+func BaseHeader() http.Header {
+	h := http.Header{}
+	h.Set("User-Agent", UserAgent)
+	h.Set("Accept", "application/v1+json")
+	return h
+}
+
+// WebSocketConfig websocket 连接配置
+type WebSocketConfig struct {
+	// URLs 候选服务器地址, 连接失败时依次尝试
+	URLs []string
+	// MaxQueueSize 未连接期间报文队列的最大长度, 超限时丢弃最旧报文,
+	// <= 0 表示不限制
+	MaxQueueSize int
+	// Headers 额外的握手请求头, 与 BaseHeader 合并, 同名时覆盖默认值
+	Headers http.Header
+	// DialTimeout 单次拨号(含 TLS 与协议升级)的超时, <= 0 使用默认 10s,
+	// 超时后换下一个候选地址或进入重连
+	DialTimeout time.Duration
+}
+
+// defaultDialTimeout 默认拨号超时
+const defaultDialTimeout = 10 * time.Second
+
+// TqWebsocket 管理单条到行情/交易服务器的 websocket 连接,
+// 提供自动重连与未连接期间的报文排队
+type TqWebsocket struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	conf   *WebSocketConfig
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	conn   wsConn
+	status atomic.Int32
+	queue  [][]byte
+
+	// dial 可在测试中替换为 mock 连接工厂
+	dial func(ctx context.Context, url string, header http.Header) (wsConn, error)
+
+	onMessage func(pkt map[string]interface{})
+	onOpen    func()
+	onClose   func()
+	onError   func(err error)
+	// rawHandlers 原始报文钩子, 任意 aid 的报文都会先经过这里,
+	// 供用户扩展 SDK 未封装的协议
+	rawHandlers []func(aid string, pkt map[string]interface{})
+
+	// latency 单条报文处理耗时采样: 从收到报文到解码/合并/回调
+	// 派发完成, 用于区分网络慢还是本地处理慢
+	latency latencyRecorder
+}
+
+func NewTqWebsocket(conf *WebSocketConfig, logger *slog.Logger) *TqWebsocket {
+	if logger == nil {
+		logger = defaultLogger()
+	}
+	return &TqWebsocket{
+		conf:   conf,
+		logger: logger,
+		dial:   dialWebsocket,
+	}
+}
+
+func (ws *TqWebsocket) Status() WebSocketStatus {
+	return WebSocketStatus(ws.status.Load())
+}
+
+func (ws *TqWebsocket) OnMessage(fn func(pkt map[string]interface{})) { ws.onMessage = fn }
+func (ws *TqWebsocket) OnOpen(fn func())                              { ws.onOpen = fn }
+func (ws *TqWebsocket) OnClose(fn func())                             { ws.onClose = fn }
+func (ws *TqWebsocket) OnError(fn func(err error))                    { ws.onError = fn }
+
+// OnRawMessage 注册原始报文钩子: 收到的每个报文在内部处理前都会
+// 触发, 用于处理 SDK 未封装的自定义 aid, 可多次注册
+func (ws *TqWebsocket) OnRawMessage(fn func(aid string, pkt map[string]interface{})) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.rawHandlers = append(ws.rawHandlers, fn)
+}
+
+// Init 建立连接并启动接收循环, 连接失败时轮换候选地址重试
+func (ws *TqWebsocket) Init(ctx context.Context) error {
+	ws.ctx, ws.cancel = context.WithCancel(ctx)
+	if err := ws.connect(); err != nil {
+		return err
+	}
+	go ws.run()
+	return nil
+}
+
+func (ws *TqWebsocket) connect() error {
+	ws.status.Store(int32(StatusConnecting))
+	var lastErr error
+	header := ws.header()
+	timeout := ws.conf.DialTimeout
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
+	}
+	for _, u := range ws.conf.URLs {
+		dialCtx, cancel := context.WithTimeout(ws.ctx, timeout)
+		conn, err := ws.dial(dialCtx, u, header)
+		cancel()
+		if err != nil {
+			lastErr = err
+			ws.logger.Warn("websocket dial failed", "url", u, "error", err)
+			continue
+		}
+		ws.mu.Lock()
+		ws.conn = conn
+		ws.mu.Unlock()
+		ws.status.Store(int32(StatusOpen))
+		ws.logger.Info("websocket connected", "url", u)
+		if ws.onOpen != nil {
+			ws.onOpen()
+		}
+		ws.flushQueue()
+		return nil
+	}
+	ws.status.Store(int32(StatusClosed))
+	if lastErr == nil {
+		lastErr = errors.New("shinny: no websocket url configured")
+	}
+	return lastErr
+}
+
+// WaitReady 阻塞等待连接进入 StatusOpen: Init 成功后连接即可用,
+// 但断线重连期间状态会回落, 订阅/下单前调用可确保报文直接发出
+// 而不是进入发送队列
+func (ws *TqWebsocket) WaitReady(ctx context.Context) error {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if ws.Status() == StatusOpen {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("shinny: wait websocket ready interrupted: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// header 合并默认请求头与用户自定义请求头
+func (ws *TqWebsocket) header() http.Header {
+	h := BaseHeader()
+	for k, vs := range ws.conf.Headers {
+		h.Del(k)
+		for _, v := range vs {
+			h.Add(k, v)
+		}
+	}
+	return h
+}
+
+// run 驱动接收循环, 连接断开后自动重连
+func (ws *TqWebsocket) run() {
+	backoff := time.Second
+	for ws.ctx.Err() == nil {
+		ws.receiveLoop()
+		if ws.ctx.Err() != nil {
+			return
+		}
+		ws.status.Store(int32(StatusConnecting))
+		ws.logger.Warn("websocket disconnected, reconnecting", "backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ws.ctx.Done():
+			return
+		}
+		if err := ws.connect(); err != nil {
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+		backoff = time.Second
+	}
+}
+
+func (ws *TqWebsocket) receiveLoop() {
+	ws.mu.Lock()
+	conn := ws.conn
+	ws.mu.Unlock()
+	if conn == nil {
+		return
+	}
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			if ws.ctx.Err() == nil {
+				if ws.onError != nil {
+					ws.onError(err)
+				}
+				if ws.onClose != nil {
+					ws.onClose()
+				}
+			}
+			conn.Close()
+			return
+		}
+		ws.handleIncoming(data)
+	}
+}
+
+// handleIncoming 解码一条报文, 先经原始报文钩子再交给内部处理,
+// 全程耗时计入延迟统计
+func (ws *TqWebsocket) handleIncoming(data []byte) {
+	start := time.Now()
+	defer func() { ws.latency.record(time.Since(start)) }()
+	// UseNumber 保留大整数精度, 纳秒时间戳不经 float64 截断,
+	// 由 DataManager 合并时统一还原为数值
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	pkt := make(map[string]interface{})
+	if err := dec.Decode(&pkt); err != nil {
+		ws.logger.Warn("websocket decode failed", "error", err)
+		return
+	}
+	aid, _ := pkt["aid"].(string)
+	ws.mu.Lock()
+	raw := make([]func(string, map[string]interface{}), len(ws.rawHandlers))
+	copy(raw, ws.rawHandlers)
+	ws.mu.Unlock()
+	for _, h := range raw {
+		h(aid, pkt)
+	}
+	if ws.onMessage != nil {
+		ws.onMessage(pkt)
+	}
+}
+
+// Send 发送一个 DIFF 报文, 未连接时进入队列等连接建立后发出,
+// 队列超过 MaxQueueSize 时丢弃最旧报文. 写入失败 (网络抖动) 时
+// 在本次调用内重试 maxSendRetries 次, 仍失败则转入队列等重连后
+// 重发, 因此订阅/查询类报文不会因瞬时故障丢失
+func (ws *TqWebsocket) Send(pkt map[string]interface{}) error {
+	data, err := json.Marshal(pkt)
+	if err != nil {
+		return err
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.Status() != StatusOpen || ws.conn == nil {
+		ws.enqueueLocked(data)
+		return nil
+	}
+	var lastErr error
+	for i := 0; i < maxSendRetries; i++ {
+		if lastErr = ws.conn.WriteMessage(data); lastErr == nil {
+			return nil
+		}
+	}
+	ws.logger.Warn("websocket send failed, packet queued for retransmit",
+		"retries", maxSendRetries, "error", lastErr)
+	ws.enqueueLocked(data)
+	return nil
+}
+
+// enqueueLocked 把报文追加进发送队列并按 MaxQueueSize 截断,
+// 调用方必须持有 ws.mu
+func (ws *TqWebsocket) enqueueLocked(data []byte) {
+	ws.queue = append(ws.queue, data)
+	if max := ws.conf.MaxQueueSize; max > 0 && len(ws.queue) > max {
+		dropped := len(ws.queue) - max
+		ws.queue = ws.queue[dropped:]
+		ws.logger.Warn("websocket queue full, dropped oldest packets", "dropped", dropped)
+	}
+}
+
+// SendRaw 发送任意自定义报文, 语义与 Send 相同: 未连接时排队,
+// 连接建立后按序发出. 与 OnRawMessage 配合扩展协议
+func (ws *TqWebsocket) SendRaw(pkt map[string]interface{}) error {
+	return ws.Send(pkt)
+}
+
+// SendImmediate 发送一个时效性报文, 未连接时不排队直接返回
+// ErrNotConnected; 写入失败时不重试不排队, 返回 ErrSendFailed
+// 包装的错误, 由上层 (下单逻辑) 立即决策
+func (ws *TqWebsocket) SendImmediate(pkt map[string]interface{}) error {
+	data, err := json.Marshal(pkt)
+	if err != nil {
+		return err
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.Status() != StatusOpen || ws.conn == nil {
+		return ErrNotConnected
+	}
+	if err := ws.conn.WriteMessage(data); err != nil {
+		return fmt.Errorf("%w: %v", ErrSendFailed, err)
+	}
+	return nil
+}
+
+// flushQueue 把排队的报文按序发出, 中途失败时保留未发出的部分
+// 等下次重连后继续, 已发出的不再重复
+func (ws *TqWebsocket) flushQueue() {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.conn == nil {
+		return
+	}
+	for i, data := range ws.queue {
+		if err := ws.conn.WriteMessage(data); err != nil {
+			ws.logger.Warn("websocket flush failed", "error", err, "remaining", len(ws.queue)-i)
+			ws.queue = append([][]byte(nil), ws.queue[i:]...)
+			return
+		}
+	}
+	ws.queue = nil
+}
+
+// Close 关闭连接并停止重连
+func (ws *TqWebsocket) Close() error {
+	if ws.cancel != nil {
+		ws.cancel()
+	}
+	ws.status.Store(int32(StatusClosed))
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.conn != nil {
+		return ws.conn.Close()
+	}
+	return nil
+}
+
+// TqQuoteWebsocket 行情连接, 收到的数据合并进 DataManager
+type TqQuoteWebsocket struct {
+	*TqWebsocket
+	dm *DataManager
+
+	mu sync.Mutex
+	// subscribeQuote 保留最近一次订阅报文, 重连后重发
+	subscribeQuote map[string]interface{}
+	// charts 保留各图表订阅报文, 重连后重发
+	charts map[string]map[string]interface{}
+}
+
+func NewTqQuoteWebsocket(conf *WebSocketConfig, dm *DataManager, logger *slog.Logger) *TqQuoteWebsocket {
+	qw := &TqQuoteWebsocket{
+		TqWebsocket: NewTqWebsocket(conf, logger),
+		dm:          dm,
+		charts:      make(map[string]map[string]interface{}),
+	}
+	qw.OnMessage(qw.handleMessage)
+	qw.OnOpen(qw.handleOpen)
+	return qw
+}
+
+func (qw *TqQuoteWebsocket) handleOpen() {
+	qw.mu.Lock()
+	sub := qw.subscribeQuote
+	charts := make([]map[string]interface{}, 0, len(qw.charts))
+	for _, c := range qw.charts {
+		charts = append(charts, c)
+	}
+	qw.mu.Unlock()
+	if sub != nil {
+		qw.Send(sub)
+	}
+	for _, c := range charts {
+		qw.Send(c)
+	}
+	qw.Send(map[string]interface{}{"aid": "peek_message"})
+}
+
+func (qw *TqQuoteWebsocket) handleMessage(pkt map[string]interface{}) {
+	aid, _ := pkt["aid"].(string)
+	if aid != "rtn_data" {
+		return
+	}
+	if datas, ok := pkt["data"].([]interface{}); ok {
+		for _, d := range datas {
+			if diff, ok := d.(map[string]interface{}); ok {
+				qw.dm.MergeData(diff)
+			}
+		}
+	}
+	qw.Send(map[string]interface{}{"aid": "peek_message"})
+}
+
+// SubscribeQuote 发送行情订阅报文, ins_list 为全量合约列表
+func (qw *TqQuoteWebsocket) SubscribeQuote(insList string) error {
+	pkt := map[string]interface{}{"aid": "subscribe_quote", "ins_list": insList}
+	qw.mu.Lock()
+	qw.subscribeQuote = pkt
+	qw.mu.Unlock()
+	return qw.Send(pkt)
+}
+
+// SetChart 发送图表(K线/Tick)订阅报文
+func (qw *TqQuoteWebsocket) SetChart(pkt map[string]interface{}) error {
+	chartID, _ := pkt["chart_id"].(string)
+	qw.mu.Lock()
+	if insList, _ := pkt["ins_list"].(string); insList == "" && chartID != "" {
+		delete(qw.charts, chartID)
+	} else if chartID != "" {
+		qw.charts[chartID] = pkt
+	}
+	qw.mu.Unlock()
+	return qw.Send(pkt)
+}
+
+// TqTradeWebsocket 交易连接
+type TqTradeWebsocket struct {
+	*TqWebsocket
+	dm *DataManager
+
+	mu      sync.Mutex
+	brokers []string
+	// reqLogin 保留登录报文, 重连后自动重新登录
+	reqLogin map[string]interface{}
+}
+
+func NewTqTradeWebsocket(conf *WebSocketConfig, dm *DataManager, logger *slog.Logger) *TqTradeWebsocket {
+	tw := &TqTradeWebsocket{
+		TqWebsocket: NewTqWebsocket(conf, logger),
+		dm:          dm,
+	}
+	tw.OnMessage(tw.handleMessage)
+	tw.OnOpen(tw.handleOpen)
+	return tw
+}
+
+func (tw *TqTradeWebsocket) handleOpen() {
+	tw.mu.Lock()
+	login := tw.reqLogin
+	tw.mu.Unlock()
+	if login != nil {
+		tw.Send(login)
+	}
+	tw.Send(map[string]interface{}{"aid": "peek_message"})
+}
+
+func (tw *TqTradeWebsocket) handleMessage(pkt map[string]interface{}) {
+	aid, _ := pkt["aid"].(string)
+	switch aid {
+	case "rtn_brokers":
+		if bs, ok := pkt["brokers"].([]interface{}); ok {
+			brokers := make([]string, 0, len(bs))
+			for _, b := range bs {
+				brokers = append(brokers, toString(b))
+			}
+			tw.mu.Lock()
+			tw.brokers = brokers
+			tw.mu.Unlock()
+		}
+	case "rtn_data":
+		if datas, ok := pkt["data"].([]interface{}); ok {
+			for _, d := range datas {
+				if diff, ok := d.(map[string]interface{}); ok {
+					tw.dm.MergeData(diff)
+				}
+			}
+		}
+		tw.Send(map[string]interface{}{"aid": "peek_message"})
+	}
+}
+
+// Brokers 返回服务端下发的期货公司列表, 未收到 rtn_brokers 时为空
+func (tw *TqTradeWebsocket) Brokers() []string {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return append([]string{}, tw.brokers...)
+}
+
+// ReqLogin 发送交易登录报文
+func (tw *TqTradeWebsocket) ReqLogin(broker, userID, password string) error {
+	pkt := map[string]interface{}{
+		"aid":       "req_login",
+		"bid":       broker,
+		"user_name": userID,
+		"password":  password,
+	}
+	tw.mu.Lock()
+	tw.reqLogin = pkt
+	tw.mu.Unlock()
+	return tw.Send(pkt)
+}
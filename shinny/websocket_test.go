@@ -0,0 +1,324 @@
+package shinny
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBaseHeaderUserAgent(t *testing.T) {
+	h := BaseHeader()
+	if got := h.Get("User-Agent"); got != "tqsdk-go/"+Version {
+		t.Fatalf("User-Agent = %q, want tqsdk-go/%s", got, Version)
+	}
+}
+
+func TestHeaderMerge(t *testing.T) {
+	custom := http.Header{}
+	custom.Set("X-Trace-Id", "trace-123")
+	custom.Set("User-Agent", "my-app/1.0")
+	ws := NewTqWebsocket(&WebSocketConfig{Headers: custom}, nil)
+
+	h := ws.header()
+	if got := h.Get("X-Trace-Id"); got != "trace-123" {
+		t.Fatalf("X-Trace-Id = %q", got)
+	}
+	if got := h.Get("User-Agent"); got != "my-app/1.0" {
+		t.Fatalf("custom User-Agent not applied, got %q", got)
+	}
+	if got := h.Get("Accept"); got != "application/v1+json" {
+		t.Fatalf("default Accept lost, got %q", got)
+	}
+}
+
+func TestSendQueueCapacity(t *testing.T) {
+	ws := NewTqWebsocket(&WebSocketConfig{MaxQueueSize: 3}, nil)
+	for i := 0; i < 5; i++ {
+		if err := ws.Send(map[string]interface{}{"aid": "subscribe_quote", "seq": i}); err != nil {
+			t.Fatalf("Send error: %v", err)
+		}
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.queue) != 3 {
+		t.Fatalf("queue length = %d, want 3", len(ws.queue))
+	}
+	// 应保留最新的 3 条 (seq 2,3,4)
+	if string(ws.queue[0]) != `{"aid":"subscribe_quote","seq":2}` {
+		t.Fatalf("oldest retained packet = %s", ws.queue[0])
+	}
+	if string(ws.queue[2]) != `{"aid":"subscribe_quote","seq":4}` {
+		t.Fatalf("newest retained packet = %s", ws.queue[2])
+	}
+}
+
+func TestSendQueueUnlimitedByDefault(t *testing.T) {
+	ws := NewTqWebsocket(&WebSocketConfig{}, nil)
+	for i := 0; i < 100; i++ {
+		ws.Send(map[string]interface{}{"seq": i})
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.queue) != 100 {
+		t.Fatalf("queue length = %d, want 100", len(ws.queue))
+	}
+}
+
+func TestSendImmediateWhenDisconnected(t *testing.T) {
+	ws := NewTqWebsocket(&WebSocketConfig{}, nil)
+	if err := ws.SendImmediate(map[string]interface{}{"aid": "insert_order"}); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("SendImmediate error = %v, want ErrNotConnected", err)
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.queue) != 0 {
+		t.Fatal("time-critical packet must not be queued")
+	}
+}
+
+func TestFailFastOrders(t *testing.T) {
+	dm := NewDataManager()
+	ws := NewTqTradeWebsocket(&WebSocketConfig{}, dm, nil)
+	session := newTradeSession(ws, dm, "simnow", "user1", defaultLogger(), WithFailFastOrders())
+	_, err := session.InsertOrder(context.Background(), &InsertOrderRequest{
+		Symbol: "SHFE.cu2601", Direction: DirectionBuy, Offset: OffsetOpen,
+		Volume: 1, PriceType: PriceTypeLimit, LimitPrice: 68000,
+	})
+	if !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("InsertOrder error = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestDialTimeoutUnreachable(t *testing.T) {
+	// 不可达地址 (TEST-NET-1), 拨号应在配置的超时内失败
+	ws := NewTqWebsocket(&WebSocketConfig{
+		URLs:        []string{"ws://192.0.2.1:9"},
+		DialTimeout: 100 * time.Millisecond,
+	}, nil)
+	start := time.Now()
+	err := ws.Init(context.Background())
+	elapsed := time.Since(start)
+	if err == nil {
+		ws.Close()
+		t.Fatal("Init should fail for unreachable address")
+	}
+	if elapsed > 2*time.Second {
+		t.Fatalf("Init took %v, dial timeout not applied", elapsed)
+	}
+}
+
+func TestOnRawMessageCustomAid(t *testing.T) {
+	dm := NewDataManager()
+	qw := NewTqQuoteWebsocket(&WebSocketConfig{}, dm, nil)
+
+	var gotAid string
+	var gotPkt map[string]interface{}
+	qw.OnRawMessage(func(aid string, pkt map[string]interface{}) {
+		gotAid = aid
+		gotPkt = pkt
+	})
+
+	// 自定义 aid 报文被钩子捕获, 内部处理忽略
+	qw.handleIncoming([]byte(`{"aid":"my_custom_aid","payload":42}`))
+	if gotAid != "my_custom_aid" {
+		t.Fatalf("aid = %q, want my_custom_aid", gotAid)
+	}
+	// 报文按 UseNumber 解码, 钩子看到的数值是 json.Number
+	if toFloat64(gotPkt["payload"]) != 42.0 {
+		t.Fatalf("payload = %v", gotPkt["payload"])
+	}
+
+	// 内置 aid 同样先经过钩子, 且不影响数据合并
+	qw.handleIncoming([]byte(`{"aid":"rtn_data","data":[{"quotes":{"SHFE.cu2601":{"last_price":68000}}}]}`))
+	if gotAid != "rtn_data" {
+		t.Fatalf("aid = %q, want rtn_data", gotAid)
+	}
+	if raw, ok := dm.GetByPath("quotes", "SHFE.cu2601", "last_price"); !ok || raw != 68000.0 {
+		t.Fatalf("merged quote = %v %v", raw, ok)
+	}
+
+	// SendRaw 未连接时排队
+	if err := qw.SendRaw(map[string]interface{}{"aid": "my_custom_aid"}); err != nil {
+		t.Fatalf("SendRaw error: %v", err)
+	}
+}
+
+func TestProcessingLatencyStats(t *testing.T) {
+	dm := NewDataManager()
+	qw := NewTqQuoteWebsocket(&WebSocketConfig{}, dm, defaultLogger())
+
+	if got := qw.ProcessingLatency(); got.Count != 0 {
+		t.Fatalf("initial count = %d, want 0", got.Count)
+	}
+	for i := 0; i < 100; i++ {
+		pkt := fmt.Sprintf(`{"aid":"rtn_data","data":[{"quotes":{"SHFE.cu2601":{"last_price":%d}}}]}`, 68000+i)
+		qw.handleIncoming([]byte(pkt))
+	}
+
+	stats := qw.ProcessingLatency()
+	if stats.Count != 100 {
+		t.Fatalf("count = %d, want 100", stats.Count)
+	}
+	if stats.P50 <= 0 || stats.P99 <= 0 || stats.Max <= 0 {
+		t.Fatalf("latency percentiles not recorded: %+v", stats)
+	}
+	if stats.P50 > stats.P99 || stats.P99 > stats.Max {
+		t.Fatalf("percentile ordering wrong: %+v", stats)
+	}
+	// 报文确实走完了合并链路
+	if v, ok := dm.GetByPath("quotes", "SHFE.cu2601", "last_price"); !ok || v != float64(68099) {
+		t.Fatalf("merged last_price = %v, want 68099", v)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	dm := NewDataManager()
+	qw := NewTqQuoteWebsocket(&WebSocketConfig{}, dm, defaultLogger())
+
+	// 未连接时在 ctx 截止后报错
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := qw.WaitReady(ctx); err == nil {
+		t.Fatal("WaitReady should fail before connection opens")
+	}
+
+	// 连接建立 (模拟状态翻转) 后立即返回
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		qw.status.Store(int32(StatusOpen))
+	}()
+	ctx2, cancel2 := context.WithTimeout(context.Background(), time.Second)
+	defer cancel2()
+	if err := qw.WaitReady(ctx2); err != nil {
+		t.Fatalf("WaitReady error: %v", err)
+	}
+}
+
+// flakyConn 可按次数注入写入失败的 mock 连接
+type flakyConn struct {
+	mu       sync.Mutex
+	failures int
+	sent     [][]byte
+}
+
+func (c *flakyConn) ReadMessage() ([]byte, error) { select {} }
+
+func (c *flakyConn) WriteMessage(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failures > 0 {
+		c.failures--
+		return errors.New("broken pipe")
+	}
+	c.sent = append(c.sent, append([]byte(nil), data...))
+	return nil
+}
+
+func (c *flakyConn) Close() error { return nil }
+
+func (c *flakyConn) sentCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sent)
+}
+
+func TestSendRetriesTransientFailure(t *testing.T) {
+	ws := NewTqWebsocket(&WebSocketConfig{}, nil)
+	conn := &flakyConn{failures: maxSendRetries - 1}
+	ws.conn = conn
+	ws.status.Store(int32(StatusOpen))
+
+	// 前两次写入失败, 第三次成功, 报文不进队列
+	if err := ws.Send(map[string]interface{}{"aid": "subscribe_quote"}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	if conn.sentCount() != 1 {
+		t.Fatalf("sent = %d, want 1", conn.sentCount())
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.queue) != 0 {
+		t.Fatalf("queue length = %d, want 0", len(ws.queue))
+	}
+}
+
+func TestSendFailureQueuesForRetransmit(t *testing.T) {
+	ws := NewTqWebsocket(&WebSocketConfig{}, nil)
+	conn := &flakyConn{failures: maxSendRetries * 2}
+	ws.conn = conn
+	ws.status.Store(int32(StatusOpen))
+
+	// 重试耗尽后报文转入队列, Send 不报错
+	if err := ws.Send(map[string]interface{}{"aid": "subscribe_quote", "ins_list": "SHFE.cu2601"}); err != nil {
+		t.Fatalf("Send error: %v", err)
+	}
+	ws.mu.Lock()
+	queued := len(ws.queue)
+	ws.mu.Unlock()
+	if queued != 1 {
+		t.Fatalf("queue length = %d, want 1", queued)
+	}
+
+	// "重连" 恢复后 flush 把报文补发出去
+	conn.mu.Lock()
+	conn.failures = 0
+	conn.mu.Unlock()
+	ws.flushQueue()
+	if conn.sentCount() != 1 {
+		t.Fatalf("sent = %d, want 1", conn.sentCount())
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.queue) != 0 {
+		t.Fatalf("queue not drained, length = %d", len(ws.queue))
+	}
+}
+
+func TestSendImmediateWriteFailure(t *testing.T) {
+	ws := NewTqWebsocket(&WebSocketConfig{}, nil)
+	conn := &flakyConn{failures: 1}
+	ws.conn = conn
+	ws.status.Store(int32(StatusOpen))
+
+	// 时效性报文写入失败立即返回可判定错误, 且不排队不重试
+	err := ws.SendImmediate(map[string]interface{}{"aid": "insert_order"})
+	if !errors.Is(err, ErrSendFailed) {
+		t.Fatalf("SendImmediate error = %v, want ErrSendFailed", err)
+	}
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.queue) != 0 {
+		t.Fatal("time-critical packet must not be queued")
+	}
+}
+
+func TestFlushQueueKeepsUnsentOnFailure(t *testing.T) {
+	ws := NewTqWebsocket(&WebSocketConfig{}, nil)
+	for i := 0; i < 3; i++ {
+		ws.Send(map[string]interface{}{"seq": i})
+	}
+	// 第一条发出后连接再次断开, 剩余两条应保留且不重复已发出的
+	conn := &flakyConn{}
+	ws.conn = conn
+	ws.flushQueue()
+	conn.mu.Lock()
+	conn.failures = 5
+	conn.mu.Unlock()
+	// 手工构造: 第二轮 flush 全部失败
+	ws.mu.Lock()
+	ws.queue = [][]byte{[]byte(`{"seq":3}`), []byte(`{"seq":4}`)}
+	ws.mu.Unlock()
+	ws.flushQueue()
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if len(ws.queue) != 2 {
+		t.Fatalf("queue length = %d, want 2", len(ws.queue))
+	}
+	if string(ws.queue[0]) != `{"seq":3}` {
+		t.Fatalf("first retained packet = %s", ws.queue[0])
+	}
+}
@@ -0,0 +1,218 @@
+package shinny
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// wsConn 是对单个 websocket 连接的最小抽象, 便于测试时注入 mock 实现
+type wsConn interface {
+	// ReadMessage 读取一条完整的文本消息
+	ReadMessage() ([]byte, error)
+	// WriteMessage 发送一条文本消息
+	WriteMessage(data []byte) error
+	Close() error
+}
+
+const wsMagicGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// rawConn 基于 RFC6455 的 websocket 客户端实现, 仅支持 SDK 需要的文本帧
+type rawConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	wmu  sync.Mutex
+}
+
+// dialWebsocket 建立 websocket 连接并完成协议升级握手
+func dialWebsocket(ctx context.Context, rawURL string, header http.Header) (wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		switch u.Scheme {
+		case "wss":
+			host += ":443"
+		default:
+			host += ":80"
+		}
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "wss" {
+		tc := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tc.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tc
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	secKey := base64.StdEncoding.EncodeToString(keyBytes)
+
+	path := u.RequestURI()
+	if path == "" {
+		path = "/"
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "GET %s HTTP/1.1\r\n", path)
+	fmt.Fprintf(&sb, "Host: %s\r\n", u.Host)
+	sb.WriteString("Upgrade: websocket\r\nConnection: Upgrade\r\n")
+	fmt.Fprintf(&sb, "Sec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n", secKey)
+	for k, vs := range header {
+		for _, v := range vs {
+			fmt.Fprintf(&sb, "%s: %s\r\n", k, v)
+		}
+	}
+	sb.WriteString("\r\n")
+	if _, err := conn.Write([]byte(sb.String())); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: %s", resp.Status)
+	}
+	h := sha1.Sum([]byte(secKey + wsMagicGUID))
+	if resp.Header.Get("Sec-Websocket-Accept") != base64.StdEncoding.EncodeToString(h[:]) {
+		conn.Close()
+		return nil, errors.New("websocket handshake failed: bad accept key")
+	}
+	return &rawConn{conn: conn, br: br}, nil
+}
+
+func (c *rawConn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case 0x1, 0x2, 0x0: // text / binary / continuation
+			msg = append(msg, payload...)
+			if fin {
+				return msg, nil
+			}
+		case 0x9: // ping
+			if err := c.writeFrame(0xA, payload); err != nil {
+				return nil, err
+			}
+		case 0xA: // pong
+		case 0x8: // close
+			c.writeFrame(0x8, nil)
+			return nil, io.EOF
+		}
+	}
+}
+
+func (c *rawConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var hdr [2]byte
+	if _, err = io.ReadFull(c.br, hdr[:]); err != nil {
+		return
+	}
+	fin = hdr[0]&0x80 != 0
+	opcode = hdr[0] & 0x0F
+	masked := hdr[1]&0x80 != 0
+	length := uint64(hdr[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	var maskKey [4]byte
+	if masked {
+		if _, err = io.ReadFull(c.br, maskKey[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= maskKey[i%4]
+		}
+	}
+	return
+}
+
+func (c *rawConn) writeFrame(opcode byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	var maskKey [4]byte
+	if _, err := rand.Read(maskKey[:]); err != nil {
+		return err
+	}
+	buf := make([]byte, 0, len(payload)+14)
+	buf = append(buf, 0x80|opcode)
+	switch {
+	case len(payload) < 126:
+		buf = append(buf, 0x80|byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		buf = append(buf, 0x80|126, byte(len(payload)>>8), byte(len(payload)))
+	default:
+		buf = append(buf, 0x80|127)
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(len(payload)))
+		buf = append(buf, ext[:]...)
+	}
+	buf = append(buf, maskKey[:]...)
+	off := len(buf)
+	buf = append(buf, payload...)
+	for i := range payload {
+		buf[off+i] ^= maskKey[i%4]
+	}
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+func (c *rawConn) WriteMessage(data []byte) error {
+	return c.writeFrame(0x1, data)
+}
+
+func (c *rawConn) Close() error {
+	c.writeFrame(0x8, nil)
+	return c.conn.Close()
+}
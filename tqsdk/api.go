@@ -0,0 +1,71 @@
+package tqsdk
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// TqApi 早期版本的接口封装, 数据由使用方通过 Feed 系列方法喂入
+type TqApi struct {
+	// prefix 本实例生成 order_id 的前缀
+	prefix   string
+	orderSeq int64
+
+	mu     sync.RWMutex
+	orders map[string]*Order
+	trades map[string]*Trade
+}
+
+func NewTqApi() *TqApi {
+	return &TqApi{
+		prefix: "tqsdk_",
+		orders: make(map[string]*Order),
+		trades: make(map[string]*Trade),
+	}
+}
+
+// SetOrderIDPrefix 设置本实例生成 order_id 的前缀, 默认 "tqsdk_"
+func (api *TqApi) SetOrderIDPrefix(prefix string) {
+	api.prefix = prefix
+}
+
+// NextOrderID 生成下一个 order_id
+func (api *TqApi) NextOrderID() string {
+	seq := atomic.AddInt64(&api.orderSeq, 1)
+	return fmt.Sprintf("%s%d", api.prefix, seq)
+}
+
+// FeedOrder 更新一笔委托单
+func (api *TqApi) FeedOrder(o *Order) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.orders[o.OrderID] = o
+}
+
+// FeedTrade 更新一笔成交
+func (api *TqApi) FeedTrade(t *Trade) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.trades[t.TradeID] = t
+}
+
+// GetOrder 查询委托单
+func (api *TqApi) GetOrder(orderID string) *Order {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+	return api.orders[orderID]
+}
+
+// GetTradesByOrder 查询一笔委托单的全部成交, 按 trade_id 返回
+func (api *TqApi) GetTradesByOrder(orderID string) map[string]interface{} {
+	api.mu.RLock()
+	defer api.mu.RUnlock()
+	out := make(map[string]interface{})
+	for id, t := range api.trades {
+		if t.OrderID == orderID {
+			out[id] = t
+		}
+	}
+	return out
+}
@@ -0,0 +1,32 @@
+// Package tqsdk 是早期版本的 DIFF 协议封装, 保留用于兼容老用户,
+// 新项目请使用 shinny 包
+package tqsdk
+
+// Order 委托单
+type Order struct {
+	OrderID        string  `json:"order_id"`
+	ExchangeID     string  `json:"exchange_id"`
+	InstrumentID   string  `json:"instrument_id"`
+	Direction      string  `json:"direction"`
+	Offset         string  `json:"offset"`
+	VolumeOrign    int64   `json:"volume_orign"`
+	VolumeLeft     int64   `json:"volume_left"`
+	LimitPrice     float64 `json:"limit_price"`
+	PriceType      string  `json:"price_type"`
+	InsertDateTime int64   `json:"insert_date_time"`
+	Status         string  `json:"status"`
+	LastMsg        string  `json:"last_msg"`
+}
+
+// Trade 成交记录
+type Trade struct {
+	TradeID       string  `json:"trade_id"`
+	OrderID       string  `json:"order_id"`
+	ExchangeID    string  `json:"exchange_id"`
+	InstrumentID  string  `json:"instrument_id"`
+	Direction     string  `json:"direction"`
+	Offset        string  `json:"offset"`
+	Price         float64 `json:"price"`
+	Volume        int64   `json:"volume"`
+	TradeDateTime int64   `json:"trade_date_time"`
+}